@@ -15,19 +15,28 @@ import (
 
 	"day.local/gee"
 	"day.local/gee/middleware"
+	"day.local/internal/app/shortlink"
 	slcache "day.local/internal/app/shortlink/cache"
 	shortlinkhttpapi "day.local/internal/app/shortlink/httpapi"
+	"day.local/internal/app/shortlink/jobs"
 	"day.local/internal/app/shortlink/repo"
 	"day.local/internal/app/shortlink/stats"
 	"day.local/internal/platform/auth"
 	platformcache "day.local/internal/platform/cache"
 	"day.local/internal/platform/config"
 	"day.local/internal/platform/db"
+	"day.local/internal/platform/dnsverify"
+	"day.local/internal/platform/geoip"
 	"day.local/internal/platform/httpmiddleware"
 	"day.local/internal/platform/httpserver"
 	"day.local/internal/platform/metrics"
+	"day.local/internal/platform/pagemeta"
+	"day.local/internal/platform/probe"
+	"day.local/internal/platform/qrcode"
 	"day.local/internal/platform/ratelimit"
+	"day.local/internal/platform/safehttp"
 	"day.local/internal/platform/trace"
+	"day.local/internal/platform/urlcheck"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
@@ -83,7 +92,18 @@ func main() {
 	//创建布隆过滤器 预期 100 万短码，1% 误判率
 	bloomFilter := slcache.NewBloomFilter(1_000_000, 0.01)
 
-	slRepo := repo.NewShortlinksRepo(dbPool, slCache, bloomFilter)
+	//短码生成方式，配置有误时直接退出，避免上线后才发现创建失败
+	codeGen, codeErr := shortlink.NewCodeGenerator(shortlink.CodeGeneratorOptions{
+		Kind:           cfg.CodeGenerator,
+		SqidsAlphabet:  cfg.SqidsAlphabet,
+		SqidsMinLength: cfg.SqidsMinLength,
+		SqidsBlocklist: cfg.SqidsBlocklist,
+		RandomLength:   cfg.RandomCodeLength,
+	})
+	if codeErr != nil {
+		log.Fatal(codeErr)
+	}
+	slRepo := repo.NewShortlinksRepo(dbPool, slCache, bloomFilter, codeGen)
 
 	//初始化统计收集器（根据配置选择 Channel 或 Kafka）
 	var collector stats.Collector
@@ -132,10 +152,26 @@ func main() {
 
 	api := r.Group("/api/v1")
 
+	// URL 信誉检查：未配置 API key 时不检查
+	var urlChecker urlcheck.Checker
+	if cfg.SafeBrowsingAPIKey != "" {
+		urlChecker = urlcheck.NewSafeBrowsing(cfg.SafeBrowsingAPIKey, "", nil)
+	} else {
+		slog.Warn("URL reputation check disabled", "SAFE_BROWSING_API_KEY", "")
+	}
+
 	// App routes (can mount multiple apps).
 	shortlinkhttpapi.RegisterWebRoutes(r)
-	shortlinkhttpapi.RegisterPublicRoutes(r, slRepo, collector, limiter)
-	shortlinkhttpapi.RegisterAPIRoutes(api, slRepo, usersRepo, ts, limiter)
+	unlockSigner := shortlink.NewUnlockSigner(cfg.JWTSecret, cfg.LinkUnlockTTL)
+	// 二维码 logo 配置错误时直接退出，不要等到印刷时才发现
+	qrRenderer, qrErr := qrcode.NewRendererFromFile(cfg.QRLogoFile)
+	if qrErr != nil {
+		log.Fatal(qrErr)
+	}
+	shortlinkhttpapi.RegisterPublicRoutes(r, slRepo, collector, limiter, unlockSigner, geoip.NewHeaderResolver(cfg.GeoIPCountryHeader), qrRenderer)
+	// 抓取目标页面元信息：只能访问公网地址，避免借短链读取内网页面
+	metaFetcher := pagemeta.NewFetcher(safehttp.NewClient(10 * time.Second))
+	shortlinkhttpapi.RegisterAPIRoutes(api, slRepo, usersRepo, ts, limiter, dnsverify.NewTXTVerifier(nil), urlcheck.NewScreener(urlChecker, cfg.URLCheckFailClosed), metaFetcher)
 
 	r.GET("/healthz", func(ctx *gee.Context) {
 		ctx.String(http.StatusOK, "ok")
@@ -212,6 +248,18 @@ func main() {
 	if channelConsumer != nil {
 		go channelConsumer.Run(stopCtx)
 	}
+	// 启动过期短链清理
+	go jobs.NewExpirySweeper(slRepo, cfg.ExpirySweepInterval, cfg.ExpiredRetention).Run(stopCtx)
+	// 启动目的地域名策略复查
+	go jobs.NewPolicyRechecker(slRepo, cfg.PolicyRecheckInterval).Run(stopCtx)
+	// 启动目标地址健康检查：只能访问公网地址，避免借短链探测内网
+	go jobs.NewHealthChecker(slRepo, probe.NewProber(safehttp.NewClient(10*time.Second)), cfg.HealthCheckInterval).Run(stopCtx)
+	// 启动目标页面元信息抓取
+	go jobs.NewMetadataFetcher(slRepo, metaFetcher, cfg.MetadataFetchInterval).Run(stopCtx)
+	// 启动 URL 信誉复扫（配置了 Safe Browsing 才启用）
+	if urlChecker != nil {
+		go jobs.NewReputationScanner(slRepo, urlChecker, cfg.URLRescanInterval).Run(stopCtx)
+	}
 	defer collector.Close()
 
 	err := <-errch
@@ -1,6 +1,7 @@
 package gee
 
 import (
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -156,3 +157,19 @@ func TestMiddlewareExecutionOrder(t *testing.T) {
 		}
 	}
 }
+
+// 测试 SetHTMLTemplate 渲染预先解析的模板
+func TestSetHTMLTemplate(t *testing.T) {
+	engine := New()
+	engine.SetHTMLTemplate(template.Must(template.New("hello.html").Parse("<p>{{.}}</p>")))
+	engine.GET("/", func(ctx *Context) {
+		ctx.HTML(http.StatusOK, "hello.html", "<gee>")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	if w.Body.String() != "<p>&lt;gee&gt;</p>" {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
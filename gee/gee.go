@@ -58,6 +58,11 @@ func (e *Engine) LoadHTMLGlob(pattern string) {
 	e.htmlTemplates = template.Must(template.New("").Funcs(e.funcMap).ParseGlob(pattern))
 }
 
+// SetHTMLTemplate 直接使用已解析好的模板（例如从 embed.FS 解析），与 LoadHTMLGlob 二选一
+func (e *Engine) SetHTMLTemplate(t *template.Template) {
+	e.htmlTemplates = t
+}
+
 func (group *RouterGroup) Group(prefix string) *RouterGroup {
 	engine := group.engine
 	newGroup := &RouterGroup{
@@ -95,6 +100,16 @@ func (group *RouterGroup) DELETE(pattern string, handlers ...HandlerFunc) {
 	group.addRoute("DELETE", pattern, handlers...)
 }
 
+// PATCH defines the method to add PATCH request
+func (group *RouterGroup) PATCH(pattern string, handlers ...HandlerFunc) {
+	group.addRoute("PATCH", pattern, handlers...)
+}
+
+// PUT defines the method to add PUT request
+func (group *RouterGroup) PUT(pattern string, handlers ...HandlerFunc) {
+	group.addRoute("PUT", pattern, handlers...)
+}
+
 func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandlerFunc {
 	absolutePath := path.Join(group.prefix, relativePath)
 	fileServer := http.StripPrefix(absolutePath, http.FileServer(fs))
@@ -147,3 +147,68 @@ func TestMethodNotAllowedGoThroughMiddleware(t *testing.T) {
 		t.Error("middleware should be executed for 405")
 	}
 }
+
+// 测试 PATCH 路由与参数解析
+func TestPatchRoute(t *testing.T) {
+	engine := New()
+	engine.PATCH("/items/:id", func(ctx *Context) {
+		ctx.String(200, "patched:%s", ctx.Param("id"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PATCH", "/items/42", nil)
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "patched:42" {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestPutRoute(t *testing.T) {
+	engine := New()
+	engine.PUT("/items/:id", func(ctx *Context) {
+		ctx.String(200, "put:%s", ctx.Param("id"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("PUT", "/items/42", nil)
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, w.Code)
+	}
+	if w.Body.String() != "put:42" {
+		t.Errorf("unexpected body: %s", w.Body.String())
+	}
+}
+
+func TestParamWithCatchAll(t *testing.T) {
+	engine := New()
+	engine.GET("/:code", func(ctx *Context) {
+		ctx.String(200, "code:%s", ctx.Param("code"))
+	})
+	engine.GET("/:code/*path", func(ctx *Context) {
+		ctx.String(200, "code:%s path:%s", ctx.Param("code"), ctx.Param("path"))
+	})
+	engine.GET("/assets/*filepath", func(ctx *Context) {
+		ctx.String(200, "asset:%s", ctx.Param("filepath"))
+	})
+
+	cases := map[string]string{
+		"/docs":          "code:docs",
+		"/docs/":         "code:docs",
+		"/docs/a/b":      "code:docs path:a/b",
+		"/assets/app.js": "asset:app.js",
+		"/jira/ABC-123":  "code:jira path:ABC-123",
+	}
+	for path, want := range cases {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		if w.Code != http.StatusOK || w.Body.String() != want {
+			t.Errorf("%s: got %d %q, want %q", path, w.Code, w.Body.String(), want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// clickCounterTTL 点击计数在 Redis 中的空闲保留时间。
+//
+// 每次占用名额都会续期；长时间无人访问后过期，下次访问再从 DB 的 click_count 重新播种，
+// 这也是 Redis 计数与异步落库的 click_count 对账的时机。
+const clickCounterTTL = 7 * 24 * time.Hour
+
+// 返回值：-1 表示计数不存在需要播种；0 表示名额已用完；>0 为占用后的点击数
+const takeClickLua = `
+local v = redis.call("GET", KEYS[1])
+if not v then
+  if ARGV[2] == "" then
+    return -1
+  end
+  redis.call("SET", KEYS[1], ARGV[2], "NX")
+  v = redis.call("GET", KEYS[1])
+end
+if tonumber(v) >= tonumber(ARGV[1]) then
+  return 0
+end
+local n = redis.call("INCR", KEYS[1])
+redis.call("PEXPIRE", KEYS[1], ARGV[3])
+return n
+`
+
+// TakeClick 为有点击上限的短链原子地占用一次名额。
+//
+// seed 在 Redis 中没有计数时调用（返回 DB 中已落库的点击数），只在冷启动/计数过期时回源一次。
+// 返回占用后的点击数；ok=false 表示已达上限。
+//
+// 设计原因：
+// - 点击统计是异步批量落库的，click_count 会滞后，不能用来做“只能用一次”的判断
+// - 用 Lua 把“比较 + 自增”做成原子操作，并发访问一次性链接时只有一个请求能拿到名额
+func (c *ShortlinkCache) TakeClick(ctx context.Context, code string, max int64, seed func() (int64, error)) (int64, bool, error) {
+	key := "slc:" + code
+	ttl := clickCounterTTL.Milliseconds()
+
+	res, err := c.client.Eval(ctx, takeClickLua, []string{key}, max, "", ttl).Int64()
+	if err != nil {
+		return 0, false, err
+	}
+	if res == -1 {
+		n, err := seed()
+		if err != nil {
+			return 0, false, err
+		}
+		res, err = c.client.Eval(ctx, takeClickLua, []string{key}, max, n, ttl).Int64()
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	if res < 0 {
+		return 0, false, fmt.Errorf("unexpected take click result: %d", res)
+	}
+	return res, res > 0, nil
+}
+
+// DeleteClicks 删除点击计数，下一次访问会从 DB 重新播种。
+func (c *ShortlinkCache) DeleteClicks(ctx context.Context, code string) error {
+	return c.client.Del(ctx, "slc:"+code).Err()
+}
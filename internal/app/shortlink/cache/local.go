@@ -17,6 +17,12 @@ type LocalCache struct {
 // maxItems: 最大缓存条目数（建议 10000-100000）
 // maxCost: 最大内存占用（字节，建议 16MB-64MB）
 func NewLocalCache(maxItems int64, maxCost int64) (*LocalCache, error) {
+	// 本地缓存 TTL 短一些，保证多实例一致性
+	return NewLocalCacheWithTTL(maxItems, maxCost, 5*time.Minute, 10*time.Second)
+}
+
+// NewLocalCacheWithTTL 同 NewLocalCache，可指定正常条目与负缓存的 TTL
+func NewLocalCacheWithTTL(maxItems, maxCost int64, ttl, emptyTTL time.Duration) (*LocalCache, error) {
 	cache, err := ristretto.NewCache(&ristretto.Config{
 		NumCounters: maxItems * 10, // 计数器数量，建议为 maxItems 的 10 倍
 		MaxCost:     maxCost,
@@ -27,25 +33,31 @@ func NewLocalCache(maxItems int64, maxCost int64) (*LocalCache, error) {
 	}
 	return &LocalCache{
 		cache:    cache,
-		ttl:      5 * time.Minute,  // 本地缓存 TTL 短一些，保证多实例一致性
-		emptyTTL: 10 * time.Second, // 负缓存 TTL
+		ttl:      ttl,
+		emptyTTL: emptyTTL,
 	}, nil
 }
 
-func (l *LocalCache) Get(code string) (string, bool) {
+// Get 读取本地缓存。存的是解码后的 *Entry，L1 命中不需要再做 JSON 反序列化；
+// Entry 写入后不再修改，可以被多个请求并发读取。
+func (l *LocalCache) Get(code string) (*Entry, bool) {
 	if v, ok := l.cache.Get(code); ok {
-		return v.(string), true
+		return v.(*Entry), true
 	}
-	return "", false
+	return nil, false
 }
 
-func (l *LocalCache) Set(code, url string) {
+// Set 写入本地缓存。ttl<=0 或超过本地 TTL 时使用本地 TTL（本地缓存不能比 L2 活得更久）。
+func (l *LocalCache) Set(code string, e *Entry, ttl time.Duration) {
+	if ttl <= 0 || ttl > l.ttl {
+		ttl = l.ttl
+	}
 	// cost=1 表示按条目数限制
-	l.cache.SetWithTTL(code, url, 1, l.ttl)
+	l.cache.SetWithTTL(code, e, 1, ttl)
 }
 
 func (l *LocalCache) SetNotFound(code string) {
-	l.cache.SetWithTTL(code, notFoundSentinel, 1, l.emptyTTL)
+	l.cache.SetWithTTL(code, notFoundEntry, 1, l.emptyTTL)
 }
 
 func (l *LocalCache) Del(code string) {
@@ -2,15 +2,34 @@ package cache
 
 import (
 	"context"
+	"encoding/json"
 	"log/slog"
 	"time"
 
+	"day.local/internal/app/shortlink"
 	"day.local/internal/platform/metrics"
 	"github.com/redis/go-redis/v9"
 )
 
 const notFoundSentinel = "__nil__"
 
+// expiredSentinel 标记“短链存在但已过期”，让热路径区分 404 与 410 而不必回源 DB。
+const expiredSentinel = "__expired__"
+
+// Entry 是一条缓存记录：Link 非空为正常命中；NotFound / Expired 为负缓存。
+//
+// 缓存整条短链（而不只是 URL），跳转状态码、响应头等跳转语义才能在热路径上不查 DB。
+type Entry struct {
+	Link     *shortlink.Shortlink
+	NotFound bool
+	Expired  bool
+}
+
+var (
+	notFoundEntry = &Entry{NotFound: true}
+	expiredEntry  = &Entry{Expired: true}
+)
+
 type ShortlinkCache struct {
 	client   *redis.Client
 	local    *LocalCache // L1 本地缓存
@@ -27,53 +46,133 @@ func NewShortlinkCache(client *redis.Client, local *LocalCache) *ShortlinkCache
 	}
 }
 
-func (c *ShortlinkCache) Get(ctx context.Context, code string) (string, error) {
+// Get 读取缓存，返回 nil 表示未命中。
+func (c *ShortlinkCache) Get(ctx context.Context, code string) (*Entry, error) {
 	// L1: 本地缓存
 	if c.local != nil {
-		if url, ok := c.local.Get(code); ok {
-			if url == notFoundSentinel {
+		if e, ok := c.local.Get(code); ok {
+			if e.Link == nil {
 				metrics.CacheOperations.WithLabelValues("l1", "hit_negative").Inc()
 			} else {
 				metrics.CacheOperations.WithLabelValues("l1", "hit").Inc()
 			}
-			return url, nil
+			return e, nil
 		}
 	}
 
-	// L2: Redis
+	// L2: Redis。GET 与 PTTL 走同一个 pipeline，回填 L1 时用 L2 剩余 TTL，
+	// 避免带过期时间的短链在 L1 中活过 expires_at。
 	key := "sl:" + code
-	res, err := c.client.Get(ctx, key).Result()
+	pipe := c.client.Pipeline()
+	getCmd := pipe.Get(ctx, key)
+	ttlCmd := pipe.PTTL(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+	res, err := getCmd.Result()
 	if err == redis.Nil {
 		metrics.CacheOperations.WithLabelValues("l2", "miss").Inc()
-		return "", nil // 缓存未命中
+		return nil, nil // 缓存未命中
 	}
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	var e *Entry
+	switch res {
+	case notFoundSentinel:
+		e = notFoundEntry
+	case expiredSentinel:
+		e = expiredEntry
+	default:
+		var link shortlink.Shortlink
+		if err := json.Unmarshal([]byte(res), &link); err != nil {
+			// 旧版本只缓存 URL 字符串，解码失败按未命中处理，回源后会被新格式覆盖
+			metrics.CacheOperations.WithLabelValues("l2", "miss").Inc()
+			return nil, nil
+		}
+		e = &Entry{Link: &link}
 	}
 	// L2 命中
-	if res == notFoundSentinel {
+	if e.Link == nil {
 		metrics.CacheOperations.WithLabelValues("l2", "hit_negative").Inc()
 	} else {
 		metrics.CacheOperations.WithLabelValues("l2", "hit").Inc()
 	}
 
-	// 回填本地缓存
+	// 回填本地缓存：负缓存用本地的短 TTL，别的实例刚创建的短码很快就能访问
 	if c.local != nil {
-		if res == notFoundSentinel {
+		if e == notFoundEntry {
 			c.local.SetNotFound(code)
 		} else {
-			c.local.Set(code, res)
+			c.local.Set(code, e, ttlCmd.Val())
 		}
 	}
-	return res, nil
+	return e, nil
 }
 
-func (c *ShortlinkCache) Set(ctx context.Context, code, url string) error {
+// Set 写入短链缓存。link.ExpiresAt 非空时 TTL 不会超过过期时间点；已过期则改写为过期哨兵。
+func (c *ShortlinkCache) Set(ctx context.Context, link shortlink.Shortlink) error {
+	ttl := c.ttl
+	if link.ExpiresAt != nil {
+		left := time.Until(*link.ExpiresAt)
+		if left <= 0 {
+			return c.SetExpired(ctx, link.Code)
+		}
+		if left < ttl {
+			ttl = left
+		}
+	}
+	data, err := json.Marshal(link)
+	if err != nil {
+		return err
+	}
 	// 同时写入本地缓存
 	if c.local != nil {
-		c.local.Set(code, url)
+		c.local.Set(link.Code, &Entry{Link: &link}, ttl)
 	}
-	return c.client.Set(ctx, "sl:"+code, url, c.ttl).Err()
+	return c.client.Set(ctx, "sl:"+link.Code, data, ttl).Err()
+}
+
+// SetMany 批量写入短链缓存，Redis 写入走一次 pipeline，避免批量创建时逐条往返。
+// TTL 规则同 Set；已过期的短链写入过期哨兵。
+func (c *ShortlinkCache) SetMany(ctx context.Context, links []shortlink.Shortlink) error {
+	pipe := c.client.Pipeline()
+	for _, link := range links {
+		ttl := c.ttl
+		if link.ExpiresAt != nil {
+			left := time.Until(*link.ExpiresAt)
+			if left <= 0 {
+				if c.local != nil {
+					c.local.Set(link.Code, expiredEntry, 0)
+				}
+				pipe.Set(ctx, "sl:"+link.Code, expiredSentinel, c.ttl)
+				continue
+			}
+			if left < ttl {
+				ttl = left
+			}
+		}
+		data, err := json.Marshal(link)
+		if err != nil {
+			return err
+		}
+		if c.local != nil {
+			l := link
+			c.local.Set(link.Code, &Entry{Link: &l}, ttl)
+		}
+		pipe.Set(ctx, "sl:"+link.Code, data, ttl)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// SetExpired 写入过期哨兵。过期是稳定状态，使用正常 TTL。
+func (c *ShortlinkCache) SetExpired(ctx context.Context, code string) error {
+	if c.local != nil {
+		c.local.Set(code, expiredEntry, 0)
+	}
+	return c.client.Set(ctx, "sl:"+code, expiredSentinel, c.ttl).Err()
 }
 
 func (c *ShortlinkCache) Delete(ctx context.Context, code string) error {
@@ -101,3 +200,29 @@ func (c *ShortlinkCache) Close() {
 		slog.Info("本地缓存已关闭")
 	}
 }
+
+// GetAlias 读取别名（自定义域名上的 "域名/key"）到内部短码的映射。
+// found 为 false 表示未命中；code 为空且 found 为 true 表示命中负缓存。
+//
+// 别名一经创建不会改指向（key 不能修改），可以长时间缓存；只走 Redis，不占本地缓存的条目。
+func (c *ShortlinkCache) GetAlias(ctx context.Context, alias string) (code string, found bool, err error) {
+	res, err := c.client.Get(ctx, "sla:"+alias).Result()
+	if err == redis.Nil {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if res == notFoundSentinel {
+		return "", true, nil
+	}
+	return res, true, nil
+}
+
+func (c *ShortlinkCache) SetAlias(ctx context.Context, alias, code string) error {
+	return c.client.Set(ctx, "sla:"+alias, code, c.ttl).Err()
+}
+
+func (c *ShortlinkCache) SetAliasNotFound(ctx context.Context, alias string) error {
+	return c.client.Set(ctx, "sla:"+alias, notFoundSentinel, c.emptyTTL).Err()
+}
@@ -0,0 +1,141 @@
+package shortlink
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+)
+
+// maxCodeLength 与 codeRe 的长度上限一致
+const maxCodeLength = 32
+
+var alnumRe = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+
+// CodeGenerator 为新建短链生成短码（自定义短码不经过这里）。
+//
+// attempt 从 0 开始；生成的短码已被占用时，调用方递增 attempt 再要一个。
+//
+// 设计原因：
+// - 生成方式由部署决定：sqids 最短但可枚举，随机与词组模式不可枚举，代价是更长、要处理冲突
+// - 短码生成后存进数据库，跳转只按存储的短码查找、从不反解 id；切换生成方式或参数后，已有短码照常可用
+// - 切换后新短码可能与旧短码撞上，统一靠唯一约束发现冲突后重试，不需要各实现自己查重
+type CodeGenerator interface {
+	Generate(id uint64, attempt int) (string, error)
+}
+
+// 生成方式，对应配置 CODE_GENERATOR
+const (
+	CodeGeneratorSqids  = "sqids"
+	CodeGeneratorRandom = "random"
+	CodeGeneratorWords  = "words"
+)
+
+// CodeGeneratorOptions 是 NewCodeGenerator 的参数，只有所选方式用到的字段生效
+type CodeGeneratorOptions struct {
+	Kind           string
+	SqidsAlphabet  string
+	SqidsMinLength int
+	SqidsBlocklist []string
+	RandomLength   int
+}
+
+// NewCodeGenerator 按配置创建生成器；Kind 为空时使用 sqids（与之前的行为一致）
+func NewCodeGenerator(opts CodeGeneratorOptions) (CodeGenerator, error) {
+	switch strings.ToLower(opts.Kind) {
+	case "", CodeGeneratorSqids:
+		return NewSqidsGenerator(opts.SqidsAlphabet, opts.SqidsMinLength, opts.SqidsBlocklist)
+	case CodeGeneratorRandom:
+		return NewRandomGenerator(opts.RandomLength)
+	case CodeGeneratorWords:
+		return WordsGenerator{}, nil
+	}
+	return nil, fmt.Errorf("unknown code generator %q (want sqids, random or words)", opts.Kind)
+}
+
+// base62Alphabet 是随机短码的字符集
+const base62Alphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// minRandomLength 是随机短码的最短长度：62^6 约 568 亿，逐个试探不现实
+const minRandomLength = 6
+
+// RandomGenerator 生成定长的密码学随机 base62 短码，与 id 无关，无法枚举。
+type RandomGenerator struct {
+	length int
+}
+
+func NewRandomGenerator(length int) (*RandomGenerator, error) {
+	if length < minRandomLength || length > maxCodeLength {
+		return nil, fmt.Errorf("random code length must be between %d and %d", minRandomLength, maxCodeLength)
+	}
+	return &RandomGenerator{length: length}, nil
+}
+
+func (g *RandomGenerator) Generate(uint64, int) (string, error) {
+	b := make([]byte, g.length)
+	for i := range b {
+		c, err := randIndex(len(base62Alphabet))
+		if err != nil {
+			return "", err
+		}
+		b[i] = base62Alphabet[c]
+	}
+	return string(b), nil
+}
+
+// WordsGenerator 生成便于口述、记忆的“形容词+名词”短码，例如 BraveOtter。
+//
+// 词组只有一万多种，前两次冲突后在末尾加两位随机数字（BraveOtter42），组合数扩大一百倍。
+type WordsGenerator struct{}
+
+func (WordsGenerator) Generate(_ uint64, attempt int) (string, error) {
+	a, err := randIndex(len(codeAdjectives))
+	if err != nil {
+		return "", err
+	}
+	n, err := randIndex(len(codeNouns))
+	if err != nil {
+		return "", err
+	}
+	code := codeAdjectives[a] + codeNouns[n]
+	if attempt >= 2 {
+		d, err := randIndex(100)
+		if err != nil {
+			return "", err
+		}
+		code += fmt.Sprintf("%02d", d)
+	}
+	return code, nil
+}
+
+// randIndex 返回 [0, n) 内均匀分布的密码学随机数
+func randIndex(n int) (int, error) {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+var codeAdjectives = []string{
+	"Able", "Amber", "Ample", "Azure", "Bold", "Brave", "Breezy", "Bright", "Brisk", "Calm", "Candid", "Cheery", "Clear", "Clever", "Cool", "Cosmic",
+	"Crisp", "Curious", "Dapper", "Daring", "Deep", "Eager", "Early", "Easy", "Elated", "Epic", "Fair", "Fancy", "Fast", "Festive", "Fine", "Firm",
+	"Fluffy", "Fond", "Free", "Fresh", "Gentle", "Giant", "Glad", "Golden", "Good", "Grand", "Green", "Happy", "Hardy", "Honest", "Humble", "Ivory",
+	"Jolly", "Keen", "Kind", "Large", "Lively", "Lucky", "Lunar", "Magic", "Mellow", "Merry", "Mighty", "Mild", "Modern", "Neat", "Nimble", "Noble",
+	"Novel", "Oaken", "Olive", "Open", "Patient", "Plain", "Polite", "Proud", "Quick", "Quiet", "Rapid", "Rare", "Ready", "Regal", "Rosy", "Royal",
+	"Rustic", "Safe", "Sandy", "Scarlet", "Serene", "Sharp", "Shiny", "Silent", "Silver", "Simple", "Sleek", "Smart", "Smooth", "Snowy", "Solar", "Solid",
+	"Sonic", "Spicy", "Steady", "Stellar", "Stout", "Sunny", "Super", "Sweet", "Swift", "Tall", "Tidy", "Tiny", "Topaz", "Tranquil", "True", "Trusty",
+	"Upbeat", "Urban", "Valiant", "Velvet", "Vivid", "Warm", "Wavy", "Whole", "Wild", "Windy", "Wise", "Witty", "Young", "Zany", "Zesty", "Zippy",
+}
+
+var codeNouns = []string{
+	"Acorn", "Anchor", "Apple", "Arrow", "Aspen", "Badger", "Bamboo", "Bay", "Beacon", "Bear", "Birch", "Bison", "Breeze", "Brook", "Cactus", "Canyon",
+	"Castle", "Cedar", "Cherry", "Cloud", "Clover", "Comet", "Coral", "Cougar", "Cove", "Crane", "Creek", "Daisy", "Delta", "Dolphin", "Dove", "Dragon",
+	"Dune", "Eagle", "Ember", "Falcon", "Fern", "Finch", "Fjord", "Forest", "Fox", "Galaxy", "Garden", "Gazelle", "Glacier", "Glade", "Grove", "Harbor",
+	"Hawk", "Heron", "Hill", "Hollow", "Island", "Ivy", "Jaguar", "Jasmine", "Kite", "Koala", "Lagoon", "Lake", "Lantern", "Lark", "Leaf", "Lemon",
+	"Lily", "Lion", "Lotus", "Maple", "Meadow", "Mesa", "Meteor", "Moon", "Moose", "Nebula", "Oak", "Ocean", "Orchid", "Otter", "Owl", "Panda",
+	"Panther", "Parrot", "Peach", "Pearl", "Pebble", "Pelican", "Pine", "Planet", "Plum", "Pond", "Poppy", "Prairie", "Quail", "Rabbit", "Rain", "Raven",
+	"Reef", "Ridge", "River", "Robin", "Rocket", "Rose", "Sage", "Salmon", "Sparrow", "Spruce", "Squirrel", "Star", "Stone", "Storm", "Summit", "Sun",
+	"Swan", "Thunder", "Tiger", "Trail", "Tulip", "Valley", "Violet", "Volcano", "Walrus", "Wave", "Whale", "Willow", "Wolf", "Wren", "Zebra", "Zephyr",
+}
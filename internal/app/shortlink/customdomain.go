@@ -0,0 +1,38 @@
+package shortlink
+
+import (
+	"errors"
+	"net"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidDomain = errors.New("invalid domain")
+
+var hostLabelRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NormalizeHost 统一 host 写法：小写、去掉端口与末尾的点。请求的 Host 头与注册的域名都先经过它再比较。
+func NormalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// ValidateDomainHost 校验用户注册的自定义域名：至少两级、每级符合 DNS 标签规则，不接受 IP 与端口。
+func ValidateDomainHost(host string) error {
+	if len(host) > 253 || net.ParseIP(host) != nil || strings.Contains(host, ":") {
+		return ErrInvalidDomain
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return ErrInvalidDomain
+	}
+	for _, l := range labels {
+		if !hostLabelRe.MatchString(l) {
+			return ErrInvalidDomain
+		}
+	}
+	return nil
+}
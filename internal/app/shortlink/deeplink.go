@@ -0,0 +1,134 @@
+package shortlink
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidDeepLink = errors.New("invalid deep link")
+
+const maxAppURLLen = 2048
+
+var schemeRe = regexp.MustCompile(`^[a-z][a-z0-9+.-]*$`)
+
+// blockedSchemes 是不能作为 App 跳转地址的 scheme：它们会在短链域名下执行脚本或读本地内容
+var blockedSchemes = map[string]bool{
+	"javascript": true,
+	"vbscript":   true,
+	"data":       true,
+	"file":       true,
+	"blob":       true,
+	"about":      true,
+}
+
+// DeepLink 是移动端的 App 跳转配置，iOS / Android 各自一组：
+// - IOS：universal link（https）或自定义 scheme（myapp://path）
+// - Android：App Link（https）、intent:// URL 或自定义 scheme
+// - IOSStore / AndroidStore：没装 App 时的去处（通常是商店页），为空时回到网页目标
+//
+// 设计原因：
+// - 与 RedirectRule 一样随短链整条缓存并以 JSON 存储，所以带 json tag
+// - 只有 https 地址能直接 302；自定义 scheme 与 intent 需要落地页用脚本唤起，失败后再去商店
+type DeepLink struct {
+	IOS          string `json:"ios,omitempty"`
+	IOSStore     string `json:"ios_store,omitempty"`
+	Android      string `json:"android,omitempty"`
+	AndroidStore string `json:"android_store,omitempty"`
+}
+
+// AppTarget 是某个平台访客的 App 跳转目标
+type AppTarget struct {
+	AppURL   string // 唤起 App 的地址
+	StoreURL string // 唤起失败时的去处，为空表示回到网页目标
+	Direct   bool   // AppURL 是 https，可以直接 302（系统会在装了 App 时接管）
+}
+
+// Target 返回操作系统 os（见 OS* 常量）对应的 App 跳转目标；d 为 nil 或该平台未配置时返回 false。
+func (d *DeepLink) Target(os string) (AppTarget, bool) {
+	if d == nil {
+		return AppTarget{}, false
+	}
+	var t AppTarget
+	switch os {
+	case OSIOS:
+		t = AppTarget{AppURL: d.IOS, StoreURL: d.IOSStore}
+	case OSAndroid:
+		t = AppTarget{AppURL: d.Android, StoreURL: d.AndroidStore}
+	}
+	if t.AppURL == "" {
+		return AppTarget{}, false
+	}
+	t.Direct = isWebURL(t.AppURL)
+	return t, true
+}
+
+// WebURLs 返回配置里的 http(s) 地址（universal link / App Link 与商店页），
+// 它们和网页目标一样会被直接 302 出去，需要同样过目的地策略与信誉检查；自定义 scheme 与 intent 不在其列。
+func (d *DeepLink) WebURLs() []string {
+	if d == nil {
+		return nil
+	}
+	var out []string
+	for _, raw := range []string{d.IOS, d.IOSStore, d.Android, d.AndroidStore} {
+		if isWebURL(raw) {
+			out = append(out, raw)
+		}
+	}
+	return out
+}
+
+func isWebURL(raw string) bool {
+	lower := strings.ToLower(raw)
+	return strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")
+}
+
+// NormalizeDeepLink 校验 App 跳转配置；全部为空时返回 nil（不启用）。
+//
+// 商店地址只在对应平台配置了 App 地址时才有意义，单独设置视为错误，避免以为生效了其实没有。
+func NormalizeDeepLink(d *DeepLink) (*DeepLink, error) {
+	if d == nil {
+		return nil, nil
+	}
+	out := DeepLink{
+		IOS:          strings.TrimSpace(d.IOS),
+		IOSStore:     strings.TrimSpace(d.IOSStore),
+		Android:      strings.TrimSpace(d.Android),
+		AndroidStore: strings.TrimSpace(d.AndroidStore),
+	}
+	if out == (DeepLink{}) {
+		return nil, nil
+	}
+	if (out.IOS == "" && out.IOSStore != "") || (out.Android == "" && out.AndroidStore != "") {
+		return nil, ErrInvalidDeepLink
+	}
+	for _, raw := range []string{out.IOS, out.IOSStore, out.Android, out.AndroidStore} {
+		if raw == "" {
+			continue
+		}
+		if err := ValidateAppURL(raw); err != nil {
+			return nil, err
+		}
+	}
+	return &out, nil
+}
+
+// ValidateAppURL 校验 App 跳转地址：http(s) 按 ValidateURL 校验，其它 scheme 只要求格式合法且不在黑名单里。
+func ValidateAppURL(raw string) error {
+	if len(raw) > maxAppURLLen {
+		return ErrInvalidDeepLink
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" {
+		return ErrInvalidDeepLink
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" || scheme == "https" {
+		return ValidateURL(raw)
+	}
+	if !schemeRe.MatchString(scheme) || blockedSchemes[scheme] {
+		return ErrInvalidDeepLink
+	}
+	return nil
+}
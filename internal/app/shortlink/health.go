@@ -0,0 +1,16 @@
+package shortlink
+
+// UseFallback 判断默认目标是否应改用备用地址：健康检查判定默认目标故障，且设置了备用地址
+func (s Shortlink) UseFallback() bool {
+	return s.DestDown && s.FallbackURL != ""
+}
+
+// HealthCheckURL 返回健康检查要探测的地址，即默认目标；空表示不探测。
+//
+// 模板地址（含 {1}、{path}）要有访客的路径才能展开，单独探测没有意义，不检查。
+func (s Shortlink) HealthCheckURL() string {
+	if IsTemplate(s.URL) {
+		return ""
+	}
+	return s.URL
+}
@@ -0,0 +1,101 @@
+package httpapi
+
+import (
+	"net/http"
+	"strings"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+// 短码不可用的原因
+const (
+	unavailableInvalid  = "invalid"  // 格式不对：只能是 3~32 位字母数字
+	unavailableReserved = "reserved" // 与站点路由冲突
+	unavailableTaken    = "taken"    // 已被占用
+)
+
+// maxSuggestions 是返回的可用候选数量上限
+const maxSuggestions = 5
+
+type AvailabilityResponse struct {
+	Code        string   `json:"code"`
+	Available   bool     `json:"available"`
+	Reason      string   `json:"reason,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// NewAvailabilityHandler 检查自定义短码是否可用：GET /api/v1/shortlinks/availability?code=&url=&title=&domain=。
+//
+// 依次检查格式、保留字与是否被占用；保留字或已被占用时，从目标地址的域名与标题生成候选（见 shortlink.SuggestCodes），
+// 返回其中最多 5 个可用的。url、title 只用于生成候选，可以不传；domain 表示检查自己已校验的自定义域名下的 key，需要登录。
+// 不可用也返回 200，reason 说明原因；结果只是当下的判断，创建时仍可能因并发被占用而返回 409。
+//
+// 设计原因：
+// - 前端边输入边查，查询走布隆过滤器，绝大多数未被占用的短码不访问数据库
+// - 候选与原短码一起一次查询判断占用，避免推荐出同样被占用的短码
+func NewAvailabilityHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		q := ctx.Req.URL.Query()
+		code := strings.TrimSpace(q.Get("code"))
+		if code == "" {
+			ctx.AbortWithError(http.StatusBadRequest, "code is required")
+			return
+		}
+		var domainID int64
+		if host := q.Get("domain"); host != "" {
+			userID, ok := tryGetUserID(ctx)
+			if !ok {
+				return
+			}
+			if userID == nil {
+				ctx.AbortWithError(http.StatusUnauthorized, "custom domain requires login")
+				return
+			}
+			domain, ok := mustVerifiedDomain(ctx, r, *userID, host)
+			if !ok {
+				return
+			}
+			domainID = domain.ID
+		}
+
+		resp := AvailabilityResponse{Code: code}
+		switch {
+		case shortlink.IsReservedCode(code):
+			resp.Reason = unavailableReserved
+		case shortlink.ValidateCode(code) != nil:
+			resp.Reason = unavailableInvalid
+			ctx.JSON(http.StatusOK, resp)
+			return
+		}
+
+		candidates := shortlink.SuggestCodes(code, q.Get("url"), q.Get("title"))
+		check := candidates
+		if resp.Reason == "" {
+			check = append([]string{code}, candidates...)
+		}
+		taken, err := r.TakenCodes(ctx.Req.Context(), domainID, check)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		if resp.Reason == "" && taken[code] {
+			resp.Reason = unavailableTaken
+		}
+		if resp.Reason == "" {
+			resp.Available = true
+			ctx.JSON(http.StatusOK, resp)
+			return
+		}
+		for _, c := range candidates {
+			if !taken[c] {
+				resp.Suggestions = append(resp.Suggestions, c)
+				if len(resp.Suggestions) == maxSuggestions {
+					break
+				}
+			}
+		}
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
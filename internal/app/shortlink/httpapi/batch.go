@@ -0,0 +1,197 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/urlcheck"
+)
+
+// maxBatchSize 单次批量创建的条数上限；maxBatchBody 请求体上限，防止超大 JSON 占满内存。
+const (
+	maxBatchSize = 2000
+	maxBatchBody = 4 << 20
+)
+
+// BatchItemRequest 是批量创建中的一条，字段含义同 ShortLinksRequest。
+//
+// 批量接口只支持共享短链的基础选项：密码（bcrypt 逐条哈希太慢）、私有短链、
+// 标题/标签等整理信息请走单条创建或之后再修改。
+type BatchItemRequest struct {
+	URL          string     `json:"url"`
+	Code         string     `json:"code,omitempty"`
+	ExpireIn     string     `json:"expire_in,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectType int        `json:"redirect_type,omitempty"`
+	CacheControl string     `json:"cache_control,omitempty"`
+	RobotsTag    string     `json:"robots_tag,omitempty"`
+	MaxClicks    int64      `json:"max_clicks,omitempty"`
+}
+
+type BatchRequest struct {
+	Items []BatchItemRequest `json:"items"`
+}
+
+// BatchItemResult 是单条的结果：成功时带短链信息，失败时带 status（与单条创建接口的状态码一致）和 error。
+type BatchItemResult struct {
+	Index    int    `json:"index"`
+	URL      string `json:"url"`
+	Code     string `json:"code,omitempty"`
+	ShortURL string `json:"short_url,omitempty"`
+	Status   int    `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+type BatchResponse struct {
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// NewCreateBatchHandler 批量创建短链：POST /api/v1/shortlinks:batch，需登录。
+//
+// 逐条校验，非法条目直接记为失败；合法条目在一个事务里创建（见 repo.CreateBatch）。
+// 只要请求本身合法就返回 200，每条的成败看 results[i].status，results 顺序与 items 一致。
+//
+// 设计原因：
+// - 部分失败很常见（某个自定义短码被占用），整批回滚会让调用方难以重试；按条返回结果更好处理
+// - 整批只算一次限流，避免 newsletter 这类一次上千条的场景被单条创建的限流卡住
+func NewCreateBatchHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		ctx.Req.Body = http.MaxBytesReader(ctx.Writer, ctx.Req.Body, maxBatchBody)
+		var req BatchRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		if len(req.Items) == 0 {
+			ctx.AbortWithError(http.StatusBadRequest, "items is empty")
+			return
+		}
+		if len(req.Items) > maxBatchSize {
+			ctx.AbortWithError(http.StatusBadRequest, "too many items, max "+strconv.Itoa(maxBatchSize))
+			return
+		}
+
+		policy, err := r.DestinationPolicy(ctx.Req.Context())
+		if err != nil {
+			ctx.AbortWithError(http.StatusServiceUnavailable, "destination policy unavailable")
+			return
+		}
+
+		now := time.Now()
+		results := make([]BatchItemResult, len(req.Items))
+		items := make([]repo.BatchItem, 0, len(req.Items))
+		indexes := make([]int, 0, len(req.Items))
+		for i, item := range req.Items {
+			results[i] = BatchItemResult{Index: i, URL: item.URL}
+			link, err := batchItemLink(item, now)
+			if err != nil {
+				results[i].Status = http.StatusBadRequest
+				results[i].Error = err.Error()
+				continue
+			}
+			if err := policy.Check(link.URL); err != nil {
+				results[i].Status = http.StatusForbidden
+				results[i].Error = err.Error()
+				continue
+			}
+			items = append(items, repo.BatchItem{Link: link})
+			indexes = append(indexes, i)
+		}
+		items, indexes, ok = screenBatchItems(ctx, screener, items, indexes, func(i int, m urlcheck.Match) {
+			results[i].Status = http.StatusForbidden
+			results[i].Error = m.Reason()
+		})
+		if !ok {
+			return
+		}
+
+		if len(items) > 0 {
+			created, err := r.CreateBatch(ctx.Req.Context(), items, &userID)
+			if err != nil {
+				ctx.AbortWithError(http.StatusInternalServerError, "shortlink batch create failed")
+				return
+			}
+			for j, res := range created {
+				out := &results[indexes[j]]
+				if res.Err != nil {
+					out.Status, out.Error = batchErrorStatus(res.Err)
+					continue
+				}
+				out.Status = http.StatusOK
+				out.Code = res.Link.Code
+				out.ShortURL = buildShortURL(ctx, res.Link.Code)
+			}
+		}
+
+		resp := BatchResponse{Results: results}
+		for _, res := range results {
+			if res.Status == http.StatusOK {
+				resp.Created++
+			} else {
+				resp.Failed++
+			}
+		}
+		ctx.JSON(http.StatusOK, resp)
+	}
+}
+
+// batchItemLink 校验单条并转换为领域对象，规则与 NewCreateHandler 一致。
+func batchItemLink(item BatchItemRequest, now time.Time) (shortlink.Shortlink, error) {
+	if err := shortlink.ValidateURL(item.URL); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	code := strings.TrimSpace(item.Code)
+	if code != "" {
+		if err := shortlink.ValidateCode(code); err != nil {
+			return shortlink.Shortlink{}, err
+		}
+	}
+	expiresAt, err := resolveExpiry(item.ExpireIn, item.ExpiresAt, now)
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	if err := shortlink.ValidateRedirectType(item.RedirectType); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	cacheControl := strings.TrimSpace(item.CacheControl)
+	robotsTag := strings.TrimSpace(item.RobotsTag)
+	if err := shortlink.ValidateHeaderValue(cacheControl); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	if err := shortlink.ValidateHeaderValue(robotsTag); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	if err := shortlink.ValidateMaxClicks(item.MaxClicks); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	return shortlink.Shortlink{
+		Code:         code,
+		URL:          item.URL,
+		ExpiresAt:    expiresAt,
+		RedirectType: item.RedirectType,
+		CacheControl: cacheControl,
+		RobotsTag:    robotsTag,
+		MaxClicks:    item.MaxClicks,
+	}, nil
+}
+
+// batchErrorStatus 把单条创建错误映射为状态码，与单条创建接口一致：冲突 409，其它 500。
+func batchErrorStatus(err error) (int, string) {
+	if errors.Is(err, repo.ErrShortlinkCodeAlreadyExists) ||
+		errors.Is(err, repo.ErrShortlinkURLAlreadyHasDifferentCode) ||
+		errors.Is(err, repo.ErrShortlinkURLAlreadyHasDifferentOptions) {
+		return http.StatusConflict, err.Error()
+	}
+	return http.StatusInternalServerError, "shortlink create failed"
+}
@@ -0,0 +1,88 @@
+package httpapi
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/urlcheck"
+)
+
+type deepLinkPage struct {
+	AppURL      template.URL
+	StoreURL    template.URL
+	WebURL      template.URL
+	FallbackURL template.URL
+}
+
+type DeepLinkResponse struct {
+	Code     string              `json:"code"`
+	DeepLink *shortlink.DeepLink `json:"deep_link"`
+}
+
+// renderDeepLinkPage 渲染 App 唤起落地页：自定义 scheme / intent 不能可靠地用 302 唤起，
+// 由页面脚本跳转，唤起失败后回退到商店地址（未配置时回到网页目标 web）。
+//
+// 地址都已通过 shortlink.ValidateAppURL 校验（排除 javascript: 等），这里才能标记为 template.URL；
+// 否则 html/template 会把非 http(s) 的 scheme 替换掉。
+func renderDeepLinkPage(ctx *gee.Context, app shortlink.AppTarget, web string) {
+	fallback := app.StoreURL
+	if fallback == "" {
+		fallback = web
+	}
+	ctx.SetHeader("Cache-Control", "private, no-store")
+	ctx.SetHeader("X-Robots-Tag", "noindex")
+	ctx.HTML(http.StatusOK, "deeplink.html", deepLinkPage{
+		AppURL:      template.URL(app.AppURL),
+		StoreURL:    template.URL(app.StoreURL),
+		WebURL:      template.URL(web),
+		FallbackURL: template.URL(fallback),
+	})
+}
+
+// NewUpdateDeepLinkHandler 替换自己短链的 App 跳转配置：PUT /api/v1/users/shortlinks/:code/deeplink。
+//
+// 请求体即 DeepLink 对象，所有字段为空（{}）即关闭。其中的 http(s) 地址会被直接 302，与网页目标一样过策略与信誉检查。
+func NewUpdateDeepLinkHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req shortlink.DeepLink
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		deepLink, err := shortlink.NormalizeDeepLink(&req)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		urls := deepLink.WebURLs()
+		if !mustPassPolicy(ctx, r, urls...) || !mustPassReputation(ctx, screener, urls...) {
+			return
+		}
+
+		link, err := r.UpdateDeepLink(ctx.Req.Context(), userID, code, deepLink)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, DeepLinkResponse{Code: link.Code, DeepLink: link.DeepLink})
+	}
+}
@@ -0,0 +1,191 @@
+package httpapi
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/dnsverify"
+)
+
+type DomainRequest struct {
+	Host string `json:"host"`
+}
+
+// DomainResponse 附带校验需要添加的 TXT 记录；校验通过后仍然返回，方便用户核对
+type DomainResponse struct {
+	Host       string     `json:"host"`
+	Verified   bool       `json:"verified"`
+	VerifiedAt *time.Time `json:"verified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	TXTName    string     `json:"txt_name"`
+	TXTValue   string     `json:"txt_value"`
+}
+
+func newDomainResponse(d repo.Domain) DomainResponse {
+	return DomainResponse{
+		Host:       d.Host,
+		Verified:   d.Verified(),
+		VerifiedAt: d.VerifiedAt,
+		CreatedAt:  d.CreatedAt,
+		TXTName:    dnsverify.RecordName(d.Host),
+		TXTValue:   dnsverify.RecordValue(d.VerifyToken),
+	}
+}
+
+// mustVerifiedDomain 查询当前用户名下已校验的域名，失败时已写入错误响应
+func mustVerifiedDomain(ctx *gee.Context, r *repo.ShortlinksRepo, userID int64, host string) (repo.Domain, bool) {
+	d, err := r.GetDomain(ctx.Req.Context(), userID, shortlink.NormalizeHost(host))
+	if err != nil {
+		if errors.Is(err, repo.ErrDomainNotFound) {
+			ctx.AbortWithError(http.StatusNotFound, err.Error())
+			return repo.Domain{}, false
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+		return repo.Domain{}, false
+	}
+	if !d.Verified() {
+		ctx.AbortWithError(http.StatusConflict, repo.ErrDomainNotVerified.Error())
+		return repo.Domain{}, false
+	}
+	return d, true
+}
+
+// newVerifyToken 生成域名校验用的随机串
+func newVerifyToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// NewCreateDomainHandler 注册自定义域名：POST /api/v1/users/domains。
+//
+// 注册后需要按返回的 txt_name/txt_value 添加 DNS TXT 记录，再调用校验接口；校验通过前不能在该域名下创建短链。
+// 校验通过前同一域名可以被多个用户认领，先通过校验的得到它；已有人校验通过或自己已认领过返回 409。
+func NewCreateDomainHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req DomainRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		host := shortlink.NormalizeHost(req.Host)
+		if err := shortlink.ValidateDomainHost(host); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		token, err := newVerifyToken()
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "domain create failed")
+			return
+		}
+
+		d, err := r.CreateDomain(ctx.Req.Context(), userID, host, token)
+		if err != nil {
+			if errors.Is(err, repo.ErrDomainAlreadyExists) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "domain create failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, newDomainResponse(d))
+	}
+}
+
+func NewListDomainsHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		domains, err := r.ListDomains(ctx.Req.Context(), userID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		items := make([]DomainResponse, 0, len(domains))
+		for _, d := range domains {
+			items = append(items, newDomainResponse(d))
+		}
+		ctx.JSON(http.StatusOK, items)
+	}
+}
+
+// NewVerifyDomainHandler 校验域名：POST /api/v1/users/domains/:host/verify。
+//
+// 记录不存在或不匹配返回 409，可以稍后（等 DNS 生效）重试；DNS 查询本身失败返回 502。已校验的域名再次校验直接成功，
+// 已被其他认领者校验通过同样返回 409。
+func NewVerifyDomainHandler(r *repo.ShortlinksRepo, verifier dnsverify.Verifier) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		d, err := r.GetDomain(ctx.Req.Context(), userID, shortlink.NormalizeHost(ctx.Param("host")))
+		if err != nil {
+			if errors.Is(err, repo.ErrDomainNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		if d.Verified() {
+			ctx.JSON(http.StatusOK, newDomainResponse(d))
+			return
+		}
+
+		if err := verifier.Verify(ctx.Req.Context(), d.Host, d.VerifyToken); err != nil {
+			if errors.Is(err, dnsverify.ErrNotVerified) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusBadGateway, "dns lookup failed")
+			return
+		}
+		d, err = r.MarkDomainVerified(ctx.Req.Context(), d.ID)
+		if err != nil {
+			if errors.Is(err, repo.ErrDomainAlreadyExists) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.JSON(http.StatusOK, newDomainResponse(d))
+	}
+}
+
+// NewDeleteDomainHandler 删除域名：DELETE /api/v1/users/domains/:host；域名下还有短链时返回 409
+func NewDeleteDomainHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		if err := r.DeleteDomain(ctx.Req.Context(), userID, shortlink.NormalizeHost(ctx.Param("host"))); err != nil {
+			if errors.Is(err, repo.ErrDomainNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrDomainInUse) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+}
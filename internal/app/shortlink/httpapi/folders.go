@@ -0,0 +1,201 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+type FolderRequest struct {
+	Name string `json:"name"`
+}
+
+// LinkMetaRequest 是用户整理短链的请求体，字段缺省表示不修改；folder_id 为 0 表示移出文件夹。
+type LinkMetaRequest struct {
+	Title    *string   `json:"title,omitempty"`
+	Notes    *string   `json:"notes,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+	FolderID *int64    `json:"folder_id,omitempty"`
+}
+
+// empty 表示请求里没有任何整理字段
+func (m LinkMetaRequest) empty() bool {
+	return m.Title == nil && m.Notes == nil && m.Tags == nil && m.FolderID == nil
+}
+
+// parseLinkMeta 校验并规范化整理字段，失败时已写入 400 响应。
+func parseLinkMeta(ctx *gee.Context, req LinkMetaRequest) (repo.LinkMeta, bool) {
+	meta := repo.LinkMeta{FolderID: req.FolderID}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if err := shortlink.ValidateTitle(title); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return repo.LinkMeta{}, false
+		}
+		meta.Title = &title
+	}
+	if req.Notes != nil {
+		notes := strings.TrimSpace(*req.Notes)
+		if err := shortlink.ValidateNotes(notes); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return repo.LinkMeta{}, false
+		}
+		meta.Notes = &notes
+	}
+	if req.Tags != nil {
+		tags, err := shortlink.NormalizeTags(*req.Tags)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return repo.LinkMeta{}, false
+		}
+		meta.Tags = &tags
+	}
+	if req.FolderID != nil && *req.FolderID < 0 {
+		ctx.AbortWithError(http.StatusBadRequest, "invalid folder_id")
+		return repo.LinkMeta{}, false
+	}
+	return meta, true
+}
+
+// NewUpdateLinkMetaHandler 修改自己名下短链的标题、备注、标签、文件夹
+func NewUpdateLinkMetaHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req LinkMetaRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		meta, ok := parseLinkMeta(ctx, req)
+		if !ok {
+			return
+		}
+		if err := r.UpdateLinkMeta(ctx.Req.Context(), userID, code, meta); err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) || errors.Is(err, repo.ErrFolderNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+}
+
+func NewListFoldersHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		folders, err := r.ListFolders(ctx.Req.Context(), userID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.JSON(http.StatusOK, folders)
+	}
+}
+
+func NewCreateFolderHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req FolderRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if err := shortlink.ValidateFolderName(name); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		folder, err := r.CreateFolder(ctx.Req.Context(), userID, name)
+		if err != nil {
+			if errors.Is(err, repo.ErrFolderAlreadyExists) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.JSON(http.StatusCreated, folder)
+	}
+}
+
+func NewRenameFolderHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		folderID, ok := parseFolderID(ctx)
+		if !ok {
+			return
+		}
+		var req FolderRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if err := shortlink.ValidateFolderName(name); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := r.RenameFolder(ctx.Req.Context(), userID, folderID, name); err != nil {
+			if errors.Is(err, repo.ErrFolderNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrFolderAlreadyExists) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+}
+
+func NewDeleteFolderHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		folderID, ok := parseFolderID(ctx)
+		if !ok {
+			return
+		}
+		if err := r.DeleteFolder(ctx.Req.Context(), userID, folderID); err != nil {
+			if errors.Is(err, repo.ErrFolderNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+}
+
+// parseFolderID 解析路径参数 :id，失败时已写入 400 响应
+func parseFolderID(ctx *gee.Context) (int64, bool) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		ctx.AbortWithError(http.StatusBadRequest, "invalid folder id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,93 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/urlcheck"
+)
+
+// healthHistoryLimit 是健康状态接口返回的最近探测条数
+const healthHistoryLimit = 50
+
+type FallbackRequest struct {
+	FallbackURL string `json:"fallback_url"` // 空字符串表示取消
+}
+
+type FallbackResponse struct {
+	Code        string `json:"code"`
+	FallbackURL string `json:"fallback_url,omitempty"`
+}
+
+// NewUpdateFallbackHandler 设置自己短链的备用地址：PUT /api/v1/users/shortlinks/:code/fallback。
+//
+// 备用地址同样是跳转目标，与 url 一样要经过目的地域名策略与信誉检查。
+func NewUpdateFallbackHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req FallbackRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		req.FallbackURL = strings.TrimSpace(req.FallbackURL)
+		if req.FallbackURL != "" {
+			if err := shortlink.ValidateURL(req.FallbackURL); err != nil {
+				ctx.AbortWithError(http.StatusBadRequest, "invalid fallback_url: "+err.Error())
+				return
+			}
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		if req.FallbackURL != "" && (!mustPassPolicy(ctx, r, req.FallbackURL) || !mustPassReputation(ctx, screener, req.FallbackURL)) {
+			return
+		}
+
+		link, err := r.UpdateFallbackURL(ctx.Req.Context(), userID, code, req.FallbackURL)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, FallbackResponse{Code: link.Code, FallbackURL: link.FallbackURL})
+	}
+}
+
+// NewHealthHandler 查看自己短链默认目标的健康状态与最近的探测记录：GET /api/v1/users/shortlinks/:code/health
+func NewHealthHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		st, err := r.GetHealth(ctx.Req.Context(), code, healthHistoryLimit)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.JSON(http.StatusOK, st)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 
 	"day.local/gee"
+	"day.local/internal/app/shortlink/repo"
 	"day.local/internal/platform/auth"
 )
 
@@ -38,3 +39,51 @@ func tryGetUserID(ctx *gee.Context) (*int64, bool) {
 	}
 	return &userID, true
 }
+
+// mustOwnShortlink 校验短码是否在当前用户名下，失败时已写入错误响应。
+// 加入共享行的用户也在名下；能否修改由 repo 的 lockEditable 判断。
+func mustOwnShortlink(ctx *gee.Context, r *repo.ShortlinksRepo, userID int64, code string) bool {
+	owns, err := r.UserOwnsShortlink(ctx.Req.Context(), userID, code)
+	if err != nil {
+		ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+		return false
+	}
+	if !owns {
+		ctx.AbortWithError(http.StatusForbidden, "no permission")
+		return false
+	}
+	return true
+}
+
+// buildShortURL 根据请求的 Host 与 X-Forwarded-Proto 拼出完整短链
+func buildShortURL(ctx *gee.Context, code string) string {
+	path := "/" + code
+	if host := ctx.Req.Host; host != "" {
+		return requestScheme(ctx) + "://" + host + path
+	}
+	return path
+}
+
+// buildDomainShortURL 拼出自定义域名上的短链，协议沿用本次请求的
+func buildDomainShortURL(ctx *gee.Context, host, key string) string {
+	return requestScheme(ctx) + "://" + host + "/" + key
+}
+
+func requestScheme(ctx *gee.Context) string {
+	if scheme := ctx.Req.Header.Get("X-Forwarded-Proto"); scheme != "" {
+		return scheme
+	}
+	return "http"
+}
+
+// linkCookiePaths 返回按短链下发 cookie 时要用的 Path：cookie 的 Path "/{code}" 匹配不到
+// "/{code}+"、"/{code}~qr"，从预览页或扫码进来时带后缀的路径也要各下发一份。raw 是路由参数里的原始短码。
+func linkCookiePaths(raw string) []string {
+	code, _ := splitPreviewCode(raw)
+	code, _ = splitSourceCode(code)
+	paths := []string{"/" + code}
+	if raw != code {
+		paths = append(paths, "/"+raw)
+	}
+	return paths
+}
@@ -0,0 +1,48 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink/jobs"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/pagemeta"
+)
+
+// NewRefreshMetadataHandler 立即重新抓取自己短链目标页面的元信息：POST /api/v1/users/shortlinks/:code/metadata/refresh。
+//
+// 目标页面改了标题、或上次抓取失败想马上重试时使用；平时由后台任务自动抓取。
+// 抓取失败时同样保存失败原因，并返回 502。
+func NewRefreshMetadataHandler(r *repo.ShortlinksRepo, fetcher *pagemeta.Fetcher) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		link, err := r.Resolve(ctx.Req.Context(), code)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) || errors.Is(err, repo.ErrShortlinkExpired) {
+				ctx.AbortWithError(http.StatusNotFound, repo.ErrShortlinkNotFound.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		meta, err := jobs.FetchMetadata(ctx.Req.Context(), r, fetcher, link)
+		if err != nil {
+			if meta.Error == "" {
+				ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+				return
+			}
+			ctx.AbortWithError(http.StatusBadGateway, "metadata fetch failed: "+meta.Error)
+			return
+		}
+		ctx.JSON(http.StatusOK, meta)
+	}
+}
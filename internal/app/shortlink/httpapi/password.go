@@ -0,0 +1,85 @@
+package httpapi
+
+import (
+	"embed"
+	"net/http"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+// unlockCookiePrefix + code 为“已输入密码”凭证的 cookie 名；Path 限定为访问路径 /{code}（自定义域名上是 /{key}），每条短链独立。
+const unlockCookiePrefix = "sl_unlock_"
+
+type passwordPage struct {
+	Action string
+	Error  string
+}
+
+// renderPasswordPage 渲染密码输入页。页面不能被缓存，否则共享缓存可能把别人的访问结果复用给你。
+func renderPasswordPage(ctx *gee.Context, status int, msg string) {
+	ctx.SetHeader("Cache-Control", "no-store")
+	ctx.SetHeader("X-Robots-Tag", "noindex")
+	ctx.HTML(status, "password.html", passwordPage{Action: linkPath(ctx), Error: msg})
+}
+
+// linkPath 返回本次访问的短链路径（/{code} 及其后的剩余路径），带上查询参数：输完密码回到跳转时不丢透传的路径与参数
+func linkPath(ctx *gee.Context) string {
+	if q := ctx.Req.URL.RawQuery; q != "" {
+		return ctx.Req.URL.EscapedPath() + "?" + q
+	}
+	return ctx.Req.URL.EscapedPath()
+}
+
+// unlocked 判断访问者是否持有该短链有效的密码凭证
+func unlocked(ctx *gee.Context, unlock *shortlink.UnlockSigner, link shortlink.Shortlink) bool {
+	c, err := ctx.Req.Cookie(unlockCookiePrefix + link.Code)
+	if err != nil {
+		return false
+	}
+	return unlock.Verify(link, c.Value, time.Now())
+}
+
+// NewUnlockHandler 处理密码页提交：校验 bcrypt 哈希，成功后下发短时 cookie 并回到 GET /:code（带剩余路径与参数）完成跳转。
+//
+// 设计原因：
+// - 303 回到 GET /:code 而不是直接跳目标地址：跳转头、点击统计只在一处处理
+// - 暴力尝试由路由上的 RateLimit 约束
+func NewUnlockHandler(r *repo.ShortlinksRepo, unlock *shortlink.UnlockSigner) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		raw := ctx.Param("code")
+		code, _ := splitPreviewCode(raw)
+		code, _ = splitSourceCode(code)
+		link, err := r.ResolveHost(ctx.Req.Context(), shortlink.NormalizeHost(ctx.Req.Host), code)
+		if err != nil {
+			abortResolveError(ctx, err)
+			return
+		}
+		if link.Protected() {
+			if !link.CheckPassword(ctx.PostForm("password")) {
+				renderPasswordPage(ctx, http.StatusUnauthorized, "密码错误")
+				return
+			}
+			value := unlock.Sign(link, time.Now())
+			for _, path := range linkCookiePaths(raw) {
+				http.SetCookie(ctx.Writer, &http.Cookie{
+					Name:     unlockCookiePrefix + link.Code,
+					Value:    value,
+					Path:     path,
+					MaxAge:   int(unlock.TTL().Seconds()),
+					HttpOnly: true,
+					Secure:   ctx.Req.TLS != nil || ctx.Req.Header.Get("X-Forwarded-Proto") == "https",
+					SameSite: http.SameSiteLaxMode,
+				})
+			}
+		}
+		ctx.SetHeader("Cache-Control", "no-store")
+		ctx.SetHeader("Location", linkPath(ctx))
+		ctx.Status(http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,78 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+type ForwardPathRequest struct {
+	ForwardPath bool `json:"forward_path"`
+}
+
+type ForwardPathResponse struct {
+	Code        string `json:"code"`
+	ForwardPath bool   `json:"forward_path"`
+}
+
+// pathSegments 把路由参数 *path（"a/b/c"）拆成路径段，空段忽略
+func pathSegments(path string) []string {
+	if path == "" {
+		return nil
+	}
+	var out []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			out = append(out, seg)
+		}
+	}
+	return out
+}
+
+// abortPathError 映射剩余路径的错误：短链不接受路径时与短码不存在一样返回 404，模板缺段返回 400
+func abortPathError(ctx *gee.Context, err error) {
+	if errors.Is(err, shortlink.ErrMissingSegment) {
+		ctx.AbortWithError(http.StatusBadRequest, err.Error())
+		return
+	}
+	ctx.AbortWithError(http.StatusNotFound, "url not found")
+}
+
+// NewUpdateForwardPathHandler 修改自己短链的路径透传开关：PUT /api/v1/users/shortlinks/:code/forward-path。
+//
+// 目标地址是模板（含 {1}、{path}）时不需要打开开关，剩余路径总会用来替换占位符。
+func NewUpdateForwardPathHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req ForwardPathRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+
+		link, err := r.UpdateForwardPath(ctx.Req.Context(), userID, code, req.ForwardPath)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, ForwardPathResponse{Code: link.Code, ForwardPath: link.ForwardPath})
+	}
+}
@@ -0,0 +1,134 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+type PolicyRequest struct {
+	Pattern string                 `json:"pattern"`
+	Action  shortlink.PolicyAction `json:"action"`
+	Note    string                 `json:"note,omitempty"`
+}
+
+type PolicyModeRequest struct {
+	AllowlistOnly bool `json:"allowlist_only"`
+}
+
+type PoliciesResponse struct {
+	AllowlistOnly bool               `json:"allowlist_only"`
+	Items         []repo.PolicyEntry `json:"items"`
+}
+
+// mustPassPolicy 按目的地域名策略检查目标地址，失败时已写入错误响应：
+// 被拒绝返回 403，错误信息以 "destination domain denied by policy" 或 "destination domain not in allowlist" 开头。
+//
+// 策略加载失败时拒绝（503），而不是放行：策略是防滥用的最后一道关。
+func mustPassPolicy(ctx *gee.Context, r *repo.ShortlinksRepo, urls ...string) bool {
+	policy, err := r.DestinationPolicy(ctx.Req.Context())
+	if err != nil {
+		ctx.AbortWithError(http.StatusServiceUnavailable, "destination policy unavailable")
+		return false
+	}
+	if err := policy.Check(urls...); err != nil {
+		ctx.AbortWithError(http.StatusForbidden, err.Error())
+		return false
+	}
+	return true
+}
+
+// NewListPoliciesHandler 列出目的地域名策略：GET /api/v1/admin/destination-policies
+func NewListPoliciesHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		items, allowlistOnly, err := r.ListPolicies(ctx.Req.Context())
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.JSON(http.StatusOK, PoliciesResponse{AllowlistOnly: allowlistOnly, Items: items})
+	}
+}
+
+// NewAddPolicyHandler 新增策略：POST /api/v1/admin/destination-policies。
+//
+// 新增后由 PolicyRechecker 在后台复查已有短链，命中的会被停用。
+func NewAddPolicyHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req PolicyRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		pattern, err := shortlink.NormalizePolicyPattern(req.Pattern)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := shortlink.ValidatePolicyAction(req.Action); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		note := strings.TrimSpace(req.Note)
+		if err := shortlink.ValidateReason(note); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		entry, err := r.AddPolicy(ctx.Req.Context(), shortlink.PolicyRule{Pattern: pattern, Action: req.Action}, note, userID)
+		if err != nil {
+			if errors.Is(err, repo.ErrPolicyAlreadyExists) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "policy create failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, entry)
+	}
+}
+
+// NewDeletePolicyHandler 删除策略：DELETE /api/v1/admin/destination-policies/:id。
+//
+// 已因该策略停用的短链不会自动恢复，需管理员逐条确认后恢复。
+func NewDeletePolicyHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
+		if err != nil || id <= 0 {
+			ctx.AbortWithError(http.StatusBadRequest, "invalid policy id")
+			return
+		}
+		if err := r.DeletePolicy(ctx.Req.Context(), id); err != nil {
+			if errors.Is(err, repo.ErrPolicyNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+}
+
+// NewSetPolicyModeHandler 打开/关闭只放行 allow 列表模式：PUT /api/v1/admin/destination-policies/mode
+func NewSetPolicyModeHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		var req PolicyModeRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		if err := r.SetAllowlistOnly(ctx.Req.Context(), req.AllowlistOnly); err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.JSON(http.StatusOK, req)
+	}
+}
@@ -0,0 +1,140 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+// previewSuffix 加在短码后面表示只预览不跳转：/{code}+。短码只含字母数字，不会与之冲突。
+const previewSuffix = "+"
+
+type previewPage struct {
+	Title       string // 目标页面标题（抓取到的元信息）
+	Host        string
+	Destination string
+	CreatedAt   string
+	ContinueURL string
+	Forced      bool // 管理员强制开启
+	Dynamic     bool // 实际目标因访客而不同，展示的是默认目标
+}
+
+type PreviewRequest struct {
+	Preview bool `json:"preview"`
+}
+
+type PreviewResponse struct {
+	Code    string `json:"code"`
+	Preview bool   `json:"preview"`
+}
+
+type ForcePreviewRequest struct {
+	Forced bool `json:"forced"`
+}
+
+// splitPreviewCode 拆出路由参数里的预览后缀
+func splitPreviewCode(code string) (string, bool) {
+	if rest, ok := strings.CutSuffix(code, previewSuffix); ok && rest != "" {
+		return rest, true
+	}
+	return code, false
+}
+
+// continuePath 返回预览页“继续访问”的地址：去掉预览后缀的 /{code}，保留剩余路径与查询参数
+func continuePath(ctx *gee.Context, code string, segments []string) string {
+	p := "/" + url.PathEscape(code)
+	for _, seg := range segments {
+		p += "/" + url.PathEscape(seg)
+	}
+	if q := ctx.Req.URL.RawQuery; q != "" {
+		p += "?" + q
+	}
+	return p
+}
+
+// renderPreviewPage 渲染预览页：展示目标页面标题、目标地址、所在站点与创建时间，由访客点击“继续访问”前往 continueURL。
+//
+// 目标地址都经过 ValidateURL（只允许 http/https），html/template 会原样输出到 href；页面不缓存、不带 Referer。
+func renderPreviewPage(ctx *gee.Context, r *repo.ShortlinksRepo, link shortlink.Shortlink, dest, continueURL string) {
+	page := previewPage{
+		Destination: dest,
+		ContinueURL: continueURL,
+		Forced:      link.PreviewForced,
+		Dynamic:     link.DynamicDestination(),
+	}
+	if u, err := url.Parse(dest); err == nil {
+		page.Host = u.Hostname()
+	}
+	// 创建时间不在缓存里，预览页访问量小，直接查一次；失败时不展示
+	if data, err := r.FindByCode(ctx.Req.Context(), link.Code); err == nil {
+		page.CreatedAt = data.CreatedAt.Format("2006-01-02")
+		// 元信息按默认目标抓取，跳到备用地址等其他目标时不展示
+		if data.Metadata != nil && dest == link.URL {
+			page.Title = data.Metadata.Title
+		}
+	}
+	ctx.SetHeader("Cache-Control", "private, no-store")
+	ctx.SetHeader("X-Robots-Tag", "noindex")
+	ctx.SetHeader("Referrer-Policy", "no-referrer")
+	ctx.HTML(http.StatusOK, "preview.html", page)
+}
+
+// NewUpdatePreviewHandler 修改自己短链的预览页开关：PUT /api/v1/users/shortlinks/:code/preview。
+//
+// 管理员强制开启的预览页不受影响，拥有者关闭后仍会展示。
+func NewUpdatePreviewHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req PreviewRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+
+		link, err := r.UpdatePreview(ctx.Req.Context(), userID, code, req.Preview)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, PreviewResponse{Code: link.Code, Preview: link.Preview})
+	}
+}
+
+// NewForcePreviewHandler 管理员对可疑短链强制开启/取消预览页：PUT /api/v1/admin/shortlinks/:code/preview
+func NewForcePreviewHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		var req ForcePreviewRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		if err := r.ForcePreview(ctx.Req.Context(), code, req.Forced); err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		ctx.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,181 @@
+package httpapi
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"image/color"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/qrcode"
+)
+
+// qrSuffix 加在短码后面表示这次访问来自扫码：/{code}~qr。二维码接口生成的码都编码这个地址，
+// 跳转照常进行，点击记录 source=qr。短码只含字母数字，不会与之冲突。
+const qrSuffix = "~qr"
+
+// sourceQR 是扫码访问记录的点击来源
+const sourceQR = "qr"
+
+// 二维码参数的默认值与范围
+const (
+	qrDefaultSize   = 256
+	qrMinSize       = 64
+	qrMaxSize       = 2048
+	qrDefaultMargin = 4
+	qrMaxMargin     = 16
+)
+
+var errInvalidQRFormat = errors.New("format must be png or svg")
+var errInvalidQRSize = errors.New("size must be between 64 and 2048")
+var errInvalidQRMargin = errors.New("margin must be between 0 and 16")
+var errInvalidQRLevel = errors.New("ecc must be one of L, M, Q, H")
+var errInvalidQRColor = errors.New("fg and bg must be hex colors like 000000 or #fff")
+var errQRNoContrast = errors.New("fg and bg must differ")
+var errInvalidQRLogo = errors.New("logo must be true or false")
+
+// splitSourceCode 拆出路由参数里的扫码后缀，返回短码与点击来源
+func splitSourceCode(code string) (string, string) {
+	if rest, ok := strings.CutSuffix(code, qrSuffix); ok && rest != "" {
+		return rest, sourceQR
+	}
+	return code, ""
+}
+
+// parseQROptions 解析二维码接口的查询参数，未给出的用默认值：png、256 像素、静区 4、纠错 M、黑码白底。
+func parseQROptions(q url.Values) (qrcode.Options, error) {
+	opts := qrcode.Options{
+		Format: qrcode.PNG,
+		Size:   qrDefaultSize,
+		Margin: qrDefaultMargin,
+		Level:  qrcode.LevelM,
+		FG:     color.RGBA{A: 0xff},
+		BG:     color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff},
+	}
+	switch f := strings.ToLower(q.Get("format")); f {
+	case "", "png":
+	case "svg":
+		opts.Format = qrcode.SVG
+	default:
+		return opts, errInvalidQRFormat
+	}
+	if v := q.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < qrMinSize || n > qrMaxSize {
+			return opts, errInvalidQRSize
+		}
+		opts.Size = n
+	}
+	if v := q.Get("margin"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > qrMaxMargin {
+			return opts, errInvalidQRMargin
+		}
+		opts.Margin = n
+	}
+	if v := q.Get("ecc"); v != "" {
+		level, ok := qrcode.ParseLevel(v)
+		if !ok {
+			return opts, errInvalidQRLevel
+		}
+		opts.Level = level
+	}
+	for _, c := range []struct {
+		key string
+		dst *color.RGBA
+	}{{"fg", &opts.FG}, {"bg", &opts.BG}} {
+		if v := q.Get(c.key); v != "" {
+			parsed, ok := parseHexColor(v)
+			if !ok {
+				return opts, errInvalidQRColor
+			}
+			*c.dst = parsed
+		}
+	}
+	if opts.FG == opts.BG {
+		return opts, errQRNoContrast
+	}
+	if v := q.Get("logo"); v != "" {
+		logo, err := strconv.ParseBool(v)
+		if err != nil {
+			return opts, errInvalidQRLogo
+		}
+		opts.Logo = logo
+	}
+	return opts, nil
+}
+
+// parseHexColor 解析 "rgb"、"rrggbb"（可带 #）格式的颜色
+func parseHexColor(s string) (color.RGBA, bool) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, false
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{R: b[0], G: b[1], B: b[2], A: 0xff}, true
+}
+
+// NewQRHandler 生成短链的二维码：GET /qr/{code}?format=png|svg&size=&margin=&ecc=&fg=&bg=&logo=。
+// 不接受剩余路径的短链（没开路径透传、目标不是模板）也可以用 /{code}/qr，见 NewRedirectHandler。
+//
+// 二维码编码的是 /{code}~qr，扫码访问照常跳转，点击记录 source=qr，便于区分线下物料带来的访问。
+// 同一个地址与参数生成的图片总是相同，允许公共缓存一天，并支持 ETag 条件请求。
+//
+// 设计原因：
+// - 放在公开路由下、不需要登录：印刷厂等第三方可以直接用地址拉取高清 SVG
+// - 短链不存在或已过期时不生成，避免给失效的链接印刷物料
+// - 自定义域名上按请求的 Host 解析，码里编码的也是该域名下的地址
+// - /qr/{code} 对所有短链都可用；/{code}/qr 在开启路径透传的短链上会被透传，对外文档以 /qr/{code} 为准
+func NewQRHandler(r *repo.ShortlinksRepo, renderer *qrcode.Renderer) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		key := ctx.Param("code")
+		if _, err := r.ResolveHost(ctx.Req.Context(), shortlink.NormalizeHost(ctx.Req.Host), key); err != nil {
+			abortResolveError(ctx, err)
+			return
+		}
+		serveQR(ctx, renderer, key)
+	}
+}
+
+// serveQR 为已解析成功的短链 key（自定义域名上是域名内的 key）输出二维码图片
+func serveQR(ctx *gee.Context, renderer *qrcode.Renderer, key string) {
+	opts, err := parseQROptions(ctx.Req.URL.Query())
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err.Error())
+		return
+	}
+	content := requestScheme(ctx) + "://" + ctx.Req.Host + "/" + url.PathEscape(key) + qrSuffix
+	body, err := renderer.Render(content, opts)
+	if err != nil {
+		if errors.Is(err, qrcode.ErrSizeTooSmall) || errors.Is(err, qrcode.ErrNoLogo) {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, "qr render failed")
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	ctx.SetHeader("ETag", etag)
+	ctx.SetHeader("Cache-Control", "public, max-age=86400")
+	if match := ctx.Req.Header.Get("If-None-Match"); match != "" && strings.Contains(match, etag) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+	ctx.SetHeader("Content-Type", opts.Format.ContentType())
+	ctx.SetHeader("Content-Disposition", `inline; filename="`+key+`-qr.`+string(opts.Format)+`"`)
+	ctx.Data(http.StatusOK, body)
+}
@@ -1,15 +1,22 @@
 package httpapi
 
 import (
+	"html/template"
 	"net/http"
 	"time"
 
 	"day.local/gee"
+	"day.local/internal/app/shortlink"
 	"day.local/internal/app/shortlink/repo"
 	"day.local/internal/app/shortlink/stats"
 	"day.local/internal/platform/auth"
+	"day.local/internal/platform/dnsverify"
+	"day.local/internal/platform/geoip"
 	"day.local/internal/platform/httpmiddleware"
+	"day.local/internal/platform/pagemeta"
+	"day.local/internal/platform/qrcode"
 	"day.local/internal/platform/ratelimit"
+	"day.local/internal/platform/urlcheck"
 )
 
 // RegisterAPIRoutes 用于在给定的路由分组下挂载短链 API 路由（例如 /api/v1）。
@@ -20,11 +27,15 @@ import (
 // 设计原因：
 // - cmd/api 只负责"组装"和"挂载"，各业务模块自己提供 Register*Routes，避免路由散落在 main.go
 // - API 路由一般用于机器调用（JSON），统一放在 /api/v1 下便于版本化
-func RegisterAPIRoutes(api *gee.RouterGroup, slRepo *repo.ShortlinksRepo, usersRepo *repo.UsersRepo, ts auth.TokenService, limiter *ratelimit.Limiter) {
+func RegisterAPIRoutes(api *gee.RouterGroup, slRepo *repo.ShortlinksRepo, usersRepo *repo.UsersRepo, ts auth.TokenService, limiter *ratelimit.Limiter, verifier dnsverify.Verifier, screener *urlcheck.Screener, fetcher *pagemeta.Fetcher) {
 	//无需登录的路由
 	api.Use(httpmiddleware.AuthOptional(ts))
 	//创建短链 限流 10次/分钟
-	api.POST("/shortlinks", httpmiddleware.RateLimit(limiter, "create", 10, time.Minute), NewCreateHandler(slRepo))
+	api.POST("/shortlinks", httpmiddleware.RateLimit(limiter, "create", 10, time.Minute), NewCreateHandler(slRepo, screener))
+	//批量创建 需登录，整批算一次 10次/分钟
+	api.POST("/shortlinks:batch", httpmiddleware.RateLimit(limiter, "create_batch", 10, time.Minute), NewCreateBatchHandler(slRepo, screener))
+	//自定义短码可用性 前端边输入边查，限流 60次/分钟；静态段优先，availability 已列为保留短码
+	api.GET("/shortlinks/availability", httpmiddleware.RateLimit(limiter, "availability", 60, time.Minute), NewAvailabilityHandler(slRepo))
 	api.GET("/shortlinks/:code", NewFindShortlinksHandler(slRepo))
 	//注册 3次/分钟
 	api.POST("/register", httpmiddleware.RateLimit(limiter, "register", 3, time.Minute), NewRegistUserHandler(usersRepo))
@@ -36,8 +47,35 @@ func RegisterAPIRoutes(api *gee.RouterGroup, slRepo *repo.ShortlinksRepo, usersR
 	users.Use(httpmiddleware.AuthRequired(ts))
 	users.GET("/me", NewUserMeHandler())
 	users.GET("/mine", NewMineHandler(slRepo))
+	users.GET("/mine/export", NewExportHandler(slRepo))
+	//导入 5次/分钟
+	users.POST("/mine/import", httpmiddleware.RateLimit(limiter, "import", 5, time.Minute), NewImportHandler(slRepo, screener))
 	users.DELETE("/mine/:code", NewRemoveFromMineHandler(slRepo))
+	users.PATCH("/mine/:code", NewUpdateLinkMetaHandler(slRepo))
+	users.GET("/folders", NewListFoldersHandler(slRepo))
+	users.POST("/folders", NewCreateFolderHandler(slRepo))
+	users.PATCH("/folders/:id", NewRenameFolderHandler(slRepo))
+	users.DELETE("/folders/:id", NewDeleteFolderHandler(slRepo))
+	users.GET("/domains", NewListDomainsHandler(slRepo))
+	users.POST("/domains", NewCreateDomainHandler(slRepo))
+	//校验会查询 DNS 5次/分钟
+	users.POST("/domains/:host/verify", httpmiddleware.RateLimit(limiter, "verify_domain", 5, time.Minute), NewVerifyDomainHandler(slRepo, verifier))
+	users.DELETE("/domains/:host", NewDeleteDomainHandler(slRepo))
 	users.GET("/shortlinks/:code/stats", NewGetStatsHandler(slRepo))
+	users.GET("/shortlinks/:code/stats/variants", NewVariantStatsHandler(slRepo))
+	users.PATCH("/shortlinks/:code", NewUpdateShortlinkHandler(slRepo, screener))
+	users.PUT("/shortlinks/:code/rules", NewUpdateRulesHandler(slRepo, screener))
+	users.PUT("/shortlinks/:code/variants", NewUpdateVariantsHandler(slRepo, screener))
+	users.PUT("/shortlinks/:code/deeplink", NewUpdateDeepLinkHandler(slRepo, screener))
+	users.PUT("/shortlinks/:code/utm", NewUpdateUTMHandler(slRepo))
+	users.PUT("/shortlinks/:code/forward-path", NewUpdateForwardPathHandler(slRepo))
+	users.PUT("/shortlinks/:code/preview", NewUpdatePreviewHandler(slRepo))
+	users.PUT("/shortlinks/:code/fallback", NewUpdateFallbackHandler(slRepo, screener))
+	users.GET("/shortlinks/:code/health", NewHealthHandler(slRepo))
+	//立即重新抓取目标页面元信息 会访问外部站点，限流 10次/分钟
+	users.POST("/shortlinks/:code/metadata/refresh", httpmiddleware.RateLimit(limiter, "metadata_refresh", 10, time.Minute), NewRefreshMetadataHandler(slRepo, fetcher))
+	users.POST("/shortlinks/:code/disable", NewOwnerDisableHandler(slRepo))
+	users.POST("/shortlinks/:code/enable", NewOwnerEnableHandler(slRepo))
 
 	//需要管理员的路由
 
@@ -48,6 +86,12 @@ func RegisterAPIRoutes(api *gee.RouterGroup, slRepo *repo.ShortlinksRepo, usersR
 		ctx.String(http.StatusOK, "pong")
 	})
 	admin.POST("/shortlinks/:code/disable", NewDisablesHandler(slRepo))
+	admin.POST("/shortlinks/:code/enable", NewEnableHandler(slRepo))
+	admin.PUT("/shortlinks/:code/preview", NewForcePreviewHandler(slRepo))
+	admin.GET("/destination-policies", NewListPoliciesHandler(slRepo))
+	admin.POST("/destination-policies", NewAddPolicyHandler(slRepo))
+	admin.DELETE("/destination-policies/:id", NewDeletePolicyHandler(slRepo))
+	admin.PUT("/destination-policies/mode", NewSetPolicyModeHandler(slRepo))
 
 }
 
@@ -58,7 +102,22 @@ func RegisterAPIRoutes(api *gee.RouterGroup, slRepo *repo.ShortlinksRepo, usersR
 // 设计原因：
 // - “短链”的使用体验是直接访问 /r/{code}，而不是 /api/v1/...
 // - 将 public 与 api 分开，后续做域名拆分（s.example.com 与 api.example.com）更顺滑
-func RegisterPublicRoutes(engine *gee.Engine, r *repo.ShortlinksRepo, collector stats.Collector, limiter *ratelimit.Limiter) {
+func RegisterPublicRoutes(engine *gee.Engine, r *repo.ShortlinksRepo, collector stats.Collector, limiter *ratelimit.Limiter, unlock *shortlink.UnlockSigner, geo geoip.CountryResolver, qr *qrcode.Renderer) {
+	// 密码页、App 唤起页等公开页面的模板随二进制一起嵌入
+	engine.SetHTMLTemplate(template.Must(template.ParseFS(templateFS, "templates/*.html")))
+
 	//跳转 100次/分钟
-	engine.GET("/:code", httpmiddleware.RateLimit(limiter, "redirect", 100, time.Minute), NewRedirectHandler(r, collector))
+	redirect := NewRedirectHandler(r, collector, unlock, geo, qr)
+	engine.GET("/:code", httpmiddleware.RateLimit(limiter, "redirect", 100, time.Minute), redirect)
+	// 路径透传与目标模板：/{code}/剩余路径，与 /{code} 共用限流额度。
+	// 自定义域名上是 /{key}，按请求的 Host 解析（见 ResolveHost）；/{code}+ 为预览页。
+	// 不接受路径的短链上 /{code}/qr 返回二维码，计入跳转的限流额度
+	engine.GET("/:code/*path", httpmiddleware.RateLimit(limiter, "redirect", 100, time.Minute), redirect)
+	//二维码 30次/分钟；/qr/{code} 对所有短链可用，不占用路径透传短链的 /{code}/qr。
+	// 短码至少 3 个字符，不会有叫 qr 的短码被静态段挡住
+	engine.GET("/qr/:code", httpmiddleware.RateLimit(limiter, "qr", 30, time.Minute), NewQRHandler(r, qr))
+	//提交访问密码 5次/分钟，防止暴力破解
+	unlockHandler := NewUnlockHandler(r, unlock)
+	engine.POST("/:code", httpmiddleware.RateLimit(limiter, "unlock", 5, time.Minute), unlockHandler)
+	engine.POST("/:code/*path", httpmiddleware.RateLimit(limiter, "unlock", 5, time.Minute), unlockHandler)
 }
@@ -0,0 +1,64 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/metrics"
+	"day.local/internal/platform/urlcheck"
+)
+
+// mustPassReputation 用信誉服务检查目标地址，失败时已写入错误响应：
+// 被标记返回 403（"destination flagged as unsafe: <威胁类型>"），fail closed 且查询失败返回 503。
+func mustPassReputation(ctx *gee.Context, s *urlcheck.Screener, urls ...string) bool {
+	matches, err := s.Screen(ctx.Req.Context(), urls)
+	if err != nil {
+		if errors.Is(err, urlcheck.ErrUnavailable) {
+			ctx.AbortWithError(http.StatusServiceUnavailable, err.Error())
+			return false
+		}
+		ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+		return false
+	}
+	if len(matches) > 0 {
+		metrics.URLCheckFlagged.WithLabelValues("create").Inc()
+		ctx.AbortWithError(http.StatusForbidden, matches[0].Reason())
+		return false
+	}
+	return true
+}
+
+// screenBatchItems 一次查询整批条目的目标地址，去掉被标记的条目，并对每条调用 reject(原下标, 命中项) 回报；
+// 返回保留的 items 与对应的 indexes。fail closed 且查询失败时已写入 503 响应，ok 为 false。
+func screenBatchItems(ctx *gee.Context, s *urlcheck.Screener, items []repo.BatchItem, indexes []int, reject func(int, urlcheck.Match)) ([]repo.BatchItem, []int, bool) {
+	urls := make([]string, len(items))
+	for j, item := range items {
+		urls[j] = item.Link.URL
+	}
+	matches, err := s.Screen(ctx.Req.Context(), urls)
+	if err != nil {
+		ctx.AbortWithError(http.StatusServiceUnavailable, err.Error())
+		return nil, nil, false
+	}
+	if len(matches) == 0 {
+		return items, indexes, true
+	}
+
+	flagged := make(map[string]urlcheck.Match, len(matches))
+	for _, m := range matches {
+		flagged[m.URL] = m
+	}
+	keptItems, keptIndexes := items[:0], indexes[:0]
+	for j, item := range items {
+		if m, ok := flagged[item.Link.URL]; ok {
+			metrics.URLCheckFlagged.WithLabelValues("create").Inc()
+			reject(indexes[j], m)
+			continue
+		}
+		keptItems = append(keptItems, item)
+		keptIndexes = append(keptIndexes, indexes[j])
+	}
+	return keptItems, keptIndexes, true
+}
@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/geoip"
+	"day.local/internal/platform/urlcheck"
+)
+
+type RulesRequest struct {
+	Rules []shortlink.RedirectRule `json:"rules"`
+}
+
+type RulesResponse struct {
+	Code  string                   `json:"code"`
+	Rules []shortlink.RedirectRule `json:"rules"`
+}
+
+// visitorFrom 从请求中提取规则匹配需要的访客信息；geo 为 nil 时国家未知
+func visitorFrom(ctx *gee.Context, geo geoip.CountryResolver) shortlink.Visitor {
+	v := shortlink.Visitor{
+		Language: shortlink.PreferredLanguage(ctx.Req.Header.Get("Accept-Language")),
+		Now:      time.Now(),
+	}
+	v.Device, v.OS = shortlink.DetectDevice(ctx.Req.UserAgent())
+	if geo != nil {
+		v.Country = geo.Country(ctx.Req)
+	}
+	return v
+}
+
+// NewUpdateRulesHandler 替换自己短链的条件跳转规则：PUT /api/v1/users/shortlinks/:code/rules。
+//
+// 规则整体替换而不是逐条增删：规则有顺序，先匹配先生效，整体提交更不容易出错；rules 为空数组即清除。
+func NewUpdateRulesHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req RulesRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		rules, err := shortlink.NormalizeRules(req.Rules)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		urls := shortlink.Shortlink{Rules: rules}.Destinations()[1:]
+		if !mustPassPolicy(ctx, r, urls...) || !mustPassReputation(ctx, screener, urls...) {
+			return
+		}
+
+		link, err := r.UpdateRules(ctx.Req.Context(), userID, code, rules)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		if link.Rules == nil {
+			link.Rules = []shortlink.RedirectRule{}
+		}
+		ctx.JSON(http.StatusOK, RulesResponse{Code: link.Code, Rules: link.Rules})
+	}
+}
@@ -10,8 +10,11 @@ import (
 	"day.local/internal/app/shortlink"
 	"day.local/internal/app/shortlink/repo"
 	"day.local/internal/app/shortlink/stats"
+	"day.local/internal/platform/geoip"
 	"day.local/internal/platform/httpmiddleware"
 	"day.local/internal/platform/metrics"
+	"day.local/internal/platform/qrcode"
+	"day.local/internal/platform/urlcheck"
 )
 
 // NOTE: 本包目前是短链 MVP handlers 的占位。
@@ -26,18 +29,56 @@ import (
 // - 未来加 blog 时也可以遵循同样模式：internal/app/blog/httpapi + internal/app/blog
 
 type ShortLinksRequest struct {
-	URL      string `json:"url"`
-	ExpireIn string `json:"expire_in,omitempty"`
-	Code     string `json:"code,omitempty"`
+	URL          string     `json:"url"`
+	ExpireIn     string     `json:"expire_in,omitempty"`  // 相对过期时间，例如 "24h"、"7d"
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"` // 绝对过期时间（RFC3339），与 expire_in 二选一
+	Code         string     `json:"code,omitempty"`
+	RedirectType int        `json:"redirect_type,omitempty"` // 301/302/307/308，默认 302
+	CacheControl string     `json:"cache_control,omitempty"` // 跳转响应的 Cache-Control
+	RobotsTag    string     `json:"robots_tag,omitempty"`    // 跳转响应的 X-Robots-Tag
+	Password     string     `json:"password,omitempty"`      // 访问密码，设置后跳转前需先输入
+	MaxClicks    int64      `json:"max_clicks,omitempty"`    // 点击上限，达到后自动停用；1 即一次性链接
+	Private      bool       `json:"private,omitempty"`       // 私有短链：不与其他用户共享同一 url 的短码，需登录
+	// 标题、备注、标签、文件夹：记在创建者名下，需登录
+	Title    *string   `json:"title,omitempty"`
+	Notes    *string   `json:"notes,omitempty"`
+	Tags     *[]string `json:"tags,omitempty"`
+	FolderID *int64    `json:"folder_id,omitempty"`
+	// 条件跳转规则，按顺序匹配，都不匹配时跳到 url
+	Rules []shortlink.RedirectRule `json:"rules,omitempty"`
+	// A/B 分流版本（按权重分配点击），sticky_variant 让同一访客固定同一版本
+	Variants      []shortlink.Variant `json:"variants,omitempty"`
+	StickyVariant bool                `json:"sticky_variant,omitempty"`
+	// 移动端 App 跳转：iOS/Android 访客唤起 App，没装时去商店
+	DeepLink *shortlink.DeepLink `json:"deep_link,omitempty"`
+	// 跳转时合并进目标地址的 UTM 参数；forward_query 把短链上的查询参数透传给目标
+	UTM          *shortlink.UTM `json:"utm,omitempty"`
+	ForwardQuery bool           `json:"forward_query,omitempty"`
+	// 路径透传：/{code}/a/b 把 a/b 拼到目标地址后面；目标地址是模板（{1}、{path}）时无需开启
+	ForwardPath bool `json:"forward_path,omitempty"`
+	// 预览页：每次访问先展示目标地址，由访客确认后再跳转
+	Preview bool `json:"preview,omitempty"`
+	// 备用地址：健康检查判定 url 故障时改跳到这里，恢复后自动切回
+	FallbackURL string `json:"fallback_url,omitempty"`
+	// 自定义域名：需登录且域名已校验，创建的是该域名下的私有短链，code 为域名内的访问路径
+	Domain string `json:"domain,omitempty"`
 }
 
 type ShortLinksResponse struct {
-	Code     string `json:"code"`
-	ShortURL string `json:"short_url"`
-	URL      string `json:"url"`
+	Code         string     `json:"code"`
+	ShortURL     string     `json:"short_url"`
+	URL          string     `json:"url"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectType int        `json:"redirect_type"`
+	CacheControl string     `json:"cache_control,omitempty"`
+	RobotsTag    string     `json:"robots_tag,omitempty"`
+	Protected    bool       `json:"protected,omitempty"`
+	MaxClicks    int64      `json:"max_clicks,omitempty"`
+	Private      bool       `json:"private,omitempty"`
+	Domain       string     `json:"domain,omitempty"`
 }
 
-func NewCreateHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+func NewCreateHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
 	return func(ctx *gee.Context) {
 		var req ShortLinksRequest
 		if err := ctx.BindJSON(&req); err != nil {
@@ -47,6 +88,12 @@ func NewCreateHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
 			ctx.AbortWithError(http.StatusBadRequest, err.Error())
 			return
 		}
+		if req.FallbackURL != "" {
+			if err := shortlink.ValidateURL(req.FallbackURL); err != nil {
+				ctx.AbortWithError(http.StatusBadRequest, "invalid fallback_url: "+err.Error())
+				return
+			}
+		}
 		customCode := strings.TrimSpace(req.Code)
 		if customCode != "" {
 			if err := shortlink.ValidateCode(customCode); err != nil {
@@ -54,18 +101,147 @@ func NewCreateHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
 				return
 			}
 		}
+		expiresAt, ok := parseExpiry(ctx, req.ExpireIn, req.ExpiresAt)
+		if !ok {
+			return
+		}
+		if err := shortlink.ValidateRedirectType(req.RedirectType); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		req.CacheControl = strings.TrimSpace(req.CacheControl)
+		req.RobotsTag = strings.TrimSpace(req.RobotsTag)
+		if err := shortlink.ValidateHeaderValue(req.CacheControl); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := shortlink.ValidateHeaderValue(req.RobotsTag); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+
+		if err := shortlink.ValidateMaxClicks(req.MaxClicks); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		rules, err := shortlink.NormalizeRules(req.Rules)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		variants, err := normalizeVariants(req.Variants, req.StickyVariant)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		deepLink, err := shortlink.NormalizeDeepLink(req.DeepLink)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		utm, err := shortlink.NormalizeUTM(req.UTM)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		var passwordHash string
+		if req.Password != "" {
+			if err := shortlink.ValidateLinkPassword(req.Password); err != nil {
+				ctx.AbortWithError(http.StatusBadRequest, err.Error())
+				return
+			}
+			h, err := shortlink.HashLinkPassword(req.Password)
+			if err != nil {
+				ctx.AbortWithError(http.StatusInternalServerError, "shortlink create failed")
+				return
+			}
+			passwordHash = h
+		}
 
 		userID, ok := tryGetUserID(ctx)
 		if !ok {
 			return
 		}
+		if req.Private && userID == nil {
+			ctx.AbortWithError(http.StatusUnauthorized, "private shortlink requires login")
+			return
+		}
+		var domain repo.Domain
+		if req.Domain != "" {
+			if userID == nil {
+				ctx.AbortWithError(http.StatusUnauthorized, "custom domain requires login")
+				return
+			}
+			if domain, ok = mustVerifiedDomain(ctx, r, *userID, req.Domain); !ok {
+				return
+			}
+			// 域名下的短链总是私有的：别人同一 url 的短链在默认域名上，不能共享
+			req.Private = true
+		}
+		metaReq := LinkMetaRequest{Title: req.Title, Notes: req.Notes, Tags: req.Tags, FolderID: req.FolderID}
+		var meta repo.LinkMeta
+		if !metaReq.empty() {
+			if userID == nil {
+				ctx.AbortWithError(http.StatusUnauthorized, "title/notes/tags/folder require login")
+				return
+			}
+			if meta, ok = parseLinkMeta(ctx, metaReq); !ok {
+				return
+			}
+			// 整理信息与短链在同一事务内写入，文件夹归属需在创建前校验
+			if meta.FolderID != nil && *meta.FolderID != 0 {
+				owns, err := r.UserOwnsFolder(ctx.Req.Context(), *userID, *meta.FolderID)
+				if err != nil {
+					ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+					return
+				}
+				if !owns {
+					ctx.AbortWithError(http.StatusNotFound, repo.ErrFolderNotFound.Error())
+					return
+				}
+			}
+		}
 
-		var code string
-		var err error
-		if customCode != "" {
-			code, err = r.CreateWithCustomCode(ctx.Req.Context(), req.URL, customCode, userID)
+		link := shortlink.Shortlink{
+			Code:          customCode,
+			URL:           req.URL,
+			ExpiresAt:     expiresAt,
+			RedirectType:  req.RedirectType,
+			CacheControl:  req.CacheControl,
+			RobotsTag:     req.RobotsTag,
+			PasswordHash:  passwordHash,
+			MaxClicks:     req.MaxClicks,
+			Rules:         rules,
+			Variants:      variants,
+			StickyVariant: req.StickyVariant,
+			DeepLink:      deepLink,
+			UTM:           utm,
+			ForwardQuery:  req.ForwardQuery,
+			ForwardPath:   req.ForwardPath,
+			Preview:       req.Preview,
+			FallbackURL:   req.FallbackURL,
+		}
+		if !mustPassPolicy(ctx, r, link.Destinations()...) || !mustPassReputation(ctx, screener, link.Destinations()...) {
+			return
+		}
+		if domain.ID != 0 {
+			// 自定义的 code 只是域名内的 key，内部短码照常生成
+			link.DomainID, link.DomainKey, link.Code = domain.ID, customCode, ""
+		}
+		if req.Private {
+			link, err = r.CreatePrivate(ctx.Req.Context(), link, meta, *userID)
 			if err != nil {
-				if errors.Is(err, repo.ErrShortlinkCodeAlreadyExists) || errors.Is(err, repo.ErrShortlinkURLAlreadyHasDifferentCode) {
+				if errors.Is(err, repo.ErrShortlinkCodeAlreadyExists) {
+					ctx.AbortWithError(http.StatusConflict, err.Error())
+					return
+				}
+				ctx.AbortWithError(http.StatusInternalServerError, "shortlink create failed")
+				return
+			}
+		} else if customCode != "" {
+			link, err = r.CreateWithCustomCode(ctx.Req.Context(), link, meta, userID)
+			if err != nil {
+				if errors.Is(err, repo.ErrShortlinkCodeAlreadyExists) || errors.Is(err, repo.ErrShortlinkURLAlreadyHasDifferentCode) || errors.Is(err, repo.ErrShortlinkURLAlreadyHasDifferentOptions) {
 					ctx.AbortWithError(http.StatusConflict, err.Error())
 					return
 				}
@@ -73,37 +249,149 @@ func NewCreateHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
 				return
 			}
 		} else {
-			code, err = r.Create(ctx.Req.Context(), req.URL, userID)
+			link, err = r.Create(ctx.Req.Context(), link, meta, userID)
 			if err != nil {
+				if errors.Is(err, repo.ErrShortlinkURLAlreadyHasDifferentOptions) {
+					ctx.AbortWithError(http.StatusConflict, err.Error())
+					return
+				}
 				ctx.AbortWithError(http.StatusInternalServerError, "shortlink create failed")
 				return
 			}
 		}
 
-		path := "/" + code
-		scheme := ctx.Req.Header.Get("X-Forwarded-Proto")
-		if scheme == "" {
-			scheme = "http"
-		}
-		shortURL := path
-		if host := ctx.Req.Host; host != "" {
-			shortURL = scheme + "://" + host + path
+		shortURL := buildShortURL(ctx, link.Code)
+		if domain.ID != 0 {
+			shortURL = buildDomainShortURL(ctx, domain.Host, link.DomainKey)
 		}
-
 		ctx.JSON(http.StatusOK, ShortLinksResponse{
-			Code:     code,
-			ShortURL: shortURL,
-			URL:      req.URL,
+			Code:         link.Code,
+			ShortURL:     shortURL,
+			URL:          req.URL,
+			ExpiresAt:    link.ExpiresAt,
+			RedirectType: link.RedirectStatus(),
+			CacheControl: link.CacheControl,
+			RobotsTag:    link.RobotsTag,
+			Protected:    link.Protected(),
+			MaxClicks:    link.MaxClicks,
+			Private:      req.Private,
+			Domain:       domain.Host,
 		})
 	}
 }
 
-func NewRedirectHandler(r *repo.ShortlinksRepo, collector stats.Collector) gee.HandlerFunc {
+// parseExpiry 把 expire_in / expires_at 统一换算成绝对过期时间，nil 表示不过期。
+// 失败时已写入 400 响应。
+func parseExpiry(ctx *gee.Context, expireIn string, expiresAt *time.Time) (*time.Time, bool) {
+	t, err := resolveExpiry(expireIn, expiresAt, time.Now())
+	if err != nil {
+		ctx.AbortWithError(http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return t, true
+}
+
+var errExpiryConflict = errors.New("expire_in and expires_at are mutually exclusive")
+var errExpiryInPast = errors.New("expires_at must be in the future")
+
+// resolveExpiry 是 parseExpiry 的纯函数部分，批量创建时逐条调用。
+func resolveExpiry(expireIn string, expiresAt *time.Time, now time.Time) (*time.Time, error) {
+	if strings.TrimSpace(expireIn) != "" && expiresAt != nil {
+		return nil, errExpiryConflict
+	}
+	if strings.TrimSpace(expireIn) != "" {
+		d, err := shortlink.ParseExpireIn(expireIn)
+		if err != nil {
+			return nil, err
+		}
+		t := now.Add(d)
+		return &t, nil
+	}
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, errExpiryInPast
+	}
+	return expiresAt, nil
+}
+
+// abortResolveError 把 Resolve 的错误映射为 HTTP 状态码：过期 410、不存在 404、其它 500。
+func abortResolveError(ctx *gee.Context, err error) {
+	if errors.Is(err, repo.ErrShortlinkExpired) {
+		ctx.AbortWithError(http.StatusGone, err.Error())
+		return
+	}
+	if errors.Is(err, repo.ErrShortlinkNotFound) {
+		ctx.AbortWithError(http.StatusNotFound, "url not found")
+		return
+	}
+	ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+}
+
+func NewRedirectHandler(r *repo.ShortlinksRepo, collector stats.Collector, unlock *shortlink.UnlockSigner, geo geoip.CountryResolver, qr *qrcode.Renderer) gee.HandlerFunc {
 	return func(ctx *gee.Context) {
-		code := ctx.Param("code")
-		url := r.Resolve(ctx.Req.Context(), code)
-		if url == "" {
-			ctx.AbortWithError(http.StatusNotFound, "url not found")
+		// 自定义域名上 code 是域名内的 key，统计、缓存等一律用解析出的 link.Code。
+		// /{code}+ 只预览不跳转；/{code}~qr 是扫码访问
+		code, preview := splitPreviewCode(ctx.Param("code"))
+		code, source := splitSourceCode(code)
+		link, err := r.ResolveHost(ctx.Req.Context(), shortlink.NormalizeHost(ctx.Req.Host), code)
+		if err != nil {
+			abortResolveError(ctx, err)
+			return
+		}
+		// /{code}/ 之后的剩余路径：先按默认目标检查一遍，不接受路径的短链直接 404，不计点击
+		segments := pathSegments(ctx.Param("path"))
+		if _, err := link.ExpandPath(link.URL, segments); err != nil {
+			// 不接受路径的短链上 /{code}/qr 本来是 404，当作二维码接口（同 /qr/{code}）
+			if errors.Is(err, shortlink.ErrPathNotAllowed) && !preview && source == "" && len(segments) == 1 && segments[0] == "qr" {
+				serveQR(ctx, qr, code)
+				return
+			}
+			abortPathError(ctx, err)
+			return
+		}
+		// 受密码保护：没有有效凭证时展示密码页，不跳转也不计点击
+		if link.Protected() && !unlocked(ctx, unlock, link) {
+			renderPasswordPage(ctx, http.StatusOK, "")
+			return
+		}
+		// 主动预览：展示默认目标，不计点击；总是展示预览页的短链走下面的正常流程，在跳转前展示
+		if preview && !link.ShowPreview() {
+			dest, _ := link.ExpandPath(link.URL, segments)
+			renderPreviewPage(ctx, r, link, link.DecorateURL(dest, ctx.Req.URL.Query()), continuePath(ctx, code, segments))
+			return
+		}
+		// 命中规则优先；否则有分流版本时按权重选一个，点击记到该版本。
+		// 移动端访客再看 App 跳转，选出的网页目标作为“在浏览器中继续”的地址
+		visitor := visitorFrom(ctx, geo)
+		// 默认目标被健康检查判定故障时改跳备用地址
+		dest, variant := link.URL, ""
+		fallback := false
+		var app shortlink.AppTarget
+		var toApp bool
+		if rule, ok := link.MatchRule(visitor); ok {
+			dest = rule.URL
+		} else {
+			if v, ok := pickVariant(ctx, link); ok {
+				dest, variant = v.URL, v.Name
+			} else if link.UseFallback() {
+				dest, fallback = link.FallbackURL, true
+			}
+			app, toApp = link.DeepLink.Target(visitor.OS)
+		}
+		if dest, err = link.ExpandPath(dest, segments); err != nil {
+			abortPathError(ctx, err)
+			return
+		}
+		query := ctx.Req.URL.Query()
+		dest = link.DecorateURL(dest, query)
+
+		// 有点击上限：目标确定能跳转后再占名额，路径不匹配等不会跳转的请求不消耗名额。
+		// 计数不可用时拒绝而不是放行，一次性链接不能因故障被重复使用
+		if err := r.TakeClick(ctx.Req.Context(), link); err != nil {
+			if errors.Is(err, repo.ErrClickLimitReached) {
+				ctx.AbortWithError(http.StatusGone, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusServiceUnavailable, "click counter unavailable")
 			return
 		}
 		// 记录跳转
@@ -111,15 +399,45 @@ func NewRedirectHandler(r *repo.ShortlinksRepo, collector stats.Collector) gee.H
 
 		//异步记录点击
 		collector.Collect(stats.ClickEvent{
-			Code:      code,
+			Code:      link.Code,
 			ClickedAt: time.Now(),
 			IP:        httpmiddleware.ClientIP(ctx.Req),
 			UserAgent: ctx.Req.UserAgent(),
 			Referer:   ctx.Req.Referer(),
+			Variant:   variant,
+			Source:    source,
 		})
 
-		ctx.SetHeader("Location", url)
-		ctx.Status(http.StatusFound)
+		// 总是展示预览页：点击照常计入，访客确认后直接去选出的网页目标
+		if link.ShowPreview() {
+			renderPreviewPage(ctx, r, link, dest, dest)
+			return
+		}
+		if toApp && !app.Direct {
+			renderDeepLinkPage(ctx, app, dest)
+			return
+		}
+		if toApp {
+			dest = link.DecorateURL(app.AppURL, query)
+		}
+
+		if link.Protected() || len(link.Rules) > 0 || len(link.Variants) > 0 || link.DeepLink != nil || fallback {
+			// 跳转结果不能进入共享缓存，否则其他人可以绕过密码，或拿到按别人的设备/语言/国家/分流选出的目标；
+			// 备用地址只是临时的，主目标恢复后要能立即切回
+			ctx.SetHeader("Cache-Control", "private, no-store")
+		} else if link.CacheControl != "" {
+			ctx.SetHeader("Cache-Control", link.CacheControl)
+		}
+		if link.RobotsTag != "" {
+			ctx.SetHeader("X-Robots-Tag", link.RobotsTag)
+		}
+		ctx.SetHeader("Location", dest)
+		if fallback {
+			// 301/308 会被浏览器永久记住，临时切换只能用 302
+			ctx.Status(http.StatusFound)
+			return
+		}
+		ctx.Status(link.RedirectStatus())
 	}
 }
 
@@ -139,19 +457,73 @@ func NewFindShortlinksHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
 	}
 }
 
+type StateChangeRequest struct {
+	Reason string `json:"reason,omitempty"` // 停用/恢复原因，可选
+}
+
+// NewDisablesHandler 管理员停用短链
 func NewDisablesHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return newStateChangeHandler(r, true, true)
+}
+
+// NewEnableHandler 管理员恢复短链（包括拥有者自己停用的）
+func NewEnableHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return newStateChangeHandler(r, false, true)
+}
+
+// NewOwnerDisableHandler 拥有者暂停自己的短链
+func NewOwnerDisableHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return newStateChangeHandler(r, true, false)
+}
+
+// NewOwnerEnableHandler 拥有者恢复自己暂停的短链；管理员停用的不能自行恢复
+func NewOwnerEnableHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return newStateChangeHandler(r, false, false)
+}
+
+func newStateChangeHandler(r *repo.ShortlinksRepo, disabled bool, admin bool) gee.HandlerFunc {
 	return func(ctx *gee.Context) {
 		code := ctx.Param("code")
-		err := r.DisableByCode(ctx.Req.Context(), code)
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		// 请求体可选：不带 body 时等同于不填原因
+		var req StateChangeRequest
+		if ctx.Req.ContentLength != 0 {
+			if err := ctx.BindJSON(&req); err != nil {
+				return
+			}
+		}
+		req.Reason = strings.TrimSpace(req.Reason)
+		if err := shortlink.ValidateReason(req.Reason); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !admin && !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+
+		change := repo.StateChange{ActorID: userID, Admin: admin, Reason: req.Reason}
+		var err error
+		if disabled {
+			err = r.DisableByCode(ctx.Req.Context(), code, change)
+		} else {
+			err = r.EnableByCode(ctx.Req.Context(), code, change)
+		}
 		if err != nil {
 			if errors.Is(err, repo.ErrShortlinkNotFound) {
 				ctx.AbortWithError(http.StatusNotFound, err.Error())
 				return
 			}
-			if errors.Is(err, repo.ErrAlreadyDisabled) {
+			if errors.Is(err, repo.ErrAlreadyDisabled) || errors.Is(err, repo.ErrAlreadyEnabled) || errors.Is(err, repo.ErrShortlinkShared) || errors.Is(err, repo.ErrClickLimitReached) {
 				ctx.AbortWithError(http.StatusConflict, err.Error())
 				return
 			}
+			if errors.Is(err, repo.ErrDisabledByAdmin) {
+				ctx.AbortWithError(http.StatusForbidden, err.Error())
+				return
+			}
 			ctx.AbortWithError(http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -173,3 +545,58 @@ func NewRemoveFromMineHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
 		ctx.Status(http.StatusOK)
 	}
 }
+
+type UpdateShortlinkRequest struct {
+	URL string `json:"url"`
+}
+
+// NewUpdateShortlinkHandler 允许短链拥有者修改目标 URL（例如修正已印刷二维码的跳转地址）。
+func NewUpdateShortlinkHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req UpdateShortlinkRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		if err := shortlink.ValidateURL(req.URL); err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		if !mustPassPolicy(ctx, r, req.URL) || !mustPassReputation(ctx, screener, req.URL) {
+			return
+		}
+
+		link, err := r.UpdateURL(ctx.Req.Context(), userID, code, req.URL)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+
+		ctx.JSON(http.StatusOK, ShortLinksResponse{
+			Code:         link.Code,
+			ShortURL:     buildShortURL(ctx, link.Code),
+			URL:          link.URL,
+			ExpiresAt:    link.ExpiresAt,
+			RedirectType: link.RedirectStatus(),
+			CacheControl: link.CacheControl,
+			RobotsTag:    link.RobotsTag,
+			Protected:    link.Protected(),
+			MaxClicks:    link.MaxClicks,
+		})
+	}
+}
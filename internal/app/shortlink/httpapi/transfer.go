@@ -0,0 +1,406 @@
+package httpapi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/urlcheck"
+)
+
+const (
+	exportPageSize = 500
+	maxImportRows  = 50000
+	maxImportBody  = 32 << 20
+	// transferDeadline 是导入/导出放宽后的读写超时，默认 WRITE_TIMEOUT 不够处理几万条
+	transferDeadline = 5 * time.Minute
+)
+
+// 导入报告中每行的状态
+const (
+	importCreated  = "created"
+	importConflict = "conflict"
+	importInvalid  = "invalid"
+	importFailed   = "error"
+)
+
+// exportColumns 是导出 CSV 的表头，同时也是导入时能识别的列名
+var exportColumns = []string{"code", "short_url", "url", "title", "notes", "tags", "folder", "created_at", "expires_at", "disabled", "click_count", "max_clicks", "private"}
+
+// ExportLink 是导出的一条短链。folder 导出名称而不是 id，方便导入到另一个账号。
+type ExportLink struct {
+	Code       string     `json:"code"`
+	ShortURL   string     `json:"short_url"`
+	URL        string     `json:"url"`
+	Title      string     `json:"title,omitempty"`
+	Notes      string     `json:"notes,omitempty"`
+	Tags       []string   `json:"tags"`
+	Folder     string     `json:"folder,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Disabled   bool       `json:"disabled"`
+	ClickCount int64      `json:"click_count"`
+	MaxClicks  int64      `json:"max_clicks,omitempty"`
+	Private    bool       `json:"private"`
+}
+
+func (e ExportLink) csvRecord() []string {
+	expiresAt := ""
+	if e.ExpiresAt != nil {
+		expiresAt = e.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		e.Code, e.ShortURL, e.URL, e.Title, e.Notes, strings.Join(e.Tags, ","), e.Folder,
+		e.CreatedAt.UTC().Format(time.RFC3339), expiresAt, strconv.FormatBool(e.Disabled),
+		strconv.FormatInt(e.ClickCount, 10), strconv.FormatInt(e.MaxClicks, 10), strconv.FormatBool(e.Private),
+	}
+}
+
+// extendDeadlines 放宽本次请求的读写超时；底层 writer 不支持时（例如测试用的 recorder）忽略
+func extendDeadlines(ctx *gee.Context, d time.Duration) {
+	rc := http.NewResponseController(ctx.Writer.ResponseWriter)
+	_ = rc.SetReadDeadline(time.Now().Add(d))
+	_ = rc.SetWriteDeadline(time.Now().Add(d))
+}
+
+// NewExportHandler 导出当前用户的全部短链：GET /api/v1/users/mine/export?format=csv|json（默认 csv）。
+//
+// 按加入时间正序分页读取、边读边写，不把全部短链放进内存；JSON 输出为一个数组。
+//
+// 设计原因：
+// - 复用 ListByUserID 的游标分页，导出与列表看到的数据一致
+// - 开始写响应后状态码已经发出，中途出错只能记日志并截断，客户端按 JSON/CSV 不完整识别
+func NewExportHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		format := ctx.Query("format")
+		if format == "" {
+			format = "csv"
+		}
+		if format != "csv" && format != "json" {
+			ctx.AbortWithError(http.StatusBadRequest, "invalid format")
+			return
+		}
+
+		folders, err := r.ListFolders(ctx.Req.Context(), userID)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		folderNames := make(map[int64]string, len(folders))
+		for _, f := range folders {
+			folderNames[f.ID] = f.Name
+		}
+		filter := repo.UserLinkFilter{Sort: repo.SortByCreatedAt, Asc: true, Limit: exportPageSize}
+		page, err := r.ListByUserID(ctx.Req.Context(), userID, filter)
+		if err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+
+		extendDeadlines(ctx, transferDeadline)
+		ctx.SetHeader("Cache-Control", "no-store")
+		ctx.SetHeader("Content-Disposition", `attachment; filename="shortlinks.`+format+`"`)
+		var csvw *csv.Writer
+		if format == "csv" {
+			ctx.SetHeader("Content-Type", "text/csv; charset=utf-8")
+			ctx.Status(http.StatusOK)
+			csvw = csv.NewWriter(ctx.Writer)
+			_ = csvw.Write(exportColumns)
+		} else {
+			ctx.SetHeader("Content-Type", "application/json")
+			ctx.Status(http.StatusOK)
+			_, _ = ctx.Writer.Write([]byte("["))
+		}
+		rc := http.NewResponseController(ctx.Writer.ResponseWriter)
+
+		first := true
+		for {
+			for _, item := range page.Items {
+				link := ExportLink{
+					Code:       item.Code,
+					ShortURL:   buildShortURL(ctx, item.Code),
+					URL:        item.URL,
+					Title:      item.Title,
+					Notes:      item.Notes,
+					Tags:       item.Tags,
+					CreatedAt:  item.CreatedAt,
+					ExpiresAt:  item.ExpiresAt,
+					Disabled:   item.Disabled,
+					ClickCount: item.ClickCount,
+					MaxClicks:  item.MaxClicks,
+					Private:    item.Private,
+				}
+				if item.FolderID != nil {
+					link.Folder = folderNames[*item.FolderID]
+				}
+				if csvw != nil {
+					_ = csvw.Write(link.csvRecord())
+					continue
+				}
+				if !first {
+					_, _ = ctx.Writer.Write([]byte(","))
+				}
+				first = false
+				data, _ := json.Marshal(link)
+				_, _ = ctx.Writer.Write(data)
+			}
+			if csvw != nil {
+				csvw.Flush()
+			}
+			_ = rc.Flush()
+			if page.NextCursor == "" {
+				break
+			}
+			filter.Cursor = page.NextCursor
+			if page, err = r.ListByUserID(ctx.Req.Context(), userID, filter); err != nil {
+				slog.Error("export shortlinks aborted", "user_id", userID, "err", err)
+				return
+			}
+		}
+		if csvw == nil {
+			_, _ = ctx.Writer.Write([]byte("]\n"))
+		}
+	}
+}
+
+// ImportRowResult 是导入报告中的一行：status 为 created / conflict / invalid / error
+type ImportRowResult struct {
+	Row    int    `json:"row"`
+	URL    string `json:"url"`
+	Code   string `json:"code,omitempty"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+type ImportReport struct {
+	Created   int               `json:"created"`
+	Conflicts int               `json:"conflicts"`
+	Invalid   int               `json:"invalid"`
+	Failed    int               `json:"failed"`
+	Rows      []ImportRowResult `json:"rows"`
+}
+
+// NewImportHandler 导入短链：POST /api/v1/users/mine/import?format=csv|json[&private=true]。
+//
+// 支持本站导出、Bitly、YOURLS 的 CSV/JSON 格式（列名映射见 shortlink.ParseImportCSV），
+// 原短码会被保留；每行都经过与创建接口相同的校验，结果逐行回报：
+// - created：已创建（或已存在且短码一致，幂等）
+// - conflict：短码已被占用
+// - invalid：url、短码、标题等不合法，或目标域名被策略拒绝、目标地址被信誉服务标记
+// - error：服务端错误，可以重试
+//
+// 文件夹按名称匹配，不存在时自动创建。每 maxBatchSize 行一个事务，前面的批次不会因后面失败而回滚；
+// 共享模式下重复导入同一个文件是安全的：已导入的行短码一致，会被判为 created。
+// private=true 时每行都创建为私有短链，与别人互不影响；私有模式重复导入时已导入的行会报 conflict。
+func NewImportHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		private := false
+		if p := ctx.Query("private"); p != "" {
+			b, err := strconv.ParseBool(p)
+			if err != nil {
+				ctx.AbortWithError(http.StatusBadRequest, "invalid private")
+				return
+			}
+			private = b
+		}
+		format := ctx.Query("format")
+		if format == "" && strings.HasPrefix(ctx.Req.Header.Get("Content-Type"), "text/csv") {
+			format = "csv"
+		}
+		if format == "" {
+			format = "json"
+		}
+
+		extendDeadlines(ctx, transferDeadline)
+		body := http.MaxBytesReader(ctx.Writer, ctx.Req.Body, maxImportBody)
+		var rows []shortlink.ImportRow
+		var err error
+		switch format {
+		case "csv":
+			rows, err = shortlink.ParseImportCSV(body)
+		case "json":
+			rows, err = shortlink.ParseImportJSON(body)
+		default:
+			ctx.AbortWithError(http.StatusBadRequest, "invalid format")
+			return
+		}
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(rows) == 0 {
+			ctx.AbortWithError(http.StatusBadRequest, "no rows to import")
+			return
+		}
+		if len(rows) > maxImportRows {
+			ctx.AbortWithError(http.StatusBadRequest, "too many rows, max "+strconv.Itoa(maxImportRows))
+			return
+		}
+
+		policy, err := r.DestinationPolicy(ctx.Req.Context())
+		if err != nil {
+			ctx.AbortWithError(http.StatusServiceUnavailable, "destination policy unavailable")
+			return
+		}
+
+		report := ImportReport{Rows: make([]ImportRowResult, len(rows))}
+		items := make([]repo.BatchItem, 0, len(rows))
+		indexes := make([]int, 0, len(rows))
+		folders := map[string]int64{}
+		for i, row := range rows {
+			report.Rows[i] = ImportRowResult{Row: row.Row, URL: row.URL, Code: row.Code}
+			item, err := importItem(row, private)
+			if err == nil {
+				err = policy.Check(item.Link.URL)
+			}
+			if err != nil {
+				report.Rows[i].Status, report.Rows[i].Error = importInvalid, err.Error()
+				continue
+			}
+			if row.Folder != "" {
+				folders[row.Folder] = 0
+			}
+			items = append(items, item)
+			indexes = append(indexes, i)
+		}
+		items, indexes, ok = screenBatchItems(ctx, screener, items, indexes, func(i int, m urlcheck.Match) {
+			report.Rows[i].Status, report.Rows[i].Error = importInvalid, m.Reason()
+		})
+		if !ok {
+			return
+		}
+
+		if err := resolveImportFolders(ctx, r, userID, folders); err != nil {
+			ctx.AbortWithError(http.StatusInternalServerError, "folder create failed")
+			return
+		}
+		for j := range items {
+			if name := rows[indexes[j]].Folder; name != "" {
+				id := folders[name]
+				items[j].Meta.FolderID = &id
+			}
+		}
+
+		for start := 0; start < len(items); start += maxBatchSize {
+			end := min(start+maxBatchSize, len(items))
+			results, err := r.CreateBatch(ctx.Req.Context(), items[start:end], &userID)
+			for j := start; j < end; j++ {
+				out := &report.Rows[indexes[j]]
+				switch {
+				case err != nil:
+					out.Status, out.Error = importFailed, "shortlink create failed"
+				case results[j-start].Err != nil:
+					status, msg := batchErrorStatus(results[j-start].Err)
+					out.Status, out.Error = importFailed, msg
+					if status == http.StatusConflict {
+						out.Status = importConflict
+					}
+				default:
+					out.Status, out.Code = importCreated, results[j-start].Link.Code
+				}
+			}
+		}
+
+		for _, row := range report.Rows {
+			switch row.Status {
+			case importCreated:
+				report.Created++
+			case importConflict:
+				report.Conflicts++
+			case importInvalid:
+				report.Invalid++
+			default:
+				report.Failed++
+			}
+		}
+		ctx.JSON(http.StatusOK, report)
+	}
+}
+
+// importItem 按创建接口的规则校验一行导入数据
+func importItem(row shortlink.ImportRow, private bool) (repo.BatchItem, error) {
+	if err := shortlink.ValidateURL(row.URL); err != nil {
+		return repo.BatchItem{}, err
+	}
+	if row.Code != "" {
+		if err := shortlink.ValidateCode(row.Code); err != nil {
+			return repo.BatchItem{}, err
+		}
+	}
+	item := repo.BatchItem{
+		Link:    shortlink.Shortlink{Code: row.Code, URL: row.URL},
+		Private: private,
+	}
+	if row.Title != "" {
+		if err := shortlink.ValidateTitle(row.Title); err != nil {
+			return repo.BatchItem{}, err
+		}
+		item.Meta.Title = &row.Title
+	}
+	if row.Notes != "" {
+		if err := shortlink.ValidateNotes(row.Notes); err != nil {
+			return repo.BatchItem{}, err
+		}
+		item.Meta.Notes = &row.Notes
+	}
+	if len(row.Tags) > 0 {
+		tags, err := shortlink.NormalizeTags(row.Tags)
+		if err != nil {
+			return repo.BatchItem{}, err
+		}
+		item.Meta.Tags = &tags
+	}
+	if row.Folder != "" {
+		if err := shortlink.ValidateFolderName(row.Folder); err != nil {
+			return repo.BatchItem{}, err
+		}
+	}
+	return item, nil
+}
+
+// resolveImportFolders 把文件夹名称解析为 id（填回 folders），不存在的自动创建
+func resolveImportFolders(ctx *gee.Context, r *repo.ShortlinksRepo, userID int64, folders map[string]int64) error {
+	if len(folders) == 0 {
+		return nil
+	}
+	existing, err := r.ListFolders(ctx.Req.Context(), userID)
+	if err != nil {
+		return err
+	}
+	for _, f := range existing {
+		if _, ok := folders[f.Name]; ok {
+			folders[f.Name] = f.ID
+		}
+	}
+	for name, id := range folders {
+		if id != 0 {
+			continue
+		}
+		f, err := r.CreateFolder(ctx.Req.Context(), userID, name)
+		if errors.Is(err, repo.ErrFolderAlreadyExists) {
+			// 并发导入时被另一个请求抢先创建，重新查一次
+			return resolveImportFolders(ctx, r, userID, folders)
+		}
+		if err != nil {
+			return err
+		}
+		folders[name] = f.ID
+	}
+	return nil
+}
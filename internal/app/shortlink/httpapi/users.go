@@ -6,6 +6,7 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"day.local/gee"
@@ -102,20 +103,108 @@ func NewUserMeHandler() gee.HandlerFunc {
 	}
 }
 
+// NewMineHandler 列出当前用户的短链，支持搜索、筛选、排序与游标分页。
+//
+// 查询参数：
+// - q：在 url、短码与目标页面标题中模糊搜索
+// - tag / folder_id：按标签、文件夹筛选
+// - disabled：true/false，按启用状态筛选
+// - broken：true/false，按健康检查结果筛选（默认目标是否被判定故障）
+// - created_after / created_before：RFC3339，按加入时间筛选（左闭右开）
+// - sort：created_at（默认）/ clicks；order：desc（默认）/ asc
+// - limit：1~100，默认 50；cursor：上一页返回的 next_cursor
 func NewMineHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
 	return func(ctx *gee.Context) {
 		userID, ok := mustGetUserID(ctx)
 		if !ok {
 			return
 		}
-		list, err := r.ListByUserID(ctx.Req.Context(), userID, 50)
+		filter, ok := parseUserLinkFilter(ctx)
+		if !ok {
+			return
+		}
+		page, err := r.ListByUserID(ctx.Req.Context(), userID, filter)
 		if err != nil {
+			if errors.Is(err, repo.ErrInvalidCursor) {
+				ctx.AbortWithError(http.StatusBadRequest, err.Error())
+				return
+			}
 			slog.Error("list user shortlinks failed", "user_id", userID, "err", err)
 			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
 			return
 		}
-		ctx.JSON(http.StatusOK, list)
+		ctx.JSON(http.StatusOK, page)
+	}
+}
+
+// parseUserLinkFilter 解析 /users/mine 的查询参数，失败时已写入 400 响应
+func parseUserLinkFilter(ctx *gee.Context) (repo.UserLinkFilter, bool) {
+	filter := repo.UserLinkFilter{
+		Query:  strings.TrimSpace(ctx.Query("q")),
+		Tag:    strings.ToLower(strings.TrimSpace(ctx.Query("tag"))),
+		Cursor: ctx.Query("cursor"),
+		Limit:  50,
+	}
+	if l := ctx.Query("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > 100 {
+			ctx.AbortWithError(http.StatusBadRequest, "invalid limit")
+			return filter, false
+		}
+		filter.Limit = n
+	}
+	if f := ctx.Query("folder_id"); f != "" {
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil || n <= 0 {
+			ctx.AbortWithError(http.StatusBadRequest, "invalid folder_id")
+			return filter, false
+		}
+		filter.FolderID = n
+	}
+	if d := ctx.Query("disabled"); d != "" {
+		b, err := strconv.ParseBool(d)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, "invalid disabled")
+			return filter, false
+		}
+		filter.Disabled = &b
+	}
+	if d := ctx.Query("broken"); d != "" {
+		b, err := strconv.ParseBool(d)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, "invalid broken")
+			return filter, false
+		}
+		filter.Broken = &b
+	}
+	for key, dst := range map[string]**time.Time{"created_after": &filter.CreatedAfter, "created_before": &filter.CreatedBefore} {
+		if v := ctx.Query(key); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				ctx.AbortWithError(http.StatusBadRequest, "invalid "+key)
+				return filter, false
+			}
+			*dst = &t
+		}
+	}
+	switch ctx.Query("sort") {
+	case "", "created_at":
+		filter.Sort = repo.SortByCreatedAt
+	case "clicks":
+		filter.Sort = repo.SortByClicks
+	default:
+		ctx.AbortWithError(http.StatusBadRequest, "invalid sort")
+		return filter, false
 	}
+	switch ctx.Query("order") {
+	case "", "desc":
+	case "asc":
+		filter.Asc = true
+	default:
+		ctx.AbortWithError(http.StatusBadRequest, "invalid order")
+		return filter, false
+	}
+	return filter, true
 }
 
 func NewGetStatsHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
@@ -126,13 +215,7 @@ func NewGetStatsHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
 			return
 		}
 		// 检查权限
-		owns, err := r.UserOwnsShortlink(ctx.Req.Context(), userID, code)
-		if err != nil {
-			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
-			return
-		}
-		if !owns {
-			ctx.AbortWithError(http.StatusForbidden, "no permission")
+		if !mustOwnShortlink(ctx, r, userID, code) {
 			return
 		}
 
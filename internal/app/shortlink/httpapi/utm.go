@@ -0,0 +1,61 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+type UTMRequest struct {
+	UTM          *shortlink.UTM `json:"utm"`
+	ForwardQuery bool           `json:"forward_query"`
+}
+
+type UTMResponse struct {
+	Code         string         `json:"code"`
+	UTM          *shortlink.UTM `json:"utm"`
+	ForwardQuery bool           `json:"forward_query"`
+}
+
+// NewUpdateUTMHandler 替换自己短链的 UTM 参数与查询参数透传开关：PUT /api/v1/users/shortlinks/:code/utm。
+//
+// utm 为空（null 或 {}）即清除；合并优先级见 shortlink.Shortlink.DecorateURL。
+func NewUpdateUTMHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req UTMRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		utm, err := shortlink.NormalizeUTM(req.UTM)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+
+		link, err := r.UpdateUTM(ctx.Req.Context(), userID, code, utm, req.ForwardQuery)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		ctx.JSON(http.StatusOK, UTMResponse{Code: link.Code, UTM: link.UTM, ForwardQuery: link.ForwardQuery})
+	}
+}
@@ -0,0 +1,146 @@
+package httpapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/urlcheck"
+)
+
+// variantCookiePrefix + code 为粘性分流的 cookie 名，值是分到的版本名；Path 限定为访问路径 /{code}（自定义域名上是 /{key}），与密码凭证一致。
+const (
+	variantCookiePrefix = "sl_v_"
+	variantCookieTTL    = 30 * 24 * time.Hour
+)
+
+var errStickyWithoutVariants = errors.New("sticky_variant requires variants")
+
+type VariantsRequest struct {
+	Variants      []shortlink.Variant `json:"variants"`
+	StickyVariant bool                `json:"sticky_variant,omitempty"`
+}
+
+type VariantsResponse struct {
+	Code          string              `json:"code"`
+	Variants      []shortlink.Variant `json:"variants"`
+	StickyVariant bool                `json:"sticky_variant"`
+}
+
+type VariantStatsResponse struct {
+	Code     string               `json:"code"`
+	Variants []repo.VariantClicks `json:"variants"`
+}
+
+// normalizeVariants 校验分流版本与粘性开关：只开粘性不设版本没有意义，直接拒绝
+func normalizeVariants(variants []shortlink.Variant, sticky bool) ([]shortlink.Variant, error) {
+	out, err := shortlink.NormalizeVariants(variants)
+	if err != nil {
+		return nil, err
+	}
+	if sticky && len(out) == 0 {
+		return nil, errStickyWithoutVariants
+	}
+	return out, nil
+}
+
+// pickVariant 为本次访问选一个分流版本；开启粘性时优先沿用 cookie 里的版本，并把结果写回 cookie。
+//
+// cookie 里的版本已被删除（改过分流配置）时重新抽取并覆盖；从 "/{code}~qr" 进来时带后缀的路径也写一份，
+// 否则扫码访客每次都拿不到 cookie、重新抽取。
+func pickVariant(ctx *gee.Context, link shortlink.Shortlink) (shortlink.Variant, bool) {
+	if !link.StickyVariant {
+		return link.PickVariant("")
+	}
+	var sticky string
+	if c, err := ctx.Req.Cookie(variantCookiePrefix + link.Code); err == nil {
+		sticky = c.Value
+	}
+	v, ok := link.PickVariant(sticky)
+	if ok && v.Name != sticky {
+		for _, path := range linkCookiePaths(ctx.Param("code")) {
+			http.SetCookie(ctx.Writer, &http.Cookie{
+				Name:     variantCookiePrefix + link.Code,
+				Value:    v.Name,
+				Path:     path,
+				MaxAge:   int(variantCookieTTL.Seconds()),
+				HttpOnly: true,
+				Secure:   ctx.Req.TLS != nil || ctx.Req.Header.Get("X-Forwarded-Proto") == "https",
+				SameSite: http.SameSiteLaxMode,
+			})
+		}
+	}
+	return v, ok
+}
+
+// NewUpdateVariantsHandler 替换自己短链的 A/B 分流版本：PUT /api/v1/users/shortlinks/:code/variants。
+//
+// 与规则一样整体替换；variants 为空数组即关闭分流，之后的点击回到默认 url。
+func NewUpdateVariantsHandler(r *repo.ShortlinksRepo, screener *urlcheck.Screener) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		var req VariantsRequest
+		if err := ctx.BindJSON(&req); err != nil {
+			return
+		}
+		variants, err := normalizeVariants(req.Variants, req.StickyVariant)
+		if err != nil {
+			ctx.AbortWithError(http.StatusBadRequest, err.Error())
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		urls := shortlink.Shortlink{Variants: variants}.Destinations()[1:]
+		if !mustPassPolicy(ctx, r, urls...) || !mustPassReputation(ctx, screener, urls...) {
+			return
+		}
+
+		link, err := r.UpdateVariants(ctx.Req.Context(), userID, code, variants, req.StickyVariant)
+		if err != nil {
+			if errors.Is(err, repo.ErrShortlinkNotFound) {
+				ctx.AbortWithError(http.StatusNotFound, err.Error())
+				return
+			}
+			if errors.Is(err, repo.ErrShortlinkShared) {
+				ctx.AbortWithError(http.StatusConflict, err.Error())
+				return
+			}
+			ctx.AbortWithError(http.StatusInternalServerError, "shortlink update failed")
+			return
+		}
+		if link.Variants == nil {
+			link.Variants = []shortlink.Variant{}
+		}
+		ctx.JSON(http.StatusOK, VariantsResponse{Code: link.Code, Variants: link.Variants, StickyVariant: link.StickyVariant})
+	}
+}
+
+// NewVariantStatsHandler 按分流版本汇总点击：GET /api/v1/users/shortlinks/:code/stats/variants。
+func NewVariantStatsHandler(r *repo.ShortlinksRepo) gee.HandlerFunc {
+	return func(ctx *gee.Context) {
+		code := ctx.Param("code")
+		userID, ok := mustGetUserID(ctx)
+		if !ok {
+			return
+		}
+		if !mustOwnShortlink(ctx, r, userID, code) {
+			return
+		}
+		variants, err := r.CountClicksByVariant(ctx.Req.Context(), code)
+		if err != nil {
+			slog.Error("count variant clicks failed", "code", code, "err", err)
+			ctx.AbortWithError(http.StatusInternalServerError, "internal error")
+			return
+		}
+		ctx.JSON(http.StatusOK, VariantStatsResponse{Code: code, Variants: variants})
+	}
+}
@@ -0,0 +1,198 @@
+package shortlink
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+var ErrInvalidImportFile = errors.New("invalid import file")
+
+// ImportRow 是导入文件中的一行，已按列名映射到统一字段，但还没有做校验。
+//
+// Row 是行号：CSV 从 2 开始（第 1 行是表头），JSON 从 1 开始，用于回报错误位置。
+type ImportRow struct {
+	Row    int
+	URL    string
+	Code   string
+	Title  string
+	Notes  string
+	Tags   []string
+	Folder string
+}
+
+// importColumns 把各家导出格式的列名映射到统一字段（列名先做 normalizeColumn）。
+//
+// 支持：
+// - 本站导出：url / code / title / notes / tags / folder
+// - Bitly：long_url、link / bitlink / id（形如 bit.ly/abc123，取最后一段作为短码）
+// - YOURLS：keyword（短码）、shorturl（完整短链，取最后一段）
+var importColumns = map[string]string{
+	"url":         "url",
+	"long_url":    "url",
+	"longurl":     "url",
+	"destination": "url",
+	"code":        "code",
+	"keyword":     "code",
+	"short_url":   "short",
+	"shorturl":    "short",
+	"bitlink":     "short",
+	"link":        "short",
+	"id":          "short",
+	"title":       "title",
+	"notes":       "notes",
+	"note":        "notes",
+	"tags":        "tags",
+	"folder":      "folder",
+}
+
+// normalizeColumn 统一列名：小写，空格/横线换成下划线（Bitly CSV 的表头是 "Long URL" 这种）
+func normalizeColumn(name string) string {
+	name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+	return strings.NewReplacer(" ", "_", "-", "_").Replace(name)
+}
+
+// ParseImportCSV 解析带表头的 CSV；必须包含 url 列（或其别名）。
+func ParseImportCSV(r io.Reader) ([]ImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, ErrInvalidImportFile
+	}
+	fields := make([]string, len(header))
+	hasURL := false
+	for i, h := range header {
+		fields[i] = importColumns[normalizeColumn(h)]
+		hasURL = hasURL || fields[i] == "url"
+	}
+	if !hasURL {
+		return nil, ErrInvalidImportFile
+	}
+
+	var rows []ImportRow
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidImportFile
+		}
+		values := map[string]any{}
+		for i, v := range record {
+			if i < len(fields) && fields[i] != "" {
+				values[fields[i]] = v
+			}
+		}
+		rows = append(rows, importRowFrom(line, values))
+	}
+	return rows, nil
+}
+
+// ParseImportJSON 解析 JSON 导入文件，支持三种外层结构：
+// - 数组：[{...}, ...]（本站导出）
+// - {"links": [{...}, ...]}（Bitly API 导出）
+// - {"links": {"link_1": {...}, ...}}（YOURLS API 导出，按 key 自然顺序）
+func ParseImportJSON(r io.Reader) ([]ImportRow, error) {
+	var doc any
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, ErrInvalidImportFile
+	}
+	if obj, ok := doc.(map[string]any); ok {
+		doc = obj["links"]
+	}
+
+	var items []any
+	switch v := doc.(type) {
+	case []any:
+		items = v
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		// link_2 排在 link_10 前面
+		sort.Slice(keys, func(i, j int) bool {
+			if len(keys[i]) != len(keys[j]) {
+				return len(keys[i]) < len(keys[j])
+			}
+			return keys[i] < keys[j]
+		})
+		for _, k := range keys {
+			items = append(items, v[k])
+		}
+	default:
+		return nil, ErrInvalidImportFile
+	}
+
+	rows := make([]ImportRow, 0, len(items))
+	for i, item := range items {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, ErrInvalidImportFile
+		}
+		values := map[string]any{}
+		for k, v := range obj {
+			if field := importColumns[normalizeColumn(k)]; field != "" {
+				values[field] = v
+			}
+		}
+		rows = append(rows, importRowFrom(i+1, values))
+	}
+	return rows, nil
+}
+
+// importRowFrom 把按统一字段名收集的值转成 ImportRow；短码列优先于完整短链列
+func importRowFrom(line int, values map[string]any) ImportRow {
+	row := ImportRow{
+		Row:    line,
+		URL:    importString(values["url"]),
+		Code:   importString(values["code"]),
+		Title:  importString(values["title"]),
+		Notes:  importString(values["notes"]),
+		Folder: importString(values["folder"]),
+	}
+	if row.Code == "" {
+		row.Code = codeFromShortURL(importString(values["short"]))
+	}
+	switch tags := values["tags"].(type) {
+	case string:
+		row.Tags = strings.FieldsFunc(tags, func(r rune) bool { return r == ',' || r == ';' || r == '|' })
+	case []any:
+		for _, t := range tags {
+			if s, ok := t.(string); ok {
+				row.Tags = append(row.Tags, s)
+			}
+		}
+	}
+	return row
+}
+
+func importString(v any) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// codeFromShortURL 从 "bit.ly/abc123"、"https://sho.rt/abc123" 中取出短码 "abc123"
+func codeFromShortURL(short string) string {
+	if short == "" {
+		return ""
+	}
+	if !strings.Contains(short, "://") {
+		short = "https://" + short
+	}
+	u, err := url.Parse(short)
+	if err != nil {
+		return ""
+	}
+	path := strings.Trim(u.Path, "/")
+	if i := strings.LastIndex(path, "/"); i >= 0 {
+		path = path[i+1:]
+	}
+	return path
+}
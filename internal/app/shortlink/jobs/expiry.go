@@ -0,0 +1,65 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/metrics"
+)
+
+// ExpirySweeper 周期性清理过期短链。
+//
+// 过期本身由 Resolve 按 expires_at 实时判断（到点即 410，不依赖本任务），
+// sweeper 只负责在保留期（retention）之后把过期行物理删除，避免表无限膨胀。
+type ExpirySweeper struct {
+	repo      *repo.ShortlinksRepo
+	interval  time.Duration
+	retention time.Duration
+	batchSize int
+}
+
+func NewExpirySweeper(r *repo.ShortlinksRepo, interval, retention time.Duration) *ExpirySweeper {
+	return &ExpirySweeper{
+		repo:      r,
+		interval:  interval,
+		retention: retention,
+		batchSize: 500, //单次事务最多删除的条数，避免长事务
+	}
+}
+
+// 阻塞 清理循环
+func (s *ExpirySweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sweep(ctx)
+		}
+	}
+}
+
+func (s *ExpirySweeper) sweep(ctx context.Context) {
+	before := time.Now().Add(-s.retention)
+	total := 0
+	for ctx.Err() == nil {
+		codes, err := s.repo.PurgeExpired(ctx, before, s.batchSize)
+		if err != nil {
+			slog.Error("expiry sweeper: purge failed", "err", err)
+			return
+		}
+		total += len(codes)
+		if len(codes) < s.batchSize {
+			break
+		}
+	}
+	if total > 0 {
+		metrics.ShortlinkExpiredPurged.Add(float64(total))
+		slog.Info("expiry sweeper: purged", "count", total)
+	}
+}
@@ -0,0 +1,125 @@
+package jobs
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/metrics"
+	"day.local/internal/platform/probe"
+)
+
+// HealthChecker 周期性探测短链的默认目标，记录状态码与耗时，连续失败时标记为故障（跳转改用备用地址）。
+//
+// 设计原因：
+// - 连续失败 threshold 次才判定故障：目标站点偶尔超时很常见，一次失败就切到备用地址会来回抖动
+// - 每批并发 workers 个探测：单个探测可能要等到超时，串行扫一遍全表太慢；并发数固定，避免把别人的站点打挂
+// - 只检查上一轮间隔内没检查过的短链，多实例部署时基本不会重复探测
+// - 探测历史只保留 retention，够看最近的趋势即可
+type HealthChecker struct {
+	repo      *repo.ShortlinksRepo
+	prober    *probe.Prober
+	interval  time.Duration
+	retention time.Duration
+	threshold int
+	workers   int
+	batchSize int
+}
+
+func NewHealthChecker(r *repo.ShortlinksRepo, prober *probe.Prober, interval time.Duration) *HealthChecker {
+	return &HealthChecker{
+		repo:      r,
+		prober:    prober,
+		interval:  interval,
+		retention: 7 * 24 * time.Hour,
+		threshold: 2,
+		workers:   8,
+		batchSize: 200,
+	}
+}
+
+// 阻塞 检查循环
+func (c *HealthChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.check(ctx)
+		}
+	}
+}
+
+func (c *HealthChecker) check(ctx context.Context) {
+	// 留一点余量：上一轮末尾检查的短链，这一轮开始时也算“已过一个间隔”
+	checkedBefore := time.Now().Add(-c.interval / 2)
+	var afterID int64
+	for ctx.Err() == nil {
+		links, err := c.repo.ListLinksForHealthCheck(ctx, afterID, c.batchSize, checkedBefore)
+		if err != nil {
+			slog.Error("health check: list failed", "err", err)
+			break
+		}
+		if len(links) == 0 {
+			break
+		}
+		afterID = links[len(links)-1].ID
+
+		sem := make(chan struct{}, c.workers)
+		var wg sync.WaitGroup
+		for _, item := range links {
+			target := item.Link.HealthCheckURL()
+			if target == "" {
+				continue
+			}
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(item repo.ScanLink, target string) {
+				defer func() { <-sem; wg.Done() }()
+				c.checkOne(ctx, item, target)
+			}(item, target)
+		}
+		wg.Wait()
+
+		if len(links) < c.batchSize {
+			break
+		}
+	}
+
+	if n, err := c.repo.PruneHealthChecks(ctx, time.Now().Add(-c.retention)); err == nil && n > 0 {
+		slog.Info("health check: pruned history", "count", n)
+	}
+}
+
+func (c *HealthChecker) checkOne(ctx context.Context, item repo.ScanLink, target string) {
+	res := c.prober.Probe(ctx, target)
+	check := repo.HealthCheck{
+		CheckedAt:  time.Now(),
+		OK:         res.OK(),
+		StatusCode: res.StatusCode,
+		LatencyMS:  res.Latency.Milliseconds(),
+	}
+	if res.Err != nil {
+		check.Error = res.Err.Error()
+	}
+	result := "ok"
+	if !check.OK {
+		result = "fail"
+	}
+	metrics.DestinationHealthChecks.WithLabelValues(result).Inc()
+
+	down, changed, err := c.repo.RecordHealthCheck(ctx, item.ID, item.Link.Code, check, c.threshold)
+	if err != nil || !changed {
+		return
+	}
+	if down {
+		slog.Warn("health check: destination down", "code", item.Link.Code, "url", target, "status", check.StatusCode, "err", check.Error)
+	} else {
+		slog.Info("health check: destination recovered", "code", item.Link.Code, "url", target)
+	}
+}
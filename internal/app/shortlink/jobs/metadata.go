@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/metrics"
+	"day.local/internal/platform/pagemeta"
+)
+
+// metadataFetchTimeout 是单次抓取（含跟随跳转）的时间上限
+const metadataFetchTimeout = 10 * time.Second
+
+// MetadataFetcher 周期性为新建或改过目标地址的短链抓取目标页面的标题、描述、图标与 Open Graph 图片。
+//
+// 设计原因：
+// - 异步抓取：创建接口不等待目标站点响应，目标站点慢或挂掉都不影响创建
+// - 间隔短（默认 15 秒）、新建的优先：用户创建后很快就能在列表里看到标题
+// - 每次抓取单独限时，并发数固定；失败也记录下来，隔一段时间再重试，不会每轮都去打同一个挂掉的站点
+type MetadataFetcher struct {
+	repo      *repo.ShortlinksRepo
+	fetcher   *pagemeta.Fetcher
+	interval  time.Duration
+	workers   int
+	batchSize int
+}
+
+func NewMetadataFetcher(r *repo.ShortlinksRepo, fetcher *pagemeta.Fetcher, interval time.Duration) *MetadataFetcher {
+	return &MetadataFetcher{
+		repo:      r,
+		fetcher:   fetcher,
+		interval:  interval,
+		workers:   4,
+		batchSize: 50,
+	}
+}
+
+// 阻塞 抓取循环
+func (f *MetadataFetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			f.fetch(ctx)
+		}
+	}
+}
+
+// fetch 每轮只处理一批，剩下的下一轮再抓
+func (f *MetadataFetcher) fetch(ctx context.Context) {
+	links, err := f.repo.ListLinksForMetadata(ctx, f.batchSize)
+	if err != nil {
+		slog.Error("metadata fetch: list failed", "err", err)
+		return
+	}
+
+	sem := make(chan struct{}, f.workers)
+	var wg sync.WaitGroup
+	for _, item := range links {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(link shortlink.Shortlink) {
+			defer func() { <-sem; wg.Done() }()
+			FetchMetadata(ctx, f.repo, f.fetcher, link)
+		}(item.Link)
+	}
+	wg.Wait()
+}
+
+// FetchMetadata 抓取 link 目标页面的元信息并保存；抓取失败时把原因存入 Error 并返回错误。
+// 后台任务与手动重新抓取接口共用。
+func FetchMetadata(ctx context.Context, r *repo.ShortlinksRepo, fetcher *pagemeta.Fetcher, link shortlink.Shortlink) (repo.LinkMetadata, error) {
+	var meta repo.LinkMetadata
+	var fetchErr error
+	if shortlink.IsTemplate(link.URL) {
+		// 模板地址要到跳转时才知道最终地址，没有可抓取的页面
+		fetchErr = errors.New("template destination")
+	} else {
+		fetchCtx, cancel := context.WithTimeout(ctx, metadataFetchTimeout)
+		var page pagemeta.Metadata
+		page, fetchErr = fetcher.Fetch(fetchCtx, link.URL)
+		cancel()
+		meta = repo.LinkMetadata{
+			Title:       page.Title,
+			Description: page.Description,
+			FaviconURL:  page.FaviconURL,
+			ImageURL:    page.ImageURL,
+		}
+	}
+	result := "ok"
+	if fetchErr != nil {
+		result = "fail"
+		meta = repo.LinkMetadata{Error: fetchErr.Error()}
+	}
+	metrics.MetadataFetches.WithLabelValues(result).Inc()
+
+	if err := r.SaveMetadata(ctx, link.Code, link.URL, meta); err != nil && !errors.Is(err, repo.ErrShortlinkNotFound) {
+		slog.Error("metadata fetch: save failed", "code", link.Code, "err", err)
+		return meta, err
+	}
+	meta.FetchedAt = time.Now()
+	return meta, fetchErr
+}
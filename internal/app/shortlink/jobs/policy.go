@@ -0,0 +1,94 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/metrics"
+)
+
+// PolicyRechecker 在目的地域名策略变化后复查已有短链，停用命中 deny（或不在 allow 列表中）的短链。
+//
+// 设计原因：
+// - 策略每次修改都会递增版本号，任务周期性比较版本号而不是由管理接口直接触发：全表扫描可能很久，不能占着请求
+// - 进程重启或扫描中途失败，下一轮还会看到未复查的版本号并补上
+// - 复查完才记录 checked_version，多实例下同一版本通常只扫一遍；偶尔重复扫描也无害（已停用的会跳过）
+// - 停用按管理员停用处理（disabled_by_admin），拥有者不能自行恢复
+type PolicyRechecker struct {
+	repo      *repo.ShortlinksRepo
+	interval  time.Duration
+	batchSize int
+}
+
+func NewPolicyRechecker(r *repo.ShortlinksRepo, interval time.Duration) *PolicyRechecker {
+	return &PolicyRechecker{
+		repo:      r,
+		interval:  interval,
+		batchSize: 500,
+	}
+}
+
+// 阻塞 复查循环
+func (c *PolicyRechecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.recheck(ctx)
+		}
+	}
+}
+
+func (c *PolicyRechecker) recheck(ctx context.Context) {
+	version, pending, err := c.repo.PendingPolicyVersion(ctx)
+	if err != nil || !pending {
+		return
+	}
+	policy, err := c.repo.ReloadDestinationPolicy(ctx)
+	if err != nil {
+		return
+	}
+
+	disabled := 0
+	var afterID int64
+	for ctx.Err() == nil {
+		links, err := c.repo.ListLinksForScan(ctx, afterID, c.batchSize)
+		if err != nil {
+			slog.Error("policy recheck: list failed", "err", err)
+			return
+		}
+		for _, item := range links {
+			afterID = item.ID
+			perr := policy.Check(item.Link.Destinations()...)
+			if perr == nil {
+				continue
+			}
+			err := c.repo.DisableByCode(ctx, item.Link.Code, repo.StateChange{Admin: true, Reason: perr.Error()})
+			if err != nil && !errors.Is(err, repo.ErrAlreadyDisabled) && !errors.Is(err, repo.ErrShortlinkNotFound) {
+				slog.Error("policy recheck: disable failed", "code", item.Link.Code, "err", err)
+				continue
+			}
+			if err == nil {
+				disabled++
+			}
+		}
+		if len(links) < c.batchSize {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if disabled > 0 {
+		metrics.ShortlinkAutoDisabled.WithLabelValues("policy").Add(float64(disabled))
+		slog.Info("policy recheck: disabled", "count", disabled, "version", version)
+	}
+	c.repo.MarkPolicyChecked(ctx, version)
+}
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/metrics"
+	"day.local/internal/platform/urlcheck"
+)
+
+// ReputationScanner 周期性用信誉服务复扫全部未停用短链，停用被标记的短链并记录原因。
+//
+// 设计原因：
+// - 创建时干净的地址之后可能被入侵或改成钓鱼页，只在创建时检查不够；复扫能比举报更早发现
+// - 复扫不受 fail open/closed 配置影响：查询失败只记日志，本轮剩下的短链下一轮再扫
+// - 停用按管理员停用处理（disabled_by_admin），拥有者不能自行恢复；误报由管理员确认后恢复
+type ReputationScanner struct {
+	repo      *repo.ShortlinksRepo
+	checker   urlcheck.Checker
+	interval  time.Duration
+	batchSize int
+}
+
+func NewReputationScanner(r *repo.ShortlinksRepo, checker urlcheck.Checker, interval time.Duration) *ReputationScanner {
+	return &ReputationScanner{
+		repo:      r,
+		checker:   checker,
+		interval:  interval,
+		batchSize: 200,
+	}
+}
+
+// 阻塞 复扫循环
+func (s *ReputationScanner) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.scan(ctx)
+		}
+	}
+}
+
+func (s *ReputationScanner) scan(ctx context.Context) {
+	disabled := 0
+	var afterID int64
+	for ctx.Err() == nil {
+		links, err := s.repo.ListLinksForScan(ctx, afterID, s.batchSize)
+		if err != nil {
+			slog.Error("reputation scan: list failed", "err", err)
+			break
+		}
+		var urls []string
+		for _, item := range links {
+			urls = append(urls, item.Link.Destinations()...)
+		}
+		matches, err := s.checker.Check(ctx, urls)
+		if err != nil {
+			metrics.URLCheckErrors.Inc()
+			slog.Error("reputation scan: check failed", "err", err)
+			break
+		}
+		flagged := make(map[string]urlcheck.Match, len(matches))
+		for _, m := range matches {
+			flagged[m.URL] = m
+		}
+		metrics.URLCheckFlagged.WithLabelValues("rescan").Add(float64(len(flagged)))
+
+		for _, item := range links {
+			afterID = item.ID
+			var hit *urlcheck.Match
+			for _, u := range item.Link.Destinations() {
+				if m, ok := flagged[u]; ok {
+					hit = &m
+					break
+				}
+			}
+			if hit == nil {
+				continue
+			}
+			err := s.repo.DisableByCode(ctx, item.Link.Code, repo.StateChange{Admin: true, Reason: hit.Reason()})
+			if err != nil && !errors.Is(err, repo.ErrAlreadyDisabled) && !errors.Is(err, repo.ErrShortlinkNotFound) {
+				slog.Error("reputation scan: disable failed", "code", item.Link.Code, "err", err)
+				continue
+			}
+			if err == nil {
+				disabled++
+				slog.Warn("reputation scan: disabled", "code", item.Link.Code, "url", hit.URL, "threat", hit.Threat)
+			}
+		}
+		if len(links) < s.batchSize {
+			break
+		}
+	}
+	if disabled > 0 {
+		metrics.ShortlinkAutoDisabled.WithLabelValues("reputation").Add(float64(disabled))
+	}
+}
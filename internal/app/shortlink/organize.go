@@ -0,0 +1,73 @@
+package shortlink
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrInvalidTitle = errors.New("invalid title")
+var ErrInvalidNotes = errors.New("invalid notes")
+var ErrInvalidTags = errors.New("invalid tags")
+var ErrInvalidFolderName = errors.New("invalid folder name")
+
+// 用户整理短链（标题、备注、标签、文件夹）的长度限制，按字符计
+const (
+	maxTitleLen      = 200
+	maxNotesLen      = 2000
+	maxTags          = 20
+	maxTagLen        = 32
+	maxFolderNameLen = 64
+)
+
+// ValidateTitle 校验短链标题，可为空。
+func ValidateTitle(title string) error {
+	if utf8.RuneCountInString(title) > maxTitleLen {
+		return ErrInvalidTitle
+	}
+	return nil
+}
+
+// ValidateNotes 校验短链备注，可为空。
+func ValidateNotes(notes string) error {
+	if utf8.RuneCountInString(notes) > maxNotesLen {
+		return ErrInvalidNotes
+	}
+	return nil
+}
+
+// NormalizeTags 规范化标签：去首尾空白、转小写、去重、丢弃空标签，保持原有顺序。
+//
+// 设计原因：
+// - 标签是自由输入，"Q3"/"q3 " 应当视为同一个，否则按标签筛选会漏
+// - 标签不能包含逗号，方便列表接口用 ?tag=a 传参与前端按逗号拆分输入
+func NormalizeTags(tags []string) ([]string, error) {
+	out := make([]string, 0, len(tags))
+	seen := make(map[string]struct{}, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" {
+			continue
+		}
+		if utf8.RuneCountInString(t) > maxTagLen || strings.ContainsRune(t, ',') {
+			return nil, ErrInvalidTags
+		}
+		if _, ok := seen[t]; ok {
+			continue
+		}
+		seen[t] = struct{}{}
+		out = append(out, t)
+	}
+	if len(out) > maxTags {
+		return nil, ErrInvalidTags
+	}
+	return out, nil
+}
+
+// ValidateFolderName 校验文件夹名称，不能为空。
+func ValidateFolderName(name string) error {
+	if name == "" || utf8.RuneCountInString(name) > maxFolderNameLen {
+		return ErrInvalidFolderName
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package shortlink
+
+import (
+	"errors"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPathNotAllowed = errors.New("shortlink does not accept a path")
+	ErrMissingSegment = errors.New("missing path segment")
+)
+
+// placeholderRe 匹配目标模板里的占位符：{1}~{9} 为路径的第 N 段，{path} 为整段剩余路径
+var placeholderRe = regexp.MustCompile(`\{([1-9]|path)\}`)
+
+// IsTemplate 判断目标地址是否是模板（含 {1}、{path} 等占位符）。
+//
+// 占位符只会出现在 host 之后：url.Parse 不接受 host 里的 "{"，ValidateURL 已经拒绝了这类地址，
+// 访客无法通过路径决定跳到哪个站点。
+func IsTemplate(dest string) bool {
+	return placeholderRe.MatchString(dest)
+}
+
+// AcceptsPath 判断短链是否接受 /{code}/ 之后的剩余路径：开启了 ForwardPath 或目标地址是模板
+func (s Shortlink) AcceptsPath() bool {
+	return s.ForwardPath || IsTemplate(s.URL)
+}
+
+// ExpandPath 把短码之后的路径段 segments 应用到目标地址 dest：
+// - dest 是模板：替换占位符，{N} 缺少对应的段时返回 ErrMissingSegment；多余的段忽略
+// - 否则开启了 ForwardPath：把剩余路径拼到 dest 的路径后面，查询参数与片段保持不变
+// - 否则有剩余路径时返回 ErrPathNotAllowed
+//
+// 每段都会重新转义，"." 与 ".." 直接拒绝，访客不能借剩余路径跳出目标地址的路径前缀。
+func (s Shortlink) ExpandPath(dest string, segments []string) (string, error) {
+	for _, seg := range segments {
+		if seg == "." || seg == ".." {
+			return "", ErrPathNotAllowed
+		}
+	}
+	if IsTemplate(dest) {
+		return expandTemplate(dest, segments)
+	}
+	if len(segments) == 0 {
+		return dest, nil
+	}
+	if !s.ForwardPath {
+		return "", ErrPathNotAllowed
+	}
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	escaped := make([]string, len(segments))
+	for i, seg := range segments {
+		escaped[i] = url.PathEscape(seg)
+	}
+	u.RawPath = strings.TrimSuffix(u.EscapedPath(), "/") + "/" + strings.Join(escaped, "/")
+	u.Path, _ = url.PathUnescape(u.RawPath)
+	return u.String(), nil
+}
+
+// expandTemplate 替换占位符：查询参数/片段里的用查询转义，路径里的用路径转义
+func expandTemplate(dest string, segments []string) (string, error) {
+	queryStart := strings.IndexAny(dest, "?#")
+	var b strings.Builder
+	last := 0
+	for _, loc := range placeholderRe.FindAllStringSubmatchIndex(dest, -1) {
+		b.WriteString(dest[last:loc[0]])
+		last = loc[1]
+		inQuery := queryStart >= 0 && loc[0] > queryStart
+		escape := url.PathEscape
+		if inQuery {
+			escape = url.QueryEscape
+		}
+
+		name := dest[loc[2]:loc[3]]
+		if name == "path" {
+			if len(segments) == 0 {
+				return "", ErrMissingSegment
+			}
+			if inQuery {
+				b.WriteString(escape(strings.Join(segments, "/")))
+				continue
+			}
+			for i, seg := range segments {
+				if i > 0 {
+					b.WriteByte('/')
+				}
+				b.WriteString(escape(seg))
+			}
+			continue
+		}
+		n, _ := strconv.Atoi(name)
+		if n > len(segments) {
+			return "", ErrMissingSegment
+		}
+		b.WriteString(escape(segments[n-1]))
+	}
+	b.WriteString(dest[last:])
+	return b.String(), nil
+}
@@ -0,0 +1,132 @@
+package shortlink
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrDestinationDenied     = errors.New("destination domain denied by policy")
+	ErrDestinationNotAllowed = errors.New("destination domain not in allowlist")
+	ErrInvalidPolicyPattern  = errors.New("invalid policy pattern")
+	ErrInvalidPolicyAction   = errors.New("invalid policy action")
+)
+
+// PolicyAction 是目的地域名策略的动作
+type PolicyAction string
+
+const (
+	PolicyAllow PolicyAction = "allow"
+	PolicyDeny  PolicyAction = "deny"
+)
+
+// PolicyRule 是一条目的地域名策略，Pattern 的写法：
+// - "example.com"：只匹配该域名本身
+// - ".example.com"：匹配该域名及其所有子域名
+// - 含 "*"：通配，"*" 匹配任意字符（包括 "."），例如 "*.example.com"、"paypal-*.com"
+type PolicyRule struct {
+	Pattern string       `json:"pattern"`
+	Action  PolicyAction `json:"action"`
+}
+
+var policyPatternRe = regexp.MustCompile(`^\.?[a-z0-9*]([a-z0-9*.-]*[a-z0-9*])?$`)
+
+// NormalizePolicyPattern 统一大小写并校验策略写法；不接受不含字母数字的模式（例如单独的 "*"）。
+func NormalizePolicyPattern(pattern string) (string, error) {
+	pattern = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(pattern)), ".")
+	if len(pattern) > 253 || !policyPatternRe.MatchString(pattern) || strings.Contains(pattern, "..") {
+		return "", ErrInvalidPolicyPattern
+	}
+	if strings.Trim(pattern, "*.-") == "" {
+		return "", ErrInvalidPolicyPattern
+	}
+	return pattern, nil
+}
+
+func ValidatePolicyAction(action PolicyAction) error {
+	if action != PolicyAllow && action != PolicyDeny {
+		return ErrInvalidPolicyAction
+	}
+	return nil
+}
+
+// Matches 判断域名 host（已小写）是否命中该策略
+func (r PolicyRule) Matches(host string) bool {
+	switch {
+	case strings.Contains(r.Pattern, "*"):
+		// 模式已排除 ? [ \ 等字符，path.Match 里只有 * 是通配；host 不含 "/"，* 可以跨越 "."
+		ok, _ := path.Match(r.Pattern, host)
+		return ok
+	case strings.HasPrefix(r.Pattern, "."):
+		return host == r.Pattern[1:] || strings.HasSuffix(host, r.Pattern)
+	default:
+		return host == r.Pattern
+	}
+}
+
+// DestinationPolicy 是管理员维护的目的地域名策略。
+//
+// 判定顺序：命中任意 deny 即拒绝（deny 优先于 allow）；AllowlistOnly 时还必须命中某条 allow。
+// nil 表示没有策略，全部放行。
+type DestinationPolicy struct {
+	Rules         []PolicyRule
+	AllowlistOnly bool
+}
+
+// CheckHost 按策略检查域名 host，拒绝时返回包装了 ErrDestinationDenied / ErrDestinationNotAllowed 的错误
+func (p *DestinationPolicy) CheckHost(host string) error {
+	if p == nil {
+		return nil
+	}
+	host = NormalizeHost(host)
+	allowed := false
+	for _, r := range p.Rules {
+		if !r.Matches(host) {
+			continue
+		}
+		if r.Action == PolicyDeny {
+			return fmt.Errorf("%w: %s", ErrDestinationDenied, host)
+		}
+		allowed = true
+	}
+	if p.AllowlistOnly && !allowed {
+		return fmt.Errorf("%w: %s", ErrDestinationNotAllowed, host)
+	}
+	return nil
+}
+
+// Check 检查一组目标地址，返回第一个被拒绝的错误。地址应已通过 ValidateURL。
+func (p *DestinationPolicy) Check(rawURLs ...string) error {
+	if p == nil {
+		return nil
+	}
+	for _, raw := range rawURLs {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return ErrInvalidURL
+		}
+		if err := p.CheckHost(u.Hostname()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Destinations 返回短链可能跳到的全部网页地址：默认目标、规则目标、分流版本、备用地址与 App 跳转里的 http(s) 地址
+func (s Shortlink) Destinations() []string {
+	out := []string{s.URL}
+	for _, r := range s.Rules {
+		out = append(out, r.URL)
+	}
+	for _, v := range s.Variants {
+		out = append(out, v.URL)
+	}
+	if s.FallbackURL != "" {
+		out = append(out, s.FallbackURL)
+	}
+	return append(out, s.DeepLink.WebURLs()...)
+}
@@ -0,0 +1,12 @@
+package shortlink
+
+// ShowPreview 判断访问时是否必须先展示预览页：拥有者开启了预览，或被管理员强制开启
+func (s Shortlink) ShowPreview() bool {
+	return s.Preview || s.PreviewForced
+}
+
+// DynamicDestination 判断实际目标是否会因访客而不同（条件规则、A/B 分流、App 跳转）；
+// 预览页据此提示展示的只是默认目标。
+func (s Shortlink) DynamicDestination() bool {
+	return len(s.Rules) > 0 || len(s.Variants) > 0 || s.DeepLink != nil
+}
@@ -0,0 +1,53 @@
+package shortlink
+
+import "errors"
+
+var ErrInvalidRedirectType = errors.New("invalid redirect type")
+var ErrInvalidHeaderValue = errors.New("invalid header value")
+
+// 跳转类型（与 shortlinks.redirect_type 取值一致）。
+//
+// 选择依据：
+// - 301/308 为永久跳转：浏览器与搜索引擎会缓存，适合 SEO 永久链接，但后续点击可能不再经过本服务（统计会偏少）
+// - 302/307 为临时跳转：每次都回到本服务，适合活动链接；307/308 额外保证不改写请求方法
+const (
+	RedirectMovedPermanently = 301
+	RedirectFound            = 302
+	RedirectTemporary        = 307
+	RedirectPermanent        = 308
+	DefaultRedirectType      = RedirectFound
+)
+
+const maxRedirectHeaderValueLen = 256
+
+// ValidateRedirectType 校验跳转类型，0 表示未指定（使用默认 302）。
+func ValidateRedirectType(t int) error {
+	switch t {
+	case 0, RedirectMovedPermanently, RedirectFound, RedirectTemporary, RedirectPermanent:
+		return nil
+	}
+	return ErrInvalidRedirectType
+}
+
+// ValidateHeaderValue 校验创建者附加到跳转响应上的头部值（Cache-Control / X-Robots-Tag）。
+//
+// 只允许可见 ASCII 与空格，防止 CRLF 注入；空字符串表示不设置。
+func ValidateHeaderValue(v string) error {
+	if len(v) > maxRedirectHeaderValueLen {
+		return ErrInvalidHeaderValue
+	}
+	for i := 0; i < len(v); i++ {
+		if v[i] < 0x20 || v[i] > 0x7e {
+			return ErrInvalidHeaderValue
+		}
+	}
+	return nil
+}
+
+// RedirectStatus 返回实际使用的跳转状态码（未指定时为 302）。
+func (s Shortlink) RedirectStatus() int {
+	if s.RedirectType == 0 {
+		return DefaultRedirectType
+	}
+	return s.RedirectType
+}
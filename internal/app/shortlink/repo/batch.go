@@ -0,0 +1,129 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"day.local/internal/platform/metrics"
+	"github.com/jackc/pgx/v5"
+)
+
+// BatchItem 是批量创建中的一条：Private 为 true 时按 CreatePrivate 创建（需要 createdBy）；
+// Meta 为整理信息，在同一个 savepoint 内写入，文件夹归属由调用方保证。
+type BatchItem struct {
+	Link    shortlink.Shortlink
+	Private bool
+	Meta    LinkMeta
+}
+
+// BatchResult 是批量创建中单条的结果：Err 非空表示该条失败，Link 无意义。
+type BatchResult struct {
+	Link shortlink.Shortlink
+	Err  error
+}
+
+// CreateBatch 在一个事务里批量创建短链，规则与逐条调用 Create / CreateWithCustomCode / CreatePrivate 相同
+// （link.Code 非空即自定义短码）。
+//
+// 每条放在独立的 savepoint 里执行：单条失败（短码冲突、跳转选项冲突等）只回滚这一条，
+// 错误记在对应的 BatchResult.Err，不影响其余条目。
+// 返回 error 表示整个批次失败（开启/提交事务失败等），此时没有任何一条生效。
+//
+// 设计原因：
+// - newsletter 一次要生成上千条短链，逐条开事务的往返和锁开销太大；一个事务只提交一次
+// - 布隆过滤器与缓存在提交后统一写入，缓存走一次 pipeline
+func (s *ShortlinksRepo) CreateBatch(ctx context.Context, items []BatchItem, createdBy *int64) ([]BatchResult, error) {
+	// 超时随条数放宽：单条约几毫秒，上限留足余量
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second+time.Duration(len(items))*10*time.Millisecond)
+	defer cancel()
+
+	tx, err := s.db.Begin(dbctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer tx.Rollback(dbctx)
+
+	results := make([]BatchResult, len(items))
+	for i, item := range items {
+		// 嵌套事务即 SAVEPOINT
+		sp, err := tx.Begin(dbctx)
+		if err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		got, err := createBatchItem(dbctx, sp, s.codes, item, createdBy)
+		if err != nil {
+			if rbErr := sp.Rollback(dbctx); rbErr != nil {
+				slog.Error(rbErr.Error())
+				return nil, rbErr
+			}
+			results[i].Err = err
+			continue
+		}
+		if err := sp.Commit(dbctx); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		results[i].Link = got
+	}
+
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	created := make([]shortlink.Shortlink, 0, len(items))
+	for _, res := range results {
+		if res.Err != nil || res.Link.Code == "" {
+			continue
+		}
+		metrics.ShortlinkCreated.Inc()
+		if s.bloom != nil {
+			s.bloom.Add(res.Link.Code)
+		}
+		created = append(created, res.Link)
+	}
+	// 写缓存/覆盖负缓存，同 Create
+	if s.cache != nil && len(created) > 0 {
+		cacheCtx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+		defer cancel()
+		_ = s.cache.SetMany(cacheCtx, created)
+	}
+	return results, nil
+}
+
+func createBatchItem(dbctx context.Context, tx pgx.Tx, codes shortlink.CodeGenerator, item BatchItem, createdBy *int64) (shortlink.Shortlink, error) {
+	var got shortlink.Shortlink
+	var err error
+	switch {
+	case item.Private:
+		if createdBy == nil {
+			return shortlink.Shortlink{}, errors.New("private shortlink requires owner")
+		}
+		got, err = createPrivate(dbctx, tx, codes, item.Link, *createdBy)
+	case item.Link.Code != "":
+		got, err = createSharedWithCode(dbctx, tx, item.Link, createdBy)
+	default:
+		got, err = createShared(dbctx, tx, codes, item.Link, createdBy)
+	}
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	if err := setCreatedLinkMeta(dbctx, tx, createdBy, got.Code, item.Meta); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	return got, nil
+}
+
+// setCreatedLinkMeta 在创建短链的事务内写入整理信息，与短链一起提交或回滚。
+// createdBy 为空（匿名创建）或 meta 为空时什么都不做；文件夹归属由调用方保证。
+func setCreatedLinkMeta(dbctx context.Context, tx pgx.Tx, createdBy *int64, code string, meta LinkMeta) error {
+	if createdBy == nil || meta.empty() {
+		return nil
+	}
+	return setLinkMeta(dbctx, tx, *createdBy, code, meta)
+}
@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"day.local/internal/platform/metrics"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrCodeGenerationFailed = errors.New("could not generate a free short code")
+
+// maxCodeAttempts 是生成短码的最多尝试次数；随机短码长度合适时几乎不会用到第二次
+const maxCodeAttempts = 8
+
+// defaultCodeGenerator 是未配置生成方式时使用的 sqids（默认字母表、最短 3 位）
+var defaultCodeGenerator = func() shortlink.CodeGenerator {
+	g, err := shortlink.NewSqidsGenerator(shortlink.DefaultSqidsAlphabet, 3, nil)
+	if err != nil {
+		panic("sqids init failed: " + err.Error())
+	}
+	return g
+}()
+
+// assignCode 为 id 生成短码并交给 set 写入；短码是保留字或已被占用（唯一约束冲突）时换一个重试。
+//
+// 每次写入都在 SAVEPOINT 里执行：冲突只回滚这一次尝试，不会让外层事务失效。
+// set 返回的其它错误（包括 pgx.ErrNoRows）原样返回，由调用方处理。
+func assignCode(dbctx context.Context, tx pgx.Tx, codes shortlink.CodeGenerator, id int64, set func(tx pgx.Tx, code string) error) error {
+	for attempt := 0; attempt < maxCodeAttempts; attempt++ {
+		code, err := codes.Generate(uint64(id), attempt)
+		if err != nil {
+			slog.Error(err.Error())
+			return err
+		}
+		if shortlink.ValidateCode(code) != nil {
+			continue
+		}
+
+		sp, err := tx.Begin(dbctx)
+		if err != nil {
+			slog.Error(err.Error())
+			return err
+		}
+		err = set(sp, code)
+		if err == nil {
+			if err := sp.Commit(dbctx); err != nil {
+				slog.Error(err.Error())
+				return err
+			}
+			return nil
+		}
+		if rbErr := sp.Rollback(dbctx); rbErr != nil {
+			slog.Error(rbErr.Error())
+			return rbErr
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			metrics.ShortcodeCollisions.Inc()
+			continue
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Error(err.Error())
+		}
+		return err
+	}
+	slog.Error("short code generation exhausted", "id", id, "attempts", maxCodeAttempts)
+	return ErrCodeGenerationFailed
+}
+
+// TakenCodes 返回 codes 中已被占用的短码；domainID 非 0 时检查该自定义域名下的 key。
+//
+// 布隆过滤器判定一定不存在的直接视为可用，剩下的用一次查询确认。停用、过期未清理的短链同样占用短码。
+// 布隆过滤器只含本实例见过的短码，多实例部署时可能把别的实例刚创建的短码判为可用，创建时仍以唯一约束为准。
+func (s *ShortlinksRepo) TakenCodes(ctx context.Context, domainID int64, codes []string) (map[string]bool, error) {
+	taken := make(map[string]bool)
+	check := make([]string, 0, len(codes))
+	for _, code := range codes {
+		key := code
+		if domainID != 0 {
+			key = domainAlias(domainID, code)
+		}
+		if s.bloom == nil || s.bloom.MightExist(key) {
+			check = append(check, code)
+		}
+	}
+	if len(check) == 0 {
+		return taken, nil
+	}
+
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	var rows pgx.Rows
+	var err error
+	if domainID != 0 {
+		rows, err = s.db.Query(dbctx, "SELECT domain_key FROM shortlinks WHERE domain_id=$1 AND domain_key = ANY($2)", domainID, check)
+	} else {
+		rows, err = s.db.Query(dbctx, "SELECT code FROM shortlinks WHERE code = ANY($1)", check)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		taken[code] = true
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	return taken, nil
+}
@@ -0,0 +1,281 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrDomainNotFound = errors.New("domain not found")
+var ErrDomainAlreadyExists = errors.New("domain already exists")
+var ErrDomainNotVerified = errors.New("domain not verified")
+var ErrDomainInUse = errors.New("domain still has shortlinks")
+
+// Domain 是用户注册的自定义域名。VerifyToken 只在拥有者查看时通过 TXT 记录值下发，不直接序列化。
+type Domain struct {
+	ID          int64      `json:"id"`
+	Host        string     `json:"host"`
+	VerifyToken string     `json:"-"`
+	VerifiedAt  *time.Time `json:"verified_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+func (d Domain) Verified() bool {
+	return d.VerifiedAt != nil
+}
+
+// domainAlias 是自定义域名上短链的别名，布隆过滤器与别名缓存都以它为键。
+// 以 "d{id}/" 开头，不会与短码（只含字母数字）冲突。
+func domainAlias(domainID int64, key string) string {
+	return "d" + strconv.FormatInt(domainID, 10) + "/" + key
+}
+
+// domainHostsRefresh 是已校验域名快照的刷新间隔
+const domainHostsRefresh = 30 * time.Second
+
+// domainHosts 是已校验域名 host -> id 的进程内快照。
+//
+// 设计原因：
+// - 每次跳转都要按 Host 头判断是不是自定义域名，域名数量少、变化少，整表放内存比逐次查 DB/Redis 便宜
+// - 刷新失败时沿用旧快照，DB 抖动不影响默认域名与已知域名的跳转
+// - 本进程内校验/删除域名后立即重新加载；其它实例最多延迟一个刷新间隔
+// - 快照过期时用 singleflight 合并重新加载：高并发跳转同时发现过期也只查一次 DB
+type domainHosts struct {
+	mu       sync.RWMutex
+	ids      map[string]int64
+	loadedAt time.Time
+	reload   singleflight.Group
+}
+
+// CreateDomain 为 ownerID 认领域名 host。
+//
+// 未校验的认领不独占 host：几个用户可以同时认领，谁先通过 DNS 校验谁得到它（见 MarkDomainVerified）；
+// 否则任何人都能抢先认领别人的域名，让真正的拥有者无法使用。
+// 已有人校验通过，或 ownerID 已认领过该 host 时返回 ErrDomainAlreadyExists。
+func (u *ShortlinksRepo) CreateDomain(ctx context.Context, ownerID int64, host, token string) (Domain, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	d := Domain{Host: host, VerifyToken: token}
+	if err := u.db.QueryRow(dbctx, `INSERT INTO domains (host, owner_id, verify_token)
+		SELECT $1, $2, $3 WHERE NOT EXISTS (SELECT 1 FROM domains WHERE host=$1 AND verified_at IS NOT NULL)
+		RETURNING id, created_at`, host, ownerID, token).
+		Scan(&d.ID, &d.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.Is(err, pgx.ErrNoRows) || (errors.As(err, &pgErr) && pgErr.Code == "23505") {
+			return Domain{}, ErrDomainAlreadyExists
+		}
+		slog.Error(err.Error())
+		return Domain{}, err
+	}
+	return d, nil
+}
+
+func (u *ShortlinksRepo) ListDomains(ctx context.Context, ownerID int64) ([]Domain, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	rows, err := u.db.Query(dbctx, "SELECT id, host, verify_token, verified_at, created_at FROM domains WHERE owner_id=$1 ORDER BY host", ownerID)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Domain{}
+	for rows.Next() {
+		var d Domain
+		if err := rows.Scan(&d.ID, &d.Host, &d.VerifyToken, &d.VerifiedAt, &d.CreatedAt); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		result = append(result, d)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDomain 查询 ownerID 名下的域名；别人的域名与不存在一样返回 ErrDomainNotFound。
+func (u *ShortlinksRepo) GetDomain(ctx context.Context, ownerID int64, host string) (Domain, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var d Domain
+	if err := u.db.QueryRow(dbctx, "SELECT id, host, verify_token, verified_at, created_at FROM domains WHERE host=$1 AND owner_id=$2", host, ownerID).
+		Scan(&d.ID, &d.Host, &d.VerifyToken, &d.VerifiedAt, &d.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Domain{}, ErrDomainNotFound
+		}
+		slog.Error(err.Error())
+		return Domain{}, err
+	}
+	return d, nil
+}
+
+// MarkDomainVerified 记录域名已通过校验（重复校验保留第一次的时间），并刷新本进程的域名快照。
+// 同一 host 已被其他认领者校验（包括并发校验中先提交的一方）时返回 ErrDomainAlreadyExists。
+func (u *ShortlinksRepo) MarkDomainVerified(ctx context.Context, domainID int64) (Domain, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var d Domain
+	if err := u.db.QueryRow(dbctx, `UPDATE domains SET verified_at=COALESCE(verified_at, now()) WHERE id=$1
+		RETURNING id, host, verify_token, verified_at, created_at`, domainID).
+		Scan(&d.ID, &d.Host, &d.VerifyToken, &d.VerifiedAt, &d.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Domain{}, ErrDomainNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return Domain{}, ErrDomainAlreadyExists
+		}
+		slog.Error(err.Error())
+		return Domain{}, err
+	}
+	u.reloadDomainHosts(ctx)
+	return d, nil
+}
+
+// DeleteDomain 删除 ownerID 名下的域名；域名下还有短链时返回 ErrDomainInUse，需先删除或停用这些短链。
+func (u *ShortlinksRepo) DeleteDomain(ctx context.Context, ownerID int64, host string) error {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	tag, err := u.db.Exec(dbctx, "DELETE FROM domains WHERE host=$1 AND owner_id=$2", host, ownerID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrDomainInUse
+		}
+		slog.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrDomainNotFound
+	}
+	u.reloadDomainHosts(ctx)
+	return nil
+}
+
+// domainIDForHost 返回已校验域名 host 的 id，0 表示不是自定义域名（按默认域名处理）。
+func (u *ShortlinksRepo) domainIDForHost(ctx context.Context, host string) int64 {
+	u.domains.mu.RLock()
+	id, stale := u.domains.ids[host], time.Since(u.domains.loadedAt) > domainHostsRefresh
+	u.domains.mu.RUnlock()
+	if stale {
+		// 合并的加载不能随第一个请求取消而失败，否则所有等待者都拿旧快照再等一个间隔
+		ids, _, _ := u.domains.reload.Do("hosts", func() (any, error) {
+			return u.reloadDomainHosts(context.WithoutCancel(ctx)), nil
+		})
+		id = ids.(map[string]int64)[host]
+	}
+	return id
+}
+
+// reloadDomainHosts 重新加载已校验域名快照并返回最新的映射；失败时保留旧快照，等下一个间隔再试。
+func (u *ShortlinksRepo) reloadDomainHosts(ctx context.Context) map[string]int64 {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	ids, err := u.loadDomainHosts(dbctx)
+
+	u.domains.mu.Lock()
+	defer u.domains.mu.Unlock()
+	u.domains.loadedAt = time.Now()
+	if err != nil {
+		slog.Error("load custom domains failed", "err", err)
+		return u.domains.ids
+	}
+	u.domains.ids = ids
+	return ids
+}
+
+func (u *ShortlinksRepo) loadDomainHosts(ctx context.Context) (map[string]int64, error) {
+	rows, err := u.db.Query(ctx, "SELECT host, id FROM domains WHERE verified_at IS NOT NULL")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ids := map[string]int64{}
+	for rows.Next() {
+		var host string
+		var id int64
+		if err := rows.Scan(&host, &id); err != nil {
+			return nil, err
+		}
+		ids[host] = id
+	}
+	return ids, rows.Err()
+}
+
+// ResolveHost 按请求的 host 与路径 key 解析短链：
+// - host 不是已校验的自定义域名：key 即短码，按 Resolve 解析；挂在自定义域名上的短链不能通过默认域名访问
+// - 否则按 (域名, key) 查到内部短码，再按 Resolve 解析（缓存、过期判断都复用短码那一套）
+//
+// 错误约定同 Resolve。
+func (u *ShortlinksRepo) ResolveHost(ctx context.Context, host, key string) (shortlink.Shortlink, error) {
+	domainID := u.domainIDForHost(ctx, host)
+	if domainID == 0 {
+		link, err := u.Resolve(ctx, key)
+		if err == nil && link.DomainID != 0 {
+			return shortlink.Shortlink{}, ErrShortlinkNotFound
+		}
+		return link, err
+	}
+
+	code, err := u.codeForAlias(ctx, domainID, key)
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	link, err := u.Resolve(ctx, code)
+	if err == nil && link.DomainID != domainID {
+		return shortlink.Shortlink{}, ErrShortlinkNotFound
+	}
+	return link, err
+}
+
+// codeForAlias 把自定义域名上的 key 换成内部短码：布隆过滤器 -> 别名缓存 -> DB，查不到写负缓存。
+func (u *ShortlinksRepo) codeForAlias(ctx context.Context, domainID int64, key string) (string, error) {
+	alias := domainAlias(domainID, key)
+	if u.bloom != nil && !u.bloom.MightExist(alias) {
+		return "", ErrShortlinkNotFound
+	}
+	if u.cache != nil {
+		if code, found, _ := u.cache.GetAlias(ctx, alias); found {
+			if code == "" {
+				return "", ErrShortlinkNotFound
+			}
+			return code, nil
+		}
+	}
+
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	var code string
+	if err := u.db.QueryRow(dbctx, "SELECT code FROM shortlinks WHERE domain_id=$1 AND domain_key=$2 AND code IS NOT NULL", domainID, key).Scan(&code); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			if u.cache != nil {
+				u.cache.SetAliasNotFound(ctx, alias)
+			}
+			return "", ErrShortlinkNotFound
+		}
+		slog.Error(err.Error())
+		return "", err
+	}
+	if u.cache != nil {
+		u.cache.SetAlias(ctx, alias, code)
+	}
+	return code, nil
+}
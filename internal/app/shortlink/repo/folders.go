@@ -0,0 +1,212 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrFolderNotFound = errors.New("folder not found")
+var ErrFolderAlreadyExists = errors.New("folder already exists")
+
+type Folder struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	LinkCount int64     `json:"link_count"`
+}
+
+// LinkMeta 是用户对自己名下短链的整理信息（挂在 user_shortlinks 上）。
+//
+// 字段为 nil 表示不修改；FolderID 指向 0 表示移出文件夹。
+type LinkMeta struct {
+	Title    *string
+	Notes    *string
+	Tags     *[]string
+	FolderID *int64
+}
+
+func (u *ShortlinksRepo) CreateFolder(ctx context.Context, userID int64, name string) (Folder, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	f := Folder{Name: name}
+	if err := u.db.QueryRow(dbctx, "INSERT INTO user_folders (user_id, name) VALUES ($1, $2) RETURNING id, created_at", userID, name).
+		Scan(&f.ID, &f.CreatedAt); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return Folder{}, ErrFolderAlreadyExists
+		}
+		slog.Error(err.Error())
+		return Folder{}, err
+	}
+	return f, nil
+}
+
+func (u *ShortlinksRepo) ListFolders(ctx context.Context, userID int64) ([]Folder, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	rows, err := u.db.Query(dbctx, `SELECT f.id, f.name, f.created_at, count(us.shortlink_id)
+		FROM user_folders f LEFT JOIN user_shortlinks us ON us.folder_id = f.id
+		WHERE f.user_id = $1
+		GROUP BY f.id ORDER BY f.name`, userID)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := []Folder{}
+	for rows.Next() {
+		var f Folder
+		if err := rows.Scan(&f.ID, &f.Name, &f.CreatedAt, &f.LinkCount); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	return result, nil
+}
+
+func (u *ShortlinksRepo) RenameFolder(ctx context.Context, userID, folderID int64, name string) error {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	tag, err := u.db.Exec(dbctx, "UPDATE user_folders SET name=$3 WHERE id=$1 AND user_id=$2", folderID, userID, name)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrFolderAlreadyExists
+		}
+		slog.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+// DeleteFolder 删除文件夹，里面的短链不会删除，只是移出文件夹（外键 ON DELETE SET NULL）。
+func (u *ShortlinksRepo) DeleteFolder(ctx context.Context, userID, folderID int64) error {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	tag, err := u.db.Exec(dbctx, "DELETE FROM user_folders WHERE id=$1 AND user_id=$2", folderID, userID)
+	if err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrFolderNotFound
+	}
+	return nil
+}
+
+func (u *ShortlinksRepo) UserOwnsFolder(ctx context.Context, userID, folderID int64) (bool, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var exists bool
+	if err := u.db.QueryRow(dbctx, "SELECT EXISTS(SELECT 1 FROM user_folders WHERE id=$1 AND user_id=$2)", folderID, userID).
+		Scan(&exists); err != nil {
+		slog.Error(err.Error())
+		return false, err
+	}
+	return exists, nil
+}
+
+// UpdateLinkMeta 修改用户名下某条短链的整理信息，只更新 meta 中非 nil 的字段。
+//
+// 返回 ErrShortlinkNotFound（该用户名下没有这个短码）或 ErrFolderNotFound（文件夹不属于该用户）。
+func (u *ShortlinksRepo) UpdateLinkMeta(ctx context.Context, userID int64, code string, meta LinkMeta) error {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	if meta.FolderID != nil && *meta.FolderID != 0 {
+		owns, err := u.UserOwnsFolder(dbctx, userID, *meta.FolderID)
+		if err != nil {
+			return err
+		}
+		if !owns {
+			return ErrFolderNotFound
+		}
+	}
+	return setLinkMeta(dbctx, u.db, userID, code, meta)
+}
+
+// rowQuerier 是 *pgxpool.Pool 与 pgx.Tx 共有的单行查询能力，便于同一段 SQL 在事务内外复用
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// setLinkMeta 执行整理信息的 UPDATE，不校验文件夹归属（由调用方保证）。
+func setLinkMeta(dbctx context.Context, q rowQuerier, userID int64, code string, meta LinkMeta) error {
+	args := []any{userID, code}
+	var sets []string
+	add := func(col string, v any) {
+		args = append(args, v)
+		sets = append(sets, col+"=$"+strconv.Itoa(len(args)))
+	}
+	if meta.Title != nil {
+		add("title", nullIfEmpty(*meta.Title))
+	}
+	if meta.Notes != nil {
+		add("notes", nullIfEmpty(*meta.Notes))
+	}
+	if meta.Tags != nil {
+		add("tags", *meta.Tags)
+	}
+	if meta.FolderID != nil {
+		if *meta.FolderID == 0 {
+			add("folder_id", nil)
+		} else {
+			add("folder_id", *meta.FolderID)
+		}
+	}
+	if len(sets) == 0 {
+		return nil
+	}
+
+	// folder 校验与更新之间文件夹可能被删除，此时外键会置空或报错，按不存在处理即可
+	var ok int
+	err := q.QueryRow(dbctx, `UPDATE user_shortlinks us SET `+strings.Join(sets, ", ")+`
+		FROM shortlinks s
+		WHERE s.id = us.shortlink_id AND us.user_id = $1 AND s.code = $2
+		RETURNING 1`, args...).Scan(&ok)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrShortlinkNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23503" {
+			return ErrFolderNotFound
+		}
+		slog.Error(err.Error())
+		return err
+	}
+	return nil
+}
+
+// empty 表示没有任何要修改的字段
+func (m LinkMeta) empty() bool {
+	return m.Title == nil && m.Notes == nil && m.Tags == nil && m.FolderID == nil
+}
+
+func nullIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
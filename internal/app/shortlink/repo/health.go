@@ -0,0 +1,160 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"github.com/jackc/pgx/v5"
+)
+
+// HealthCheck 是一次目标地址探测记录，StatusCode 为 0 表示没有拿到响应
+type HealthCheck struct {
+	CheckedAt  time.Time `json:"checked_at"`
+	OK         bool      `json:"ok"`
+	StatusCode int       `json:"status_code,omitempty"`
+	LatencyMS  int64     `json:"latency_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// HealthStatus 是短链目标地址的健康状态与最近的探测历史
+type HealthStatus struct {
+	DestinationDown bool          `json:"destination_down"`
+	Failures        int           `json:"consecutive_failures"`
+	CheckedAt       *time.Time    `json:"checked_at,omitempty"`
+	FallbackURL     string        `json:"fallback_url,omitempty"`
+	Checks          []HealthCheck `json:"checks"`
+}
+
+// RecordHealthCheck 记录一次探测并更新连续失败次数：连续失败达到 threshold 次判定为故障，成功一次即恢复。
+// 状态变化时失效缓存（跳转要切到/切回备用地址），changed 表示本次是否改变了故障状态。
+func (u *ShortlinksRepo) RecordHealthCheck(ctx context.Context, id int64, code string, check HealthCheck, threshold int) (down, changed bool, err error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	tx, err := u.db.Begin(dbctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return false, false, err
+	}
+	defer tx.Rollback(dbctx)
+
+	if _, err := tx.Exec(dbctx, `INSERT INTO destination_checks (shortlink_id, checked_at, ok, status_code, latency_ms, error)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6,''))`, id, check.CheckedAt, check.OK, check.StatusCode, check.LatencyMS, check.Error); err != nil {
+		slog.Error(err.Error())
+		return false, false, err
+	}
+	// FROM 子句里的 old 是更新前的行，用来判断状态是否变化
+	var was bool
+	if err := tx.QueryRow(dbctx, `UPDATE shortlinks s SET
+			health_failures = CASE WHEN $2 THEN 0 ELSE old.health_failures+1 END,
+			dest_down = NOT $2 AND old.health_failures+1 >= $3,
+			health_checked_at = $4
+		FROM (SELECT id, dest_down, health_failures FROM shortlinks WHERE id=$1) old
+		WHERE s.id=old.id
+		RETURNING old.dest_down, s.dest_down`, id, check.OK, threshold, check.CheckedAt).Scan(&was, &down); err != nil {
+		slog.Error(err.Error())
+		return false, false, err
+	}
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return false, false, err
+	}
+
+	if was != down && u.cache != nil {
+		u.cache.Delete(ctx, code)
+	}
+	return down, was != down, nil
+}
+
+// GetHealth 返回短码的健康状态与最近 limit 次探测（新的在前）
+func (u *ShortlinksRepo) GetHealth(ctx context.Context, code string, limit int) (HealthStatus, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var id int64
+	st := HealthStatus{Checks: []HealthCheck{}}
+	if err := u.db.QueryRow(dbctx, "SELECT id, dest_down, health_failures, health_checked_at, COALESCE(fallback_url,'') FROM shortlinks WHERE code=$1", code).
+		Scan(&id, &st.DestinationDown, &st.Failures, &st.CheckedAt, &st.FallbackURL); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return HealthStatus{}, ErrShortlinkNotFound
+		}
+		slog.Error(err.Error())
+		return HealthStatus{}, err
+	}
+
+	rows, err := u.db.Query(dbctx, `SELECT checked_at, ok, status_code, latency_ms, COALESCE(error,'')
+		FROM destination_checks WHERE shortlink_id=$1 ORDER BY checked_at DESC LIMIT $2`, id, limit)
+	if err != nil {
+		slog.Error(err.Error())
+		return HealthStatus{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c HealthCheck
+		if err := rows.Scan(&c.CheckedAt, &c.OK, &c.StatusCode, &c.LatencyMS, &c.Error); err != nil {
+			slog.Error(err.Error())
+			return HealthStatus{}, err
+		}
+		st.Checks = append(st.Checks, c)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return HealthStatus{}, err
+	}
+	return st, nil
+}
+
+// PruneHealthChecks 删除 before 之前的探测记录，返回删除条数
+func (u *ShortlinksRepo) PruneHealthChecks(ctx context.Context, before time.Time) (int64, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tag, err := u.db.Exec(dbctx, "DELETE FROM destination_checks WHERE checked_at<$1", before)
+	if err != nil {
+		slog.Error(err.Error())
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// UpdateFallbackURL 修改短码的备用地址（空字符串表示取消），并失效缓存。
+//
+// 行为约定同 UpdateURL：code 不存在 ErrShortlinkNotFound；不是 userID 自己创建的独立行 ErrShortlinkShared。
+func (u *ShortlinksRepo) UpdateFallbackURL(ctx context.Context, userID int64, code string, url string) (shortlink.Shortlink, error) {
+	return u.updateEditable(ctx, userID, code, "fallback_url=NULLIF($1,'')", url)
+}
+
+// ListLinksForHealthCheck 按 id 升序列出 afterID 之后、checkedBefore 之后还没检查过的未停用短链（最多 limit 条）。
+// 多实例同时跑健康检查时，先检查到的实例会更新 health_checked_at，其它实例大多会跳过。
+func (u *ShortlinksRepo) ListLinksForHealthCheck(ctx context.Context, afterID int64, limit int, checkedBefore time.Time) ([]ScanLink, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := u.db.Query(dbctx, `SELECT id, `+linkColumns+` FROM shortlinks
+		WHERE id>$1 AND disabled=false AND code IS NOT NULL AND (expires_at IS NULL OR expires_at>now())
+			AND (health_checked_at IS NULL OR health_checked_at<$3)
+		ORDER BY id LIMIT $2`, afterID, limit, checkedBefore)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ScanLink
+	for rows.Next() {
+		var item ScanLink
+		if err := rows.Scan(append([]any{&item.ID}, linkDest(&item.Link)...)...); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package repo
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// LinkMetadata 是目标页面的元信息（标题、描述、图标、Open Graph 图片），由后台任务抓取
+type LinkMetadata struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	FaviconURL  string    `json:"favicon_url,omitempty"`
+	ImageURL    string    `json:"image_url,omitempty"`
+	Error       string    `json:"error,omitempty"` // 上次抓取失败的原因
+	FetchedAt   time.Time `json:"fetched_at"`
+}
+
+// metadataRetryAfter 是抓取失败后多久再重试
+const metadataRetryAfter = 24 * time.Hour
+
+// metadataColumns 与 metadataRow 配合，用于 LEFT JOIN link_metadata m 的查询；JOIN 条件带上 m.url=s.url，目标地址改过后旧的元信息不再返回
+const metadataColumns = "COALESCE(m.title,''), COALESCE(m.description,''), COALESCE(m.favicon_url,''), COALESCE(m.image_url,''), COALESCE(m.error,''), m.fetched_at"
+
+type metadataRow struct {
+	meta      LinkMetadata
+	fetchedAt *time.Time
+}
+
+func (r *metadataRow) dest() []any {
+	return []any{&r.meta.Title, &r.meta.Description, &r.meta.FaviconURL, &r.meta.ImageURL, &r.meta.Error, &r.fetchedAt}
+}
+
+// result 返回抓取结果，还没抓取过时为 nil
+func (r *metadataRow) result() *LinkMetadata {
+	if r.fetchedAt == nil {
+		return nil
+	}
+	m := r.meta
+	m.FetchedAt = *r.fetchedAt
+	return &m
+}
+
+// ListLinksForMetadata 列出需要抓取元信息的未停用短链（最多 limit 条，新建的优先）：
+// 从未抓取过、目标地址改过，或上次失败已超过 metadataRetryAfter。
+func (u *ShortlinksRepo) ListLinksForMetadata(ctx context.Context, limit int) ([]ScanLink, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := u.db.Query(dbctx, `SELECT id, `+linkColumns+` FROM shortlinks s
+		WHERE disabled=false AND code IS NOT NULL AND NOT EXISTS (
+			SELECT 1 FROM link_metadata m WHERE m.shortlink_id=s.id AND m.url=s.url AND (m.error IS NULL OR m.fetched_at>=$2))
+		ORDER BY id DESC LIMIT $1`, limit, time.Now().Add(-metadataRetryAfter))
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ScanLink
+	for rows.Next() {
+		var item ScanLink
+		if err := rows.Scan(append([]any{&item.ID}, linkDest(&item.Link)...)...); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveMetadata 保存短码 code 按目标地址 url 抓取到的元信息（覆盖旧的）；抓取失败时 meta.Error 非空。
+// 目标地址在抓取期间被改过时不保存，等下一轮按新地址抓取。
+func (u *ShortlinksRepo) SaveMetadata(ctx context.Context, code, url string, meta LinkMetadata) error {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	tag, err := u.db.Exec(dbctx, `INSERT INTO link_metadata (shortlink_id, url, title, description, favicon_url, image_url, error, fetched_at)
+		SELECT id, url, NULLIF($3,''), NULLIF($4,''), NULLIF($5,''), NULLIF($6,''), NULLIF($7,''), now() FROM shortlinks WHERE code=$1 AND url=$2
+		ON CONFLICT (shortlink_id) DO UPDATE SET url=EXCLUDED.url, title=EXCLUDED.title, description=EXCLUDED.description,
+			favicon_url=EXCLUDED.favicon_url, image_url=EXCLUDED.image_url, error=EXCLUDED.error, fetched_at=EXCLUDED.fetched_at`,
+		code, url, meta.Title, meta.Description, meta.FaviconURL, meta.ImageURL, meta.Error)
+	if err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrShortlinkNotFound
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var ErrPolicyNotFound = errors.New("policy not found")
+var ErrPolicyAlreadyExists = errors.New("policy already exists")
+
+// PolicyEntry 是一条管理员维护的目的地域名策略
+type PolicyEntry struct {
+	ID        int64                  `json:"id"`
+	Pattern   string                 `json:"pattern"`
+	Action    shortlink.PolicyAction `json:"action"`
+	Note      string                 `json:"note,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// policyRefresh 是策略快照的刷新间隔
+const policyRefresh = 30 * time.Second
+
+// policySnapshot 是目的地域名策略的进程内快照，与 domainHosts 相同：
+// 创建/修改短链都要检查，策略条目少、变化少，整表放内存；本进程修改后立即失效，其它实例最多延迟一个刷新间隔。
+type policySnapshot struct {
+	mu       sync.RWMutex
+	policy   *shortlink.DestinationPolicy
+	loadedAt time.Time
+}
+
+func (u *ShortlinksRepo) ListPolicies(ctx context.Context) ([]PolicyEntry, bool, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var allowlistOnly bool
+	if err := u.db.QueryRow(dbctx, "SELECT allowlist_only FROM destination_policy_settings").Scan(&allowlistOnly); err != nil {
+		slog.Error(err.Error())
+		return nil, false, err
+	}
+	rows, err := u.db.Query(dbctx, "SELECT id, pattern, action, COALESCE(note,''), created_at FROM destination_policies ORDER BY pattern")
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, false, err
+	}
+	defer rows.Close()
+
+	result := []PolicyEntry{}
+	for rows.Next() {
+		var e PolicyEntry
+		if err := rows.Scan(&e.ID, &e.Pattern, &e.Action, &e.Note, &e.CreatedAt); err != nil {
+			slog.Error(err.Error())
+			return nil, false, err
+		}
+		result = append(result, e)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, false, err
+	}
+	return result, allowlistOnly, nil
+}
+
+// AddPolicy 新增一条策略并递增策略版本（触发复查），同一 pattern 已存在返回 ErrPolicyAlreadyExists。
+func (u *ShortlinksRepo) AddPolicy(ctx context.Context, rule shortlink.PolicyRule, note string, createdBy int64) (PolicyEntry, error) {
+	e := PolicyEntry{Pattern: rule.Pattern, Action: rule.Action, Note: note}
+	err := u.changePolicy(ctx, func(dbctx context.Context, tx pgx.Tx) error {
+		return tx.QueryRow(dbctx, `INSERT INTO destination_policies (pattern, action, note, created_by)
+			VALUES ($1, $2, NULLIF($3,''), $4) RETURNING id, created_at`, rule.Pattern, string(rule.Action), note, createdBy).
+			Scan(&e.ID, &e.CreatedAt)
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return PolicyEntry{}, ErrPolicyAlreadyExists
+		}
+		return PolicyEntry{}, err
+	}
+	return e, nil
+}
+
+func (u *ShortlinksRepo) DeletePolicy(ctx context.Context, id int64) error {
+	return u.changePolicy(ctx, func(dbctx context.Context, tx pgx.Tx) error {
+		tag, err := tx.Exec(dbctx, "DELETE FROM destination_policies WHERE id=$1", id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrPolicyNotFound
+		}
+		return nil
+	})
+}
+
+// SetAllowlistOnly 打开/关闭“只放行 allow 列表”模式
+func (u *ShortlinksRepo) SetAllowlistOnly(ctx context.Context, on bool) error {
+	return u.changePolicy(ctx, func(dbctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(dbctx, "UPDATE destination_policy_settings SET allowlist_only=$1", on)
+		return err
+	})
+}
+
+// changePolicy 在一个事务里修改策略并递增版本号，提交后失效本进程的策略快照。
+func (u *ShortlinksRepo) changePolicy(ctx context.Context, fn func(context.Context, pgx.Tx) error) error {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := u.db.Begin(dbctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	defer tx.Rollback(dbctx)
+
+	if err := fn(dbctx, tx); err != nil {
+		if !errors.Is(err, ErrPolicyNotFound) {
+			slog.Error(err.Error())
+		}
+		return err
+	}
+	if _, err := tx.Exec(dbctx, "UPDATE destination_policy_settings SET version=version+1, updated_at=now()"); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	u.policy.mu.Lock()
+	u.policy.loadedAt = time.Time{}
+	u.policy.mu.Unlock()
+	return nil
+}
+
+// DestinationPolicy 返回当前的目的地域名策略（进程内快照）。
+//
+// 刷新失败时沿用旧快照；从未加载成功时返回错误，调用方应拒绝创建而不是绕过策略。
+func (u *ShortlinksRepo) DestinationPolicy(ctx context.Context) (*shortlink.DestinationPolicy, error) {
+	u.policy.mu.RLock()
+	p, fresh := u.policy.policy, time.Since(u.policy.loadedAt) <= policyRefresh
+	u.policy.mu.RUnlock()
+	if fresh && p != nil {
+		return p, nil
+	}
+	return u.ReloadDestinationPolicy(ctx)
+}
+
+// ReloadDestinationPolicy 立即从 DB 重新加载策略快照；失败规则同 DestinationPolicy。
+// 策略复查任务用它拿到与版本号一致的最新策略，而不是其它实例修改前的旧快照。
+func (u *ShortlinksRepo) ReloadDestinationPolicy(ctx context.Context) (*shortlink.DestinationPolicy, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	loaded, err := u.loadPolicy(dbctx)
+
+	u.policy.mu.Lock()
+	defer u.policy.mu.Unlock()
+	if err != nil {
+		slog.Error("load destination policy failed", "err", err)
+		if u.policy.policy == nil {
+			return nil, err
+		}
+		u.policy.loadedAt = time.Now()
+		return u.policy.policy, nil
+	}
+	u.policy.policy, u.policy.loadedAt = loaded, time.Now()
+	return loaded, nil
+}
+
+func (u *ShortlinksRepo) loadPolicy(ctx context.Context) (*shortlink.DestinationPolicy, error) {
+	p := &shortlink.DestinationPolicy{}
+	if err := u.db.QueryRow(ctx, "SELECT allowlist_only FROM destination_policy_settings").Scan(&p.AllowlistOnly); err != nil {
+		return nil, err
+	}
+	rows, err := u.db.Query(ctx, "SELECT pattern, action FROM destination_policies")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var r shortlink.PolicyRule
+		if err := rows.Scan(&r.Pattern, &r.Action); err != nil {
+			return nil, err
+		}
+		p.Rules = append(p.Rules, r)
+	}
+	return p, rows.Err()
+}
+
+// PendingPolicyVersion 返回尚未复查的策略版本；ok 为 false 表示当前版本已经复查过。
+func (u *ShortlinksRepo) PendingPolicyVersion(ctx context.Context) (version int64, ok bool, err error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	var checked int64
+	if err := u.db.QueryRow(dbctx, "SELECT version, checked_version FROM destination_policy_settings").Scan(&version, &checked); err != nil {
+		slog.Error(err.Error())
+		return 0, false, err
+	}
+	return version, version > checked, nil
+}
+
+// MarkPolicyChecked 记录版本 version 已复查完；不会把 checked_version 往回改。
+func (u *ShortlinksRepo) MarkPolicyChecked(ctx context.Context, version int64) error {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	if _, err := u.db.Exec(dbctx, "UPDATE destination_policy_settings SET checked_version=GREATEST(checked_version, $1)", version); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	return nil
+}
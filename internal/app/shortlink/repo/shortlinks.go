@@ -2,8 +2,11 @@ package repo
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"log/slog"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,43 +19,125 @@ import (
 )
 
 var ErrShortlinkNotFound = errors.New("shortlink not found")
+var ErrShortlinkExpired = errors.New("shortlink expired")
 var ErrAlreadyDisabled = errors.New("shortlink already disabled")
+var ErrAlreadyEnabled = errors.New("shortlink already enabled")
+var ErrDisabledByAdmin = errors.New("shortlink disabled by admin")
 var ErrShortlinkCodeAlreadyExists = errors.New("shortlink code already exists")
 var ErrShortlinkURLAlreadyHasDifferentCode = errors.New("shortlink url already has different code")
+var ErrShortlinkURLAlreadyHasDifferentOptions = errors.New("shortlink url already has different redirect options")
+var ErrShortlinkShared = errors.New("shortlink is shared with other users")
+var ErrClickLimitReached = errors.New("shortlink click limit reached")
+var ErrInvalidCursor = errors.New("invalid cursor")
 
 type ShortlinksMetaData struct {
-	URL       string    `json:"url"`
-	Disabled  bool      `json:"disabled"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	URL          string     `json:"url"`
+	Disabled     bool       `json:"disabled"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	RedirectType int        `json:"redirect_type"`
+	CacheControl string     `json:"cache_control,omitempty"`
+	RobotsTag    string     `json:"robots_tag,omitempty"`
+	Protected    bool       `json:"protected"` // 受密码保护时不返回 url
+
+	Metadata *LinkMetadata `json:"metadata,omitempty"` // 目标页面的标题、图标等，还没抓取或受密码保护时为空
 }
 
 type UserShortlink struct {
-	Code       string    `json:"code"`
-	URL        string    `json:"url"`
-	Disabled   bool      `json:"disabled"`
-	CreatedAt  time.Time `json:"created_at"`
-	ClickCount int64     `json:"click_count"`
+	Code           string     `json:"code"`
+	URL            string     `json:"url"`
+	Disabled       bool       `json:"disabled"`
+	DisabledReason string     `json:"disabled_reason,omitempty"` // 仅拥有者可见
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	ClickCount     int64      `json:"click_count"`
+	MaxClicks      int64      `json:"max_clicks,omitempty"`
+	Private        bool       `json:"private"`
+	Title          string     `json:"title,omitempty"`
+	Notes          string     `json:"notes,omitempty"`
+	Tags           []string   `json:"tags"`
+	FolderID       *int64     `json:"folder_id,omitempty"`
+
+	Rules         []shortlink.RedirectRule `json:"rules,omitempty"`    // 条件跳转规则，仅拥有者可见
+	Variants      []shortlink.Variant      `json:"variants,omitempty"` // A/B 分流版本，仅拥有者可见
+	StickyVariant bool                     `json:"sticky_variant,omitempty"`
+	DeepLink      *shortlink.DeepLink      `json:"deep_link,omitempty"` // 移动端 App 跳转，仅拥有者可见
+	UTM           *shortlink.UTM           `json:"utm,omitempty"`
+	ForwardQuery  bool                     `json:"forward_query,omitempty"`
+	ForwardPath   bool                     `json:"forward_path,omitempty"`
+	Domain        string                   `json:"domain,omitempty"`     // 自定义域名，空表示默认域名
+	DomainKey     string                   `json:"domain_key,omitempty"` // 在自定义域名上的访问路径
+	Preview       bool                     `json:"preview,omitempty"`
+	PreviewForced bool                     `json:"preview_forced,omitempty"` // 管理员强制开启的预览页
+
+	FallbackURL     string     `json:"fallback_url,omitempty"`
+	DestinationDown bool       `json:"destination_down,omitempty"` // 健康检查判定默认目标故障
+	HealthCheckedAt *time.Time `json:"health_checked_at,omitempty"`
+
+	Metadata *LinkMetadata `json:"metadata,omitempty"` // 目标页面的标题、图标等，还没抓取时为空
+}
+
+// UserLinkSort 是用户短链列表的排序字段
+type UserLinkSort string
+
+const (
+	SortByCreatedAt UserLinkSort = "created_at"
+	SortByClicks    UserLinkSort = "clicks"
+)
+
+// UserLinkFilter 是用户短链列表的筛选、排序与分页条件，零值字段表示不按该条件过滤。
+type UserLinkFilter struct {
+	Query         string // 在 url、code 与目标页面标题中模糊搜索
+	Tag           string
+	FolderID      int64
+	Disabled      *bool
+	Broken        *bool // 按健康检查结果（destination_down）过滤
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          UserLinkSort // 默认按创建时间
+	Asc           bool         // 默认倒序
+	Cursor        string       // 上一页返回的 next_cursor
+	Limit         int
+}
+
+// UserLinkPage 是一页用户短链，NextCursor 为空表示没有下一页
+type UserLinkPage struct {
+	Items      []UserShortlink `json:"items"`
+	NextCursor string          `json:"next_cursor,omitempty"`
 }
 
+// loadCodesSQL 查出布隆过滤器需要的全部键：短码，以及自定义域名上短链的别名
+const loadCodesSQL = `SELECT code FROM shortlinks WHERE code IS NOT NULL
+	UNION ALL
+	SELECT 'd' || domain_id || '/' || domain_key FROM shortlinks WHERE domain_id IS NOT NULL AND domain_key IS NOT NULL`
+
 type ShortlinksRepo struct {
-	db    *pgxpool.Pool
-	cache *cache.ShortlinkCache
-	bloom *cache.BloomFilter
+	db      *pgxpool.Pool
+	cache   *cache.ShortlinkCache
+	bloom   *cache.BloomFilter
+	domains domainHosts
+	policy  policySnapshot
+	codes   shortlink.CodeGenerator
 }
 
-func NewShortlinksRepo(db *pgxpool.Pool, cache *cache.ShortlinkCache, bloom *cache.BloomFilter) *ShortlinksRepo {
+// NewShortlinksRepo 创建仓库；codes 为 nil 时用默认参数的 sqids 生成短码
+func NewShortlinksRepo(db *pgxpool.Pool, cache *cache.ShortlinkCache, bloom *cache.BloomFilter, codes shortlink.CodeGenerator) *ShortlinksRepo {
+	if codes == nil {
+		codes = defaultCodeGenerator
+	}
 	repo := &ShortlinksRepo{
 		db:    db,
 		cache: cache,
 		bloom: bloom,
+		codes: codes,
 	}
 	// 初始化布隆过滤器
 	if bloom != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		rows, err := db.Query(ctx, "SELECT code FROM shortlinks WHERE code IS NOT NULL")
+		rows, err := db.Query(ctx, loadCodesSQL)
 		if err != nil {
 			slog.Error("bloom filter: load codes failed", "err", err)
 			return repo
@@ -76,128 +161,266 @@ func NewShortlinksRepo(db *pgxpool.Pool, cache *cache.ShortlinkCache, bloom *cac
 /*
 将用户的长连接，生成短码并保存到数据库
 传入http请求的上下文c.Req.Context()
+
+同一 url 会复用同一行（见 reusableLink）：
+- 设置了过期时间、密码等无法合并选项的创建不复用，各自一行
+- 登录用户复用自己的普通行或匿名创建的共享行，都没有时插入自己的独立行（见 createShared）
+- 跳转选项无法合并，与已有行不一致时返回 ErrShortlinkURLAlreadyHasDifferentOptions
+
+meta 为 createdBy 对这条短链的整理信息，在同一事务内写入（匿名创建时忽略），文件夹归属由调用方保证。
 */
-func (s *ShortlinksRepo) Create(ctx context.Context, url string, createdBy *int64) (string, error) {
+func (s *ShortlinksRepo) Create(ctx context.Context, link shortlink.Shortlink, meta LinkMeta, createdBy *int64) (shortlink.Shortlink, error) {
 	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 	//开启事务
 	tx, err := s.db.Begin(dbctx)
 	if err != nil {
 		slog.Error(err.Error())
-		return "", err
+		return shortlink.Shortlink{}, err
 	}
 	defer tx.Rollback(dbctx) //事务提交成功后 rollback 会无效/返回错误，可忽略
 
+	got, err := createShared(dbctx, tx, s.codes, link, createdBy)
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	if err := setCreatedLinkMeta(dbctx, tx, createdBy, got.Code, meta); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+
+	metrics.ShortlinkCreated.Inc()
+	if s.bloom != nil && got.Code != "" {
+		s.bloom.Add(got.Code)
+	}
+
+	// 写缓存/覆盖负缓存：创建成功后立刻写入，避免此前命中 "__nil__" 导致短码暂时不可用。
+	if s.cache != nil && got.Code != "" {
+		cacheCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_ = s.cache.Set(cacheCtx, got)
+	}
+
+	return got, nil
+}
+
+// reusableLink 判断创建请求能否按 url 复用可复用的共享行：只有没设任何无法合并的选项才行。
+//
+// 设计原因：
+// - 过期时间：复用就得合并，别人不设过期会让活动链接永不过期、设得更晚会把它延长，过期后再缩短同一 url 还会让旧短码复活
+// - 密码、点击上限、条件跳转、A/B 分流、App 跳转、UTM/参数/路径透传、预览页、备用地址：复用会把这些加到别人的链接上，反过来先设的人也会让同一 url 的其它创建全部冲突
+// - 这些创建各占一行，同一 url 的普通创建照常共用一个短码
+func reusableLink(link shortlink.Shortlink) bool {
+	return link.ExpiresAt == nil && link.PasswordHash == "" && link.MaxClicks == 0 &&
+		len(link.Rules) == 0 && len(link.Variants) == 0 && !link.StickyVariant && link.DeepLink == nil &&
+		link.UTM == nil && !link.ForwardQuery && !link.ForwardPath && !link.Preview && link.FallbackURL == ""
+}
+
+// createShared 在 tx 内按 url 插入或复用共享行，并生成缺失的短码。
+// 不能复用（见 reusableLink）时插入一条不参与去重的独立行。
+// 不提交事务，也不写布隆过滤器/缓存，由调用方在提交后处理。
+//
+// 设计原因（登录用户）：
+// - 可复用的共享行只由匿名创建插入：登录用户能修改自己创建的行，这样的行不能再按 url 发给别人
+// - 自己已有同 url 的普通行时直接返回它；否则已有匿名创建的共享行时加入它（同一个短码），但不能修改，见 lockEditable
+// - 都没有时插入自己的独立行，之后可以修改
+func createShared(dbctx context.Context, tx pgx.Tx, codes shortlink.CodeGenerator, link shortlink.Shortlink, createdBy *int64) (shortlink.Shortlink, error) {
+	if !reusableLink(link) {
+		return createOwnRow(dbctx, tx, codes, link, nil, createdBy)
+	}
+	if createdBy != nil {
+		got, ok, err := joinShared(dbctx, tx, link, *createdBy)
+		if err != nil || ok {
+			return got, err
+		}
+		return createOwnRow(dbctx, tx, codes, link, nil, createdBy)
+	}
+
 	//插入 url并获取id
 	var id int64
-	var code string
+	var got shortlink.Shortlink
 
 	if err := tx.
-		QueryRow(dbctx, "INSERT INTO shortlinks (url,disabled) VALUES ($1,$2) ON CONFLICT (url) DO UPDATE SET url=EXCLUDED.url RETURNING id, COALESCE(code,'')", url, false).
-		Scan(&id, &code); err != nil {
+		QueryRow(dbctx, `INSERT INTO shortlinks (url,disabled,expires_at,redirect_type,cache_control,robots_tag,password_hash,max_clicks,rules,variants,sticky_variant,deep_link,utm,forward_query,forward_path,preview,fallback_url)
+			VALUES ($1,false,$2,$3,NULLIF($4,''),NULLIF($5,''),NULLIF($6,''),NULLIF($7,0),$8,$9,$10,$11,$12,$13,$14,$15,NULLIF($16,''))
+			ON CONFLICT (url) WHERE owner_id IS NULL AND reusable DO UPDATE SET url=EXCLUDED.url
+			RETURNING id, `+linkColumns,
+			link.URL, link.ExpiresAt, strconv.Itoa(link.RedirectStatus()), link.CacheControl, link.RobotsTag, link.PasswordHash, link.MaxClicks, jsonListArg(link.Rules), jsonListArg(link.Variants), link.StickyVariant, link.DeepLink, link.UTM, link.ForwardQuery, link.ForwardPath, link.Preview, link.FallbackURL).
+		Scan(append([]any{&id}, linkDest(&got)...)...); err != nil {
 		slog.Error(err.Error())
-		return "", err
+		return shortlink.Shortlink{}, err
+	}
+	if redirectOptionsConflict(got, link) {
+		return shortlink.Shortlink{}, ErrShortlinkURLAlreadyHasDifferentOptions
 	}
 
-	if code == "" {
-		newCode, err := shortlink.SqidsEncode(uint64(id))
-		if err != nil {
-			slog.Error(err.Error())
-			return "", err
-		}
-
+	if got.Code == "" {
 		// Only set code when missing; if another transaction already set it, fall back to SELECT.
-		if err := tx.
-			QueryRow(dbctx, "UPDATE shortlinks SET code=$1 WHERE id=$2 AND (code IS NULL OR code='') RETURNING code", newCode, id).
-			Scan(&code); err != nil {
+		if err := assignCode(dbctx, tx, codes, id, func(tx pgx.Tx, code string) error {
+			return tx.QueryRow(dbctx, "UPDATE shortlinks SET code=$1 WHERE id=$2 AND (code IS NULL OR code='') RETURNING code", code, id).Scan(&got.Code)
+		}); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				if err := tx.QueryRow(dbctx, "SELECT code FROM shortlinks WHERE id=$1", id).Scan(&code); err != nil {
+				if err := tx.QueryRow(dbctx, "SELECT code FROM shortlinks WHERE id=$1", id).Scan(&got.Code); err != nil {
 					slog.Error(err.Error())
-					return "", err
+					return shortlink.Shortlink{}, err
 				}
 			} else {
 				slog.Error(err.Error())
-				return "", err
+				return shortlink.Shortlink{}, err
 			}
 		}
 	}
+	return got, nil
+}
 
-	if createdBy != nil {
-		_, err := tx.Exec(dbctx, "INSERT INTO user_shortlinks (user_id,shortlink_id) VALUES ($1,$2) ON CONFLICT DO NOTHING", *createdBy, id)
-		if err != nil {
+// joinShared 为登录用户的普通创建找一条 url 相同、可以直接返回的已有行并记入其名下：
+// 优先是 userID 自己创建的普通独立行（重复提交、重复导入幂等），其次是匿名创建的可复用共享行。
+// 没有这样的行，或跳转选项、自定义短码（link.Code 非空时）对不上时返回 ok=false，由调用方插入独立行。
+func joinShared(dbctx context.Context, tx pgx.Tx, link shortlink.Shortlink, userID int64) (shortlink.Shortlink, bool, error) {
+	rows, err := tx.Query(dbctx, `SELECT id, reusable, `+linkColumns+` FROM shortlinks
+		WHERE url=$1 AND owner_id IS NULL AND code IS NOT NULL AND (reusable OR created_by=$2) AND ($3='' OR code=$3)
+		ORDER BY reusable, id`, link.URL, userID, link.Code)
+	if err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, false, err
+	}
+	var id int64
+	var got shortlink.Shortlink
+	found := false
+	for rows.Next() {
+		var candID int64
+		var reusable bool
+		var cand shortlink.Shortlink
+		if err := rows.Scan(append([]any{&candID, &reusable}, linkDest(&cand)...)...); err != nil {
+			rows.Close()
 			slog.Error(err.Error())
-			return "", err
+			return shortlink.Shortlink{}, false, err
+		}
+		// 自己带选项的行（密码、过期等）不能给普通创建复用
+		if (!reusable && !reusableLink(cand)) || redirectOptionsConflict(cand, link) {
+			continue
 		}
+		id, got, found = candID, cand, true
+		break
 	}
-
-	if err := tx.Commit(dbctx); err != nil {
+	rows.Close()
+	if err := rows.Err(); err != nil {
 		slog.Error(err.Error())
-		return "", err
+		return shortlink.Shortlink{}, false, err
 	}
-
-	metrics.ShortlinkCreated.Inc()
-	if s.bloom != nil && code != "" {
-		s.bloom.Add(code)
+	if !found {
+		return shortlink.Shortlink{}, false, nil
 	}
-
-	// 写缓存/覆盖负缓存：创建成功后立刻写入，避免此前命中 "__nil__" 导致短码暂时不可用。
-	if s.cache != nil && code != "" {
-		cacheCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
-		defer cancel()
-		_ = s.cache.Set(cacheCtx, code, url)
+	if _, err := tx.Exec(dbctx, "INSERT INTO user_shortlinks (user_id,shortlink_id) VALUES ($1,$2) ON CONFLICT DO NOTHING", userID, id); err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, false, err
 	}
-
-	return code, nil
+	return got, true, nil
 }
 
-// CreateWithCustomCode 创建短链，并尝试使用用户自定义 code。
+// CreateWithCustomCode 创建短链，并尝试使用用户自定义 code（link.Code）。
 //
 // 行为约定：
 // - code 已被占用：返回 ErrShortlinkCodeAlreadyExists
 // - url 已存在且 code 不同：返回 ErrShortlinkURLAlreadyHasDifferentCode
 // - url 已存在且 code 为空：会尝试把 code 更新为自定义 code
 // - url 已存在且 code 相同：幂等返回该 code
-func (s *ShortlinksRepo) CreateWithCustomCode(ctx context.Context, url string, code string, createdBy *int64) (string, error) {
+// - 设置了过期时间、密码等无法合并的选项：不复用已有行，直接以自定义 code 插入独立行（规则同 Create）
+// - 登录用户：只返回短码相同的自己的普通行或匿名共享行，否则以自定义 code 插入独立行，上面的 url 冲突不适用
+// - url 已存在时跳转选项需一致（规则同 Create）
+// - meta 在同一事务内写入（规则同 Create）
+func (s *ShortlinksRepo) CreateWithCustomCode(ctx context.Context, link shortlink.Shortlink, meta LinkMeta, createdBy *int64) (shortlink.Shortlink, error) {
 	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
 	tx, err := s.db.Begin(dbctx)
 	if err != nil {
 		slog.Error(err.Error())
-		return "", err
+		return shortlink.Shortlink{}, err
 	}
 	defer tx.Rollback(dbctx)
 
+	got, err := createSharedWithCode(dbctx, tx, link, createdBy)
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	if err := setCreatedLinkMeta(dbctx, tx, createdBy, got.Code, meta); err != nil {
+		return shortlink.Shortlink{}, err
+	}
+
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+	// 添加到布隆过滤器
+	if s.bloom != nil && got.Code != "" {
+		s.bloom.Add(got.Code)
+	}
+
+	// 写缓存/覆盖负缓存：自定义短码创建成功后立刻写入。
+	if s.cache != nil && got.Code != "" {
+		cacheCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_ = s.cache.Set(cacheCtx, got)
+	}
+
+	return got, nil
+}
+
+// createSharedWithCode 是 CreateWithCustomCode 的事务内部分，规则同 CreateWithCustomCode。
+func createSharedWithCode(dbctx context.Context, tx pgx.Tx, link shortlink.Shortlink, createdBy *int64) (shortlink.Shortlink, error) {
+	if !reusableLink(link) {
+		// 自定义 code 已经给出，不会用到生成器
+		return createOwnRow(dbctx, tx, nil, link, nil, createdBy)
+	}
+	// 登录用户同 createShared：只返回短码相同的已有行，否则以自定义 code 插入独立行
+	if createdBy != nil {
+		got, ok, err := joinShared(dbctx, tx, link, *createdBy)
+		if err != nil || ok {
+			return got, err
+		}
+		return createOwnRow(dbctx, tx, nil, link, nil, createdBy)
+	}
 	// 1) 尝试直接插入（url/codel 都有唯一约束）
 	var id int64
-	var gotCode string
-	err = tx.QueryRow(dbctx,
-		"INSERT INTO shortlinks (url, code, disabled) VALUES ($1, $2, false) ON CONFLICT (url) DO NOTHING RETURNING id, code",
-		url, code,
-	).Scan(&id, &gotCode)
+	var got shortlink.Shortlink
+	err := tx.QueryRow(dbctx,
+		`INSERT INTO shortlinks (url, code, disabled, expires_at, redirect_type, cache_control, robots_tag, password_hash, max_clicks, rules, variants, sticky_variant, deep_link, utm, forward_query, forward_path, preview, fallback_url)
+		VALUES ($1, $2, false, $3, $4, NULLIF($5,''), NULLIF($6,''), NULLIF($7,''), NULLIF($8,0), $9, $10, $11, $12, $13, $14, $15, $16, NULLIF($17,''))
+		ON CONFLICT (url) WHERE owner_id IS NULL AND reusable DO NOTHING RETURNING id, `+linkColumns,
+		link.URL, link.Code, link.ExpiresAt, strconv.Itoa(link.RedirectStatus()), link.CacheControl, link.RobotsTag, link.PasswordHash, link.MaxClicks, jsonListArg(link.Rules), jsonListArg(link.Variants), link.StickyVariant, link.DeepLink, link.UTM, link.ForwardQuery, link.ForwardPath, link.Preview, link.FallbackURL,
+	).Scan(append([]any{&id}, linkDest(&got)...)...)
 	if err == nil {
 		// inserted new row with custom code
 	} else if errors.Is(err, pgx.ErrNoRows) {
 		// url 已存在，查出当前 code
-		if err := tx.QueryRow(dbctx, "SELECT id, COALESCE(code,'') FROM shortlinks WHERE url=$1", url).Scan(&id, &gotCode); err != nil {
+		if err := tx.QueryRow(dbctx, "SELECT id, "+linkColumns+" FROM shortlinks WHERE url=$1 AND owner_id IS NULL AND reusable", link.URL).
+			Scan(append([]any{&id}, linkDest(&got)...)...); err != nil {
 			slog.Error(err.Error())
-			return "", err
+			return shortlink.Shortlink{}, err
+		}
+		if got.Code != "" && got.Code != link.Code {
+			return shortlink.Shortlink{}, ErrShortlinkURLAlreadyHasDifferentCode
 		}
-		if gotCode != "" && gotCode != code {
-			return "", ErrShortlinkURLAlreadyHasDifferentCode
+		if redirectOptionsConflict(got, link) {
+			return shortlink.Shortlink{}, ErrShortlinkURLAlreadyHasDifferentOptions
 		}
-		if gotCode == "" {
+		if got.Code == "" {
 			// 尝试填充缺失 code（可能会与其它短码冲突）
 			if err := tx.QueryRow(dbctx,
-				"UPDATE shortlinks SET code=$1 WHERE url=$2 AND (code IS NULL OR code='') RETURNING code",
-				code, url,
-			).Scan(&gotCode); err != nil {
+				"UPDATE shortlinks SET code=$1 WHERE id=$2 AND (code IS NULL OR code='') RETURNING code",
+				link.Code, id,
+			).Scan(&got.Code); err != nil {
 				var pgErr *pgconn.PgError
 				if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-					return "", ErrShortlinkCodeAlreadyExists
+					return shortlink.Shortlink{}, ErrShortlinkCodeAlreadyExists
 				}
 				slog.Error(err.Error())
-				return "", err
+				return shortlink.Shortlink{}, err
 			}
 		}
 	} else {
@@ -205,55 +428,186 @@ func (s *ShortlinksRepo) CreateWithCustomCode(ctx context.Context, url string, c
 		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
 			// unique violation: code 冲突
 			if strings.Contains(strings.ToLower(pgErr.ConstraintName), "code") {
-				return "", ErrShortlinkCodeAlreadyExists
+				return shortlink.Shortlink{}, ErrShortlinkCodeAlreadyExists
 			}
 		}
 		slog.Error(err.Error())
-		return "", err
+		return shortlink.Shortlink{}, err
 	}
+	return got, nil
+}
 
-	if createdBy != nil {
-		_, err := tx.Exec(dbctx, "INSERT INTO user_shortlinks (user_id,shortlink_id) VALUES ($1,$2) ON CONFLICT DO NOTHING", *createdBy, id)
-		if err != nil {
-			slog.Error(err.Error())
-			return "", err
-		}
+// CreatePrivate 为 ownerID 创建一条私有短链：不与其它用户按 url 去重复用，
+// 独立的短码、统计与启用/停用/修改，互不影响。
+//
+// link.Code 非空时使用自定义短码，已被占用返回 ErrShortlinkCodeAlreadyExists；否则按 id 生成。
+// link.DomainID 非 0 时挂在该自定义域名上，DomainKey 在域名内已被占用同样返回 ErrShortlinkCodeAlreadyExists。
+// meta 在同一事务内写入（规则同 Create）。
+func (s *ShortlinksRepo) CreatePrivate(ctx context.Context, link shortlink.Shortlink, meta LinkMeta, ownerID int64) (shortlink.Shortlink, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(dbctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+	defer tx.Rollback(dbctx)
+
+	got, err := createPrivate(dbctx, tx, s.codes, link, ownerID)
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+	if err := setCreatedLinkMeta(dbctx, tx, &ownerID, got.Code, meta); err != nil {
+		return shortlink.Shortlink{}, err
 	}
 
 	if err := tx.Commit(dbctx); err != nil {
 		slog.Error(err.Error())
-		return "", err
-	}
-	// 添加到布隆过滤器
-	if s.bloom != nil && code != "" {
-		s.bloom.Add(code)
+		return shortlink.Shortlink{}, err
 	}
 
-	// 写缓存/覆盖负缓存：自定义短码创建成功后立刻写入。
-	if s.cache != nil && gotCode != "" {
+	metrics.ShortlinkCreated.Inc()
+	if s.bloom != nil {
+		s.bloom.Add(got.Code)
+		if got.DomainID != 0 {
+			s.bloom.Add(domainAlias(got.DomainID, got.DomainKey))
+		}
+	}
+	if s.cache != nil {
 		cacheCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
 		defer cancel()
-		_ = s.cache.Set(cacheCtx, gotCode, url)
+		_ = s.cache.Set(cacheCtx, got)
+		if got.DomainID != 0 {
+			_ = s.cache.SetAlias(cacheCtx, domainAlias(got.DomainID, got.DomainKey), got.Code)
+		}
+	}
+	return got, nil
+}
+
+// createPrivate 是 CreatePrivate 的事务内部分。
+func createPrivate(dbctx context.Context, tx pgx.Tx, codes shortlink.CodeGenerator, link shortlink.Shortlink, ownerID int64) (shortlink.Shortlink, error) {
+	return createOwnRow(dbctx, tx, codes, link, &ownerID, &ownerID)
+}
+
+// createOwnRow 插入一条不参与 url 去重的行：ownerID 非空是私有行，为空是不可复用的共享行（见 reusableLink）。
+// link.Code 为空时用 codes 生成；member 非空时记为创建者（created_by）并记入其名下。
+func createOwnRow(dbctx context.Context, tx pgx.Tx, codes shortlink.CodeGenerator, link shortlink.Shortlink, ownerID, member *int64) (shortlink.Shortlink, error) {
+	var id int64
+	var got shortlink.Shortlink
+	if err := tx.QueryRow(dbctx,
+		`INSERT INTO shortlinks (url, code, owner_id, created_by, reusable, disabled, expires_at, redirect_type, cache_control, robots_tag, password_hash, max_clicks, rules, variants, sticky_variant, deep_link, utm, forward_query, forward_path, domain_id, domain_key, preview, fallback_url)
+		VALUES ($1, NULLIF($2,''), $3, $21, false, false, $4, $5, NULLIF($6,''), NULLIF($7,''), NULLIF($8,''), NULLIF($9,0), $10, $11, $12, $13, $14, $15, $16, NULLIF($17,0), NULLIF($18,''), $19, NULLIF($20,''))
+		RETURNING id, `+linkColumns,
+		link.URL, link.Code, ownerID, link.ExpiresAt, strconv.Itoa(link.RedirectStatus()), link.CacheControl, link.RobotsTag, link.PasswordHash, link.MaxClicks, jsonListArg(link.Rules), jsonListArg(link.Variants), link.StickyVariant, link.DeepLink, link.UTM, link.ForwardQuery, link.ForwardPath, link.DomainID, link.DomainKey, link.Preview, link.FallbackURL, member,
+	).Scan(append([]any{&id}, linkDest(&got)...)...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return shortlink.Shortlink{}, ErrShortlinkCodeAlreadyExists
+		}
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+
+	if got.Code == "" {
+		// 自定义域名上未指定 key 时，key 与生成的短码相同
+		if err := assignCode(dbctx, tx, codes, id, func(tx pgx.Tx, code string) error {
+			if err := tx.QueryRow(dbctx, "UPDATE shortlinks SET code=$1, domain_key=CASE WHEN domain_id IS NOT NULL THEN COALESCE(domain_key,$1) END WHERE id=$2 RETURNING COALESCE(domain_key,'')", code, id).
+				Scan(&got.DomainKey); err != nil {
+				return err
+			}
+			got.Code = code
+			return nil
+		}); err != nil {
+			return shortlink.Shortlink{}, err
+		}
+	}
+
+	// 私有行仍然写 user_shortlinks：列表、归属校验、统计等沿用同一套查询
+	if member != nil {
+		if _, err := tx.Exec(dbctx, "INSERT INTO user_shortlinks (user_id,shortlink_id) VALUES ($1,$2)", *member, id); err != nil {
+			slog.Error(err.Error())
+			return shortlink.Shortlink{}, err
+		}
+	}
+	return got, nil
+}
+
+// linkColumns 是跳转热路径需要的列（也是缓存里存的内容），顺序与 linkDest 一致。
+const linkColumns = "COALESCE(code,''), url, expires_at, redirect_type::int, COALESCE(cache_control,''), COALESCE(robots_tag,''), COALESCE(password_hash,''), COALESCE(max_clicks,0), rules, variants, sticky_variant, deep_link, utm, forward_query, forward_path, COALESCE(domain_id,0), COALESCE(domain_key,''), preview, preview_forced, COALESCE(fallback_url,''), dest_down"
+
+func linkDest(link *shortlink.Shortlink) []any {
+	return []any{&link.Code, &link.URL, &link.ExpiresAt, &link.RedirectType, &link.CacheControl, &link.RobotsTag, &link.PasswordHash, &link.MaxClicks, &link.Rules, &link.Variants, &link.StickyVariant, &link.DeepLink, &link.UTM, &link.ForwardQuery, &link.ForwardPath, &link.DomainID, &link.DomainKey, &link.Preview, &link.PreviewForced, &link.FallbackURL, &link.DestDown}
+}
+
+// jsonListArg 把规则/分流版本转成 JSONB 参数：为空时写 NULL 而不是 JSON 的 null/[]
+func jsonListArg[T any](list []T) any {
+	if len(list) == 0 {
+		return nil
+	}
+	return list
+}
+
+// sameJSONList 比较两组规则/分流版本是否一致（按存储的 JSON 形式比较）
+func sameJSONList[T any](a, b []T) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(ja) == string(jb)
+}
+
+func sameDeepLink(a, b *shortlink.DeepLink) bool {
+	if a == nil || b == nil {
+		return a == b
 	}
+	return *a == *b
+}
+
+func sameUTM(a, b *shortlink.UTM) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
 
-	return gotCode, nil
+// redirectOptionsConflict 判断请求的响应选项是否与复用的共享行冲突；请求未指定的选项不算冲突。
+//
+// 能走到这里的请求都满足 reusableLink，只剩跳转状态码与响应头需要比较。
+func redirectOptionsConflict(existing, req shortlink.Shortlink) bool {
+	if req.RedirectType != 0 && req.RedirectType != existing.RedirectStatus() {
+		return true
+	}
+	if req.CacheControl != "" && req.CacheControl != existing.CacheControl {
+		return true
+	}
+	if req.RobotsTag != "" && req.RobotsTag != existing.RobotsTag {
+		return true
+	}
+	return false
 }
 
-// 用户访问短码 code,返回对应的长链接url
-func (s *ShortlinksRepo) Resolve(ctx context.Context, code string) string {
+// 用户访问短码 code,返回对应的短链
+//
+// 返回 ErrShortlinkNotFound（不存在/已禁用）或 ErrShortlinkExpired（已过期，对应 410）。
+func (s *ShortlinksRepo) Resolve(ctx context.Context, code string) (shortlink.Shortlink, error) {
 	//布隆过滤器判断
 	if s.bloom != nil && !s.bloom.MightExist(code) {
 		// 一定不存在，直接返回
-		return ""
+		return shortlink.Shortlink{}, ErrShortlinkNotFound
 	}
 
 	//先查缓存
 	if s.cache != nil {
-		if url, _ := s.cache.Get(ctx, code); url != "" {
-			if url == "__nil__" {
-				return "" //命中负缓存
+		if e, _ := s.cache.Get(ctx, code); e != nil {
+			if e.NotFound {
+				return shortlink.Shortlink{}, ErrShortlinkNotFound //命中负缓存
 			}
-			return url
+			if e.Expired || e.Link.Expired(time.Now()) {
+				return shortlink.Shortlink{}, ErrShortlinkExpired
+			}
+			return *e.Link, nil
 		}
 	}
 
@@ -263,106 +617,619 @@ func (s *ShortlinksRepo) Resolve(ctx context.Context, code string) string {
 
 	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
-	rows := s.db.QueryRow(dbctx, "SELECT url FROM shortlinks WHERE code=$1 AND disabled=false", code)
-	var url string
-	if err := rows.Scan(&url); err != nil {
+	var link shortlink.Shortlink
+	rows := s.db.QueryRow(dbctx, "SELECT "+linkColumns+" FROM shortlinks WHERE code=$1 AND disabled=false", code)
+	if err := rows.Scan(linkDest(&link)...); err != nil {
 		metrics.DBQueryDuration.WithLabelValues("resolve").Observe(time.Since(dbStart).Seconds())
 		if errors.Is(err, pgx.ErrNoRows) {
 			if s.cache != nil {
 				s.cache.SetNotFound(ctx, code)
 			}
+			return shortlink.Shortlink{}, ErrShortlinkNotFound
 		}
-		if !errors.Is(err, pgx.ErrNoRows) {
-			slog.Error(err.Error())
-		}
-		return ""
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
 	}
 	metrics.DBQueryDuration.WithLabelValues("resolve").Observe(time.Since(dbStart).Seconds())
 
+	if link.Expired(time.Now()) {
+		if s.cache != nil {
+			s.cache.SetExpired(ctx, code)
+		}
+		return shortlink.Shortlink{}, ErrShortlinkExpired
+	}
+
 	//写缓存
-	if s.cache != nil && url != "" {
-		s.cache.Set(ctx, code, url)
+	if s.cache != nil && link.URL != "" {
+		s.cache.Set(ctx, link)
 	}
-	return url
+	return link, nil
 }
 
 func (s *ShortlinksRepo) FindByCode(ctx context.Context, code string) (*ShortlinksMetaData, error) {
 	var data ShortlinksMetaData
+	var meta metadataRow
 	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
 	if err := s.db.
-		QueryRow(dbctx, "SELECT url,disabled,created_at,updated_at FROM shortlinks WHERE code=$1", code).
-		Scan(&data.URL, &data.Disabled, &data.CreatedAt, &data.UpdatedAt); err != nil {
+		QueryRow(dbctx, `SELECT s.url,s.disabled,s.created_at,s.updated_at,s.expires_at,s.redirect_type::int,COALESCE(s.cache_control,''),COALESCE(s.robots_tag,''),s.password_hash IS NOT NULL,`+metadataColumns+`
+			FROM shortlinks s LEFT JOIN link_metadata m ON m.shortlink_id=s.id AND m.url=s.url WHERE s.code=$1`, code).
+		Scan(append([]any{&data.URL, &data.Disabled, &data.CreatedAt, &data.UpdatedAt, &data.ExpiresAt, &data.RedirectType, &data.CacheControl, &data.RobotsTag, &data.Protected}, meta.dest()...)...); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrShortlinkNotFound
 		}
 		slog.Error(err.Error())
 		return nil, err
 	}
+	// 元数据接口是公开的，不能绕过密码拿到目标地址（页面标题等也会透露目标）
+	if data.Protected {
+		data.URL = ""
+	} else {
+		data.Metadata = meta.result()
+	}
 	return &data, nil
 }
 
-func (s *ShortlinksRepo) DisableByCode(ctx context.Context, code string) error {
-	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-	defer cancel()
+// exhaustedReason 是达到点击上限后自动停用时记录的原因
+const exhaustedReason = "max clicks reached"
 
-	// Try to disable when currently enabled.
-	var ok int
-	err := s.db.QueryRow(dbctx, "UPDATE shortlinks SET disabled=true, updated_at=now() WHERE code=$1 AND disabled=false RETURNING 1", code).Scan(&ok)
-	if err == nil {
-		if s.cache != nil {
-			s.cache.Delete(ctx, code)
+// TakeClick 在跳转前为有点击上限的短链占用一次名额，名额用完返回 ErrClickLimitReached。
+//
+// 占用最后一个名额的请求负责把短链停用（落库并失效缓存），之后的访问不会再走到这里。
+// 没有 Redis（cache 为 nil）时退化为按 DB 中已落库的 click_count 判断，并发下不保证严格。
+func (s *ShortlinksRepo) TakeClick(ctx context.Context, link shortlink.Shortlink) error {
+	if link.MaxClicks <= 0 {
+		return nil
+	}
+	if s.cache == nil {
+		n, err := s.clickCount(ctx, link.Code)
+		if err != nil {
+			return err
+		}
+		if n >= link.MaxClicks {
+			return ErrClickLimitReached
 		}
 		return nil
 	}
-	if !errors.Is(err, pgx.ErrNoRows) {
+
+	n, ok, err := s.cache.TakeClick(ctx, link.Code, link.MaxClicks, func() (int64, error) {
+		return s.clickCount(ctx, link.Code)
+	})
+	if err != nil {
+		slog.Error("take click failed", "code", link.Code, "err", err)
+		return err
+	}
+	if !ok {
+		return ErrClickLimitReached
+	}
+	if n == link.MaxClicks {
+		s.disableExhausted(ctx, link.Code)
+	}
+	return nil
+}
+
+// clickCount 读取已落库的点击数（异步统计，可能滞后）
+func (s *ShortlinksRepo) clickCount(ctx context.Context, code string) (int64, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	var n int64
+	if err := s.db.QueryRow(dbctx, "SELECT COALESCE(click_count,0) FROM shortlinks WHERE code=$1", code).Scan(&n); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrShortlinkNotFound
+		}
+		slog.Error(err.Error())
+		return 0, err
+	}
+	return n, nil
+}
+
+// disableExhausted 名额用完后停用短链，并记录一条系统发起的状态变更。
+// 失败只记日志：Redis 计数仍会拒绝后续访问。
+func (s *ShortlinksRepo) disableExhausted(ctx context.Context, code string) {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+	if _, err := s.db.Exec(dbctx, `WITH d AS (
+			UPDATE shortlinks SET disabled=true, disabled_reason=$2, updated_at=now()
+			WHERE code=$1 AND disabled=false RETURNING id
+		)
+		INSERT INTO shortlink_state_changes (shortlink_id, disabled, reason)
+		SELECT id, true, $2 FROM d`, code, exhaustedReason); err != nil {
+		slog.Error("disable exhausted shortlink failed", "code", code, "err", err)
+		return
+	}
+	if s.cache != nil {
+		s.cache.Delete(ctx, code)
+	}
+}
+
+// StateChange 描述一次启用/停用操作：谁发起、为什么。
+type StateChange struct {
+	ActorID int64
+	Admin   bool // 管理员操作：可恢复任何停用；其停用不能被拥有者恢复
+	Reason  string
+}
+
+// DisableByCode 停用短链，已停用时返回 ErrAlreadyDisabled。
+func (s *ShortlinksRepo) DisableByCode(ctx context.Context, code string, change StateChange) error {
+	return s.setDisabled(ctx, code, true, change)
+}
+
+// EnableByCode 恢复短链，未停用时返回 ErrAlreadyEnabled。
+func (s *ShortlinksRepo) EnableByCode(ctx context.Context, code string, change StateChange) error {
+	return s.setDisabled(ctx, code, false, change)
+}
+
+// setDisabled 在一个事务里切换 disabled 状态并写入变更记录。
+//
+// 行为约定：
+// - code 不存在：ErrShortlinkNotFound
+// - 状态未变化：ErrAlreadyDisabled / ErrAlreadyEnabled
+// - 拥有者恢复管理员停用的短链：ErrDisabledByAdmin
+// - 拥有者操作不是自己创建的独立行：ErrShortlinkShared（规则同 lockEditable，停用会影响其他拿到短码的人）
+// - 恢复因点击上限而停用的短链：ErrClickLimitReached
+//
+// 设计原因（缓存与布隆过滤器）：
+// - 提交后删除 L1/Redis 缓存；停用后 Resolve 回源查不到（disabled=false 条件）会写负缓存
+// - 恢复时删除的是停用期间留下的负缓存，否则最长要等 emptyTTL 才能恢复跳转
+// - 布隆过滤器不支持删除，停用时保留；恢复时重新 Add，兜底启动时未加载到的情况
+func (s *ShortlinksRepo) setDisabled(ctx context.Context, code string, disabled bool, change StateChange) error {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := s.db.Begin(dbctx)
+	if err != nil {
 		slog.Error(err.Error())
 		return err
 	}
+	defer tx.Rollback(dbctx)
 
-	// No rows updated: either not found, or already disabled.
-	var disabled bool
-	if err := s.db.QueryRow(dbctx, "SELECT disabled FROM shortlinks WHERE code=$1", code).Scan(&disabled); err != nil {
+	var id int64
+	var cur, byAdmin, reusable bool
+	var curReason string
+	var createdBy *int64
+	if err := tx.QueryRow(dbctx, "SELECT id, disabled, disabled_by_admin, COALESCE(disabled_reason,''), reusable, created_by FROM shortlinks WHERE code=$1 FOR UPDATE", code).
+		Scan(&id, &cur, &byAdmin, &curReason, &reusable, &createdBy); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return ErrShortlinkNotFound
 		}
 		slog.Error(err.Error())
 		return err
 	}
-	if disabled {
-		return ErrAlreadyDisabled
+	if cur == disabled {
+		if disabled {
+			return ErrAlreadyDisabled
+		}
+		return ErrAlreadyEnabled
+	}
+	// 点击名额用完而停用的短链恢复后也会立刻被计数拒绝，直接告诉调用方
+	if !disabled && curReason == exhaustedReason {
+		return ErrClickLimitReached
+	}
+	if !change.Admin {
+		if !disabled && byAdmin {
+			return ErrDisabledByAdmin
+		}
+		if reusable || createdBy == nil || *createdBy != change.ActorID {
+			return ErrShortlinkShared
+		}
 	}
 
-	// Should not happen; the row exists and is enabled, but UPDATE matched no rows.
-	return errors.New("shortlink disable failed")
+	if _, err := tx.Exec(dbctx, `UPDATE shortlinks
+		SET disabled=$2,
+			disabled_reason=CASE WHEN $2 THEN NULLIF($3,'') END,
+			disabled_by_admin=($2 AND $4),
+			updated_at=now()
+		WHERE id=$1`, id, disabled, change.Reason, change.Admin); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if _, err := tx.Exec(dbctx, `INSERT INTO shortlink_state_changes (shortlink_id, disabled, reason, actor_id, by_admin)
+		VALUES ($1, $2, NULLIF($3,''), NULLIF($4,0), $5)`, id, disabled, change.Reason, change.ActorID, change.Admin); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+
+	if s.cache != nil {
+		s.cache.Delete(ctx, code)
+	}
+	if !disabled && s.bloom != nil {
+		s.bloom.Add(code)
+	}
+	return nil
 }
 
-func (u *ShortlinksRepo) ListByUserID(ctx context.Context, userID int64, limit int) ([]UserShortlink, error) {
+// UpdateURL 修改短码指向的目标 URL，并失效 L1/Redis 缓存。
+//
+// 行为约定：
+// - code 不存在：返回 ErrShortlinkNotFound
+// - 不是 userID 自己创建的独立行（按 url 去重的共享行可能已发给了别人）：返回 ErrShortlinkShared，见 lockEditable
+// - 改过目标的行不再参与 url 去重（reusable=false）：新 url 已有共享行也不冲突，之后同一 url 的创建也不会复用到这一行
+// - 健康检查状态清零：旧目标的故障不代表新目标，等下一轮检查重新判断
+func (u *ShortlinksRepo) UpdateURL(ctx context.Context, userID int64, code string, url string) (shortlink.Shortlink, error) {
 	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
 
-	rows, err := u.db.Query(dbctx, "SELECT s.code,s.url,s.disabled,s.click_count,us.created_at FROM user_shortlinks us JOIN shortlinks s ON s.id=us.shortlink_id WHERE us.user_id=$1 ORDER BY us.created_at DESC LIMIT $2", userID, limit)
+	tx, err := u.db.Begin(dbctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+	defer tx.Rollback(dbctx)
+
+	id, err := lockEditable(dbctx, tx, code, userID)
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+
+	var got shortlink.Shortlink
+	if err := tx.QueryRow(dbctx, "UPDATE shortlinks SET url=$1, reusable=false, dest_down=false, health_failures=0, updated_at=now() WHERE id=$2 RETURNING "+linkColumns, url, id).
+		Scan(linkDest(&got)...); err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+
+	// 提交后再删缓存：下一次 Resolve 回源拿到新 url
+	if u.cache != nil {
+		u.cache.Delete(ctx, code)
+	}
+	return got, nil
+}
+
+// lockEditable 锁住 code 对应的行并确认 userID 可以修改它，返回行 id。
+//
+// 返回 ErrShortlinkNotFound 或 ErrShortlinkShared。
+//
+// 设计原因：
+// - user_shortlinks 只表示“在我的列表里”，加入匿名共享行的用户也有；按它判断拥有者会让后来者改掉别人拿到的短码
+// - 只有 created_by 是自己、且不参与 url 去重（reusable=false）的行才能修改：私有行，或登录后创建的独立行
+// - 可复用的共享行可能已经通过去重发给了任何人，谁都不能修改
+func lockEditable(dbctx context.Context, tx pgx.Tx, code string, userID int64) (int64, error) {
+	// 锁住该行，避免与并发的创建/修改交错
+	var id int64
+	var reusable bool
+	var createdBy *int64
+	if err := tx.QueryRow(dbctx, "SELECT id, reusable, created_by FROM shortlinks WHERE code=$1 FOR UPDATE", code).Scan(&id, &reusable, &createdBy); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return 0, ErrShortlinkNotFound
+		}
+		slog.Error(err.Error())
+		return 0, err
+	}
+	if reusable || createdBy == nil || *createdBy != userID {
+		return 0, ErrShortlinkShared
+	}
+	return id, nil
+}
+
+// UpdateRules 替换短码的条件跳转规则（rules 为空即清除），并失效缓存。
+//
+// 与 UpdateURL 相同：不是 userID 自己创建的独立行返回 ErrShortlinkShared。
+func (u *ShortlinksRepo) UpdateRules(ctx context.Context, userID int64, code string, rules []shortlink.RedirectRule) (shortlink.Shortlink, error) {
+	return u.updateEditable(ctx, userID, code, "rules=$1", jsonListArg(rules))
+}
+
+// UpdateVariants 替换短码的 A/B 分流版本（variants 为空即关闭分流），并失效缓存。
+//
+// 与 UpdateURL 相同：不是 userID 自己创建的独立行返回 ErrShortlinkShared。
+func (u *ShortlinksRepo) UpdateVariants(ctx context.Context, userID int64, code string, variants []shortlink.Variant, sticky bool) (shortlink.Shortlink, error) {
+	return u.updateEditable(ctx, userID, code, "variants=$1, sticky_variant=$2", jsonListArg(variants), sticky)
+}
+
+// UpdateDeepLink 替换短码的 App 跳转配置（nil 即关闭），并失效缓存。
+//
+// 与 UpdateURL 相同：不是 userID 自己创建的独立行返回 ErrShortlinkShared。
+func (u *ShortlinksRepo) UpdateDeepLink(ctx context.Context, userID int64, code string, deepLink *shortlink.DeepLink) (shortlink.Shortlink, error) {
+	return u.updateEditable(ctx, userID, code, "deep_link=$1", deepLink)
+}
+
+// UpdateUTM 替换短码的 UTM 参数（nil 即清除）与查询参数透传开关，并失效缓存。
+//
+// 与 UpdateURL 相同：不是 userID 自己创建的独立行返回 ErrShortlinkShared。
+func (u *ShortlinksRepo) UpdateUTM(ctx context.Context, userID int64, code string, utm *shortlink.UTM, forwardQuery bool) (shortlink.Shortlink, error) {
+	return u.updateEditable(ctx, userID, code, "utm=$1, forward_query=$2", utm, forwardQuery)
+}
+
+// UpdateForwardPath 修改短码的路径透传开关，并失效缓存。
+//
+// 与 UpdateURL 相同：不是 userID 自己创建的独立行返回 ErrShortlinkShared。
+func (u *ShortlinksRepo) UpdateForwardPath(ctx context.Context, userID int64, code string, forwardPath bool) (shortlink.Shortlink, error) {
+	return u.updateEditable(ctx, userID, code, "forward_path=$1", forwardPath)
+}
+
+// UpdatePreview 修改短码的预览页开关（拥有者设置的那一个），并失效缓存。
+//
+// 与 UpdateURL 相同：不是 userID 自己创建的独立行返回 ErrShortlinkShared。
+func (u *ShortlinksRepo) UpdatePreview(ctx context.Context, userID int64, code string, preview bool) (shortlink.Shortlink, error) {
+	return u.updateEditable(ctx, userID, code, "preview=$1", preview)
+}
+
+// ForcePreview 由管理员强制开启/取消短码的预览页，并失效缓存。
+//
+// 不检查共享：可疑链接对所有访问者都应先展示预览页，与管理员停用相同。
+func (u *ShortlinksRepo) ForcePreview(ctx context.Context, code string, forced bool) error {
+	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
+	defer cancel()
+
+	tag, err := u.db.Exec(dbctx, "UPDATE shortlinks SET preview_forced=$2, updated_at=now() WHERE code=$1", code, forced)
+	if err != nil {
+		slog.Error(err.Error())
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrShortlinkNotFound
+	}
+	if u.cache != nil {
+		u.cache.Delete(ctx, code)
+	}
+	return nil
+}
+
+// updateEditable 在锁住短码行并确认 userID 可以修改（见 lockEditable）后执行 UPDATE shortlinks SET <set>，提交后失效缓存。
+//
+// set 中的参数占位符从 $1 开始，行 id 会作为最后一个参数追加。
+// 改过选项的行不再参与 url 去重（reusable=false），之后同一 url 的普通创建不会复用到带选项的行。
+func (u *ShortlinksRepo) updateEditable(ctx context.Context, userID int64, code, set string, args ...any) (shortlink.Shortlink, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	tx, err := u.db.Begin(dbctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+	defer tx.Rollback(dbctx)
+
+	id, err := lockEditable(dbctx, tx, code, userID)
+	if err != nil {
+		return shortlink.Shortlink{}, err
+	}
+
+	args = append(args, id)
+	var got shortlink.Shortlink
+	if err := tx.QueryRow(dbctx, "UPDATE shortlinks SET "+set+", reusable=false, updated_at=now() WHERE id=$"+strconv.Itoa(len(args))+" RETURNING "+linkColumns, args...).
+		Scan(linkDest(&got)...); err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return shortlink.Shortlink{}, err
+	}
+	if u.cache != nil {
+		u.cache.Delete(ctx, code)
+	}
+	return got, nil
+}
+
+// ListByUserID 按条件分页列出用户名下的短链。
+//
+// 分页用键集游标 (排序值, shortlink_id)，而不是 OFFSET：
+// - 深翻页不需要扫描并丢弃前面的行
+// - 翻页过程中有新建短链也不会导致重复/遗漏（按点击数排序时点击数本身会变化，只能尽量保证）
+func (u *ShortlinksRepo) ListByUserID(ctx context.Context, userID int64, filter UserLinkFilter) (*UserLinkPage, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	sortExpr := "us.created_at"
+	if filter.Sort == SortByClicks {
+		sortExpr = "COALESCE(s.click_count,0)"
+	}
+	cmp, dir := "<", "DESC"
+	if filter.Asc {
+		cmp, dir = ">", "ASC"
+	}
+
+	args := []any{userID}
+	where := "us.user_id=$1"
+	arg := func(v any) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if filter.Query != "" {
+		p := "%" + escapeLike(filter.Query) + "%"
+		where += " AND (s.url ILIKE " + arg(p) + " OR s.code ILIKE " + arg(p) + " OR m.title ILIKE " + arg(p) + ")"
+	}
+	if filter.Tag != "" {
+		where += " AND " + arg(filter.Tag) + " = ANY(us.tags)"
+	}
+	if filter.FolderID != 0 {
+		where += " AND us.folder_id=" + arg(filter.FolderID)
+	}
+	if filter.Disabled != nil {
+		where += " AND s.disabled=" + arg(*filter.Disabled)
+	}
+	if filter.Broken != nil {
+		where += " AND s.dest_down=" + arg(*filter.Broken)
+	}
+	if filter.CreatedAfter != nil {
+		where += " AND us.created_at>=" + arg(*filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		where += " AND us.created_at<" + arg(*filter.CreatedBefore)
+	}
+	if filter.Cursor != "" {
+		v, id, err := decodeUserLinkCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return nil, err
+		}
+		where += " AND (" + sortExpr + ", us.shortlink_id) " + cmp + " (" + arg(v) + ", " + arg(id) + ")"
+	}
+	// 多取一条判断是否还有下一页
+	limit := arg(filter.Limit + 1)
+
+	rows, err := u.db.Query(dbctx, `SELECT us.shortlink_id,s.code,s.url,s.disabled,COALESCE(s.disabled_reason,''),COALESCE(s.click_count,0),us.created_at,s.expires_at,COALESCE(s.max_clicks,0),s.owner_id IS NOT NULL,
+			COALESCE(us.title,''),COALESCE(us.notes,''),us.tags,us.folder_id,s.rules,s.variants,s.sticky_variant,s.deep_link,s.utm,s.forward_query,s.forward_path,
+			COALESCE(d.host,''),COALESCE(s.domain_key,''),s.preview,s.preview_forced,COALESCE(s.fallback_url,''),s.dest_down,s.health_checked_at,
+			`+metadataColumns+`
+		FROM user_shortlinks us JOIN shortlinks s ON s.id=us.shortlink_id LEFT JOIN domains d ON d.id=s.domain_id LEFT JOIN link_metadata m ON m.shortlink_id=s.id AND m.url=s.url
+		WHERE `+where+`
+		ORDER BY `+sortExpr+` `+dir+`, us.shortlink_id `+dir+`
+		LIMIT `+limit, args...)
 	if err != nil {
 		slog.Error(err.Error())
 		return nil, err
 	}
 	defer rows.Close()
 
-	var result []UserShortlink
+	page := &UserLinkPage{Items: []UserShortlink{}}
+	var lastID int64
 	for rows.Next() {
+		var id int64
 		var item UserShortlink
-		if err := rows.Scan(&item.Code, &item.URL, &item.Disabled, &item.ClickCount, &item.CreatedAt); err != nil {
+		var meta metadataRow
+		if err := rows.Scan(append([]any{&id, &item.Code, &item.URL, &item.Disabled, &item.DisabledReason, &item.ClickCount, &item.CreatedAt, &item.ExpiresAt, &item.MaxClicks, &item.Private,
+			&item.Title, &item.Notes, &item.Tags, &item.FolderID, &item.Rules, &item.Variants, &item.StickyVariant, &item.DeepLink, &item.UTM, &item.ForwardQuery, &item.ForwardPath,
+			&item.Domain, &item.DomainKey, &item.Preview, &item.PreviewForced, &item.FallbackURL, &item.DestinationDown, &item.HealthCheckedAt},
+			meta.dest()...)...); err != nil {
 			slog.Error(err.Error())
 			return nil, err
 		}
-		result = append(result, item)
+		item.Metadata = meta.result()
+		if len(page.Items) == filter.Limit {
+			// 第 limit+1 条只用于判断是否有下一页
+			last := page.Items[len(page.Items)-1]
+			page.NextCursor = encodeUserLinkCursor(filter.Sort, last, lastID)
+			break
+		}
+		page.Items = append(page.Items, item)
+		lastID = id
 	}
 
 	if err := rows.Err(); err != nil {
 		slog.Error(err.Error())
 		return nil, err
 	}
-	return result, nil
+	return page, nil
+}
+
+// encodeUserLinkCursor 游标格式（base64url）：{排序字段}:{排序值}:{shortlink_id}
+// 创建时间用微秒，与 Postgres timestamptz 精度一致，避免比较时丢精度。
+func encodeUserLinkCursor(sort UserLinkSort, last UserShortlink, id int64) string {
+	v := last.CreatedAt.UnixMicro()
+	if sort == SortByClicks {
+		v = last.ClickCount
+	}
+	raw := string(userLinkSortOrDefault(sort)) + ":" + strconv.FormatInt(v, 10) + ":" + strconv.FormatInt(id, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeUserLinkCursor 解析游标，返回可直接作为 SQL 参数的排序值；排序字段与请求不一致时视为无效。
+func decodeUserLinkCursor(cursor string, sort UserLinkSort) (any, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	parts := strings.Split(string(raw), ":")
+	if len(parts) != 3 || parts[0] != string(userLinkSortOrDefault(sort)) {
+		return nil, 0, ErrInvalidCursor
+	}
+	v, err1 := strconv.ParseInt(parts[1], 10, 64)
+	id, err2 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil {
+		return nil, 0, ErrInvalidCursor
+	}
+	if sort == SortByClicks {
+		return v, id, nil
+	}
+	return time.UnixMicro(v), id, nil
+}
+
+func userLinkSortOrDefault(sort UserLinkSort) UserLinkSort {
+	if sort == "" {
+		return SortByCreatedAt
+	}
+	return sort
+}
+
+// escapeLike 转义 LIKE 模式中的通配符，用户输入的 % / _ 按字面匹配
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// PurgeExpired 物理删除在 before 之前就已过期的短链（最多 limit 条），返回被删除的短码。
+//
+// 过期短链在保留期内仍保留（Resolve 返回 410、用户列表可见），超过保留期再由 sweeper 清理；
+// 连同 user_shortlinks 关联与 click_stats 明细一起删除，避免短码被重新占用后统计串号。
+func (u *ShortlinksRepo) PurgeExpired(ctx context.Context, before time.Time, limit int) ([]string, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	tx, err := u.db.Begin(dbctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer tx.Rollback(dbctx)
+
+	rows, err := tx.Query(dbctx, `
+          SELECT id, COALESCE(code,'') FROM shortlinks
+          WHERE expires_at IS NOT NULL AND expires_at <= $1
+          ORDER BY expires_at
+          LIMIT $2
+          FOR UPDATE SKIP LOCKED
+      `, before, limit)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	var ids []int64
+	var codes []string
+	for rows.Next() {
+		var id int64
+		var code string
+		if err := rows.Scan(&id, &code); err != nil {
+			rows.Close()
+			slog.Error(err.Error())
+			return nil, err
+		}
+		ids = append(ids, id)
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	if _, err := tx.Exec(dbctx, `DELETE FROM user_shortlinks WHERE shortlink_id = ANY($1)`, ids); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	if _, err := tx.Exec(dbctx, `DELETE FROM click_stats WHERE code = ANY($1)`, codes); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	if _, err := tx.Exec(dbctx, `DELETE FROM shortlinks WHERE id = ANY($1)`, ids); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	if err := tx.Commit(dbctx); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+
+	if u.cache != nil {
+		for _, code := range codes {
+			u.cache.Delete(ctx, code)
+			u.cache.DeleteClicks(ctx, code)
+		}
+	}
+	return codes, nil
 }
 
 func (u *ShortlinksRepo) RemoveFromUserList(ctx context.Context, userID int64, code string) error {
@@ -389,6 +1256,8 @@ type ClickStats struct {
 	ClickedAt time.Time `json:"clicked_at"`
 	Referer   string    `json:"referer"`
 	UserAgent string    `json:"user_agent"`
+	Variant   string    `json:"variant,omitempty"` //A/B 分流命中的版本
+	Source    string    `json:"source,omitempty"`  //访问来源，扫码为 "qr"
 }
 
 type StatsResponse struct {
@@ -414,9 +1283,9 @@ func (u *ShortlinksRepo) ListStatsByCode(ctx context.Context, code string, limit
 	var rows pgx.Rows
 	var err error
 	if cursor == 0 {
-		rows, err = u.db.Query(dbctx, `SELECT id,clicked_at,referer,user_agent FROM click_stats WHERE code = $1 ORDER BY id DESC LIMIT $2`, code, limit)
+		rows, err = u.db.Query(dbctx, `SELECT id,clicked_at,referer,user_agent,COALESCE(variant,''),COALESCE(source,'') FROM click_stats WHERE code = $1 ORDER BY id DESC LIMIT $2`, code, limit)
 	} else {
-		rows, err = u.db.Query(dbctx, `SELECT id,clicked_at,referer,user_agent FROM click_stats WHERE code = $1 AND id<$2 ORDER BY id DESC LIMIT $3`, code, cursor, limit)
+		rows, err = u.db.Query(dbctx, `SELECT id,clicked_at,referer,user_agent,COALESCE(variant,''),COALESCE(source,'') FROM click_stats WHERE code = $1 AND id<$2 ORDER BY id DESC LIMIT $3`, code, cursor, limit)
 	}
 	if err != nil {
 		slog.Error(err.Error())
@@ -427,7 +1296,7 @@ func (u *ShortlinksRepo) ListStatsByCode(ctx context.Context, code string, limit
 	var clicks []ClickStats
 	for rows.Next() {
 		var item ClickStats
-		if err := rows.Scan(&item.ID, &item.ClickedAt, &item.Referer, &item.UserAgent); err != nil {
+		if err := rows.Scan(&item.ID, &item.ClickedAt, &item.Referer, &item.UserAgent, &item.Variant, &item.Source); err != nil {
 			slog.Error(err.Error())
 			return nil, err
 		}
@@ -451,6 +1320,43 @@ func (u *ShortlinksRepo) ListStatsByCode(ctx context.Context, code string, limit
 
 }
 
+// VariantClicks 是某个分流版本的点击数；Variant 为空表示开启分流前（或没有分流时）的点击
+type VariantClicks struct {
+	Variant string `json:"variant"`
+	Clicks  int64  `json:"clicks"`
+}
+
+// CountClicksByVariant 按版本汇总短码的点击明细，按点击数降序。
+//
+// 依据 click_stats 明细聚合，而不是 click_count：明细由异步消费者写入，会比 click_count 略有延迟。
+func (u *ShortlinksRepo) CountClicksByVariant(ctx context.Context, code string) ([]VariantClicks, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := u.db.Query(dbctx, `SELECT COALESCE(variant,''), count(*) FROM click_stats WHERE code = $1
+		GROUP BY variant ORDER BY count(*) DESC, COALESCE(variant,'')`, code)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := []VariantClicks{}
+	for rows.Next() {
+		var item VariantClicks
+		if err := rows.Scan(&item.Variant, &item.Clicks); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
+
 func (u *ShortlinksRepo) UserOwnsShortlink(ctx context.Context, userID int64, code string) (bool, error) {
 	dbctx, cancel := context.WithTimeout(ctx, 1*time.Second)
 	defer cancel()
@@ -466,8 +1372,10 @@ func (u *ShortlinksRepo) UserOwnsShortlink(ctx context.Context, userID int64, co
 }
 
 // LoadAllCodes 加载所有短码（用于初始化布隆过滤器）
+//
+// 自定义域名上的短链同时返回其别名（见 domainAlias）。
 func (u *ShortlinksRepo) LoadAllCodes(ctx context.Context) ([]string, error) {
-	rows, err := u.db.Query(ctx, "SELECT code FROM shortlinks WHERE code IS NOT NULL")
+	rows, err := u.db.Query(ctx, loadCodesSQL)
 	if err != nil {
 		return nil, err
 	}
@@ -483,3 +1391,37 @@ func (u *ShortlinksRepo) LoadAllCodes(ctx context.Context) ([]string, error) {
 	}
 	return codes, rows.Err()
 }
+
+// ScanLink 是后台扫描需要的短链字段，ID 用作翻页游标
+type ScanLink struct {
+	ID   int64
+	Link shortlink.Shortlink
+}
+
+// ListLinksForScan 按 id 升序列出 afterID 之后未停用的短链（最多 limit 条），供后台任务（策略复查、信誉复扫）分批扫描。
+func (u *ShortlinksRepo) ListLinksForScan(ctx context.Context, afterID int64, limit int) ([]ScanLink, error) {
+	dbctx, cancel := context.WithTimeout(ctx, 3*time.Second)
+	defer cancel()
+
+	rows, err := u.db.Query(dbctx, "SELECT id, "+linkColumns+" FROM shortlinks WHERE id>$1 AND disabled=false AND code IS NOT NULL ORDER BY id LIMIT $2", afterID, limit)
+	if err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []ScanLink
+	for rows.Next() {
+		var item ScanLink
+		if err := rows.Scan(append([]any{&item.ID}, linkDest(&item.Link)...)...); err != nil {
+			slog.Error(err.Error())
+			return nil, err
+		}
+		out = append(out, item)
+	}
+	if err := rows.Err(); err != nil {
+		slog.Error(err.Error())
+		return nil, err
+	}
+	return out, nil
+}
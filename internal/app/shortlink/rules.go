@@ -0,0 +1,310 @@
+package shortlink
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRules = errors.New("invalid redirect rules")
+
+// 规则引擎识别的设备类型与操作系统
+const (
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceDesktop = "desktop"
+
+	OSIOS     = "ios"
+	OSAndroid = "android"
+	OSWindows = "windows"
+	OSMacOS   = "macos"
+	OSLinux   = "linux"
+)
+
+const (
+	maxRedirectRules = 20
+	clockLayout      = "15:04"
+	defaultRuleTZ    = "UTC"
+)
+
+var (
+	ruleDevices = map[string]bool{DeviceMobile: true, DeviceTablet: true, DeviceDesktop: true}
+	ruleOS      = map[string]bool{OSIOS: true, OSAndroid: true, OSWindows: true, OSMacOS: true, OSLinux: true}
+	languageRe  = regexp.MustCompile(`^[a-z]{2,3}(-[a-z0-9]{2,8})?$`)
+	countryRe   = regexp.MustCompile(`^[A-Z]{2}$`)
+)
+
+// RedirectRule 是一条条件跳转规则：所有已设置的条件同时满足（AND）时跳到 URL，
+// 同一条件里的多个取值满足其一即可（OR）。
+//
+// 条件：
+// - Devices / OS：由 User-Agent 识别，取值见 Device* / OS* 常量
+// - Languages：访客首选语言（Accept-Language 中权重最高的一项），"de" 匹配 de、de-AT 等，"zh-tw" 只匹配 zh-TW
+// - Countries：ISO 3166-1 alpha-2 国家码，由 GeoIP 来源提供，未知国家不匹配
+// - StartAt / EndAt：日期窗口 [StartAt, EndAt)
+// - DailyFrom / DailyTo：每天的时间窗口 "HH:MM"，按 Timezone（IANA 名称，默认 UTC）计算，可跨零点
+//
+// 设计原因：
+// - 规则随短链一起缓存（ShortlinkCache 存整条 Shortlink），跳转热路径不查 DB
+// - 规则作为 JSON 存储与缓存，字段名即存储格式，所以这里带 json tag
+type RedirectRule struct {
+	Devices   []string   `json:"devices,omitempty"`
+	OS        []string   `json:"os,omitempty"`
+	Languages []string   `json:"languages,omitempty"`
+	Countries []string   `json:"countries,omitempty"`
+	StartAt   *time.Time `json:"start_at,omitempty"`
+	EndAt     *time.Time `json:"end_at,omitempty"`
+	DailyFrom string     `json:"daily_from,omitempty"`
+	DailyTo   string     `json:"daily_to,omitempty"`
+	Timezone  string     `json:"timezone,omitempty"`
+	URL       string     `json:"url"`
+}
+
+// Visitor 是规则匹配需要的访客信息，由传输层从请求中提取。
+type Visitor struct {
+	Device   string
+	OS       string
+	Language string // 首选语言，小写，例如 "de-at"
+	Country  string // 大写国家码，未知为空
+	Now      time.Time
+}
+
+// Destination 返回访客 v 应跳转的目标：第一条匹配的规则的 URL，都不匹配时为 s.URL。
+func (s Shortlink) Destination(v Visitor) string {
+	if r, ok := s.MatchRule(v); ok {
+		return r.URL
+	}
+	return s.URL
+}
+
+// MatchRule 返回第一条匹配访客 v 的规则
+func (s Shortlink) MatchRule(v Visitor) (RedirectRule, bool) {
+	for _, r := range s.Rules {
+		if r.Matches(v) {
+			return r, true
+		}
+	}
+	return RedirectRule{}, false
+}
+
+// Matches 判断规则是否匹配访客 v
+func (r RedirectRule) Matches(v Visitor) bool {
+	if len(r.Devices) > 0 && !containsString(r.Devices, v.Device) {
+		return false
+	}
+	if len(r.OS) > 0 && !containsString(r.OS, v.OS) {
+		return false
+	}
+	if len(r.Languages) > 0 && !matchLanguage(r.Languages, v.Language) {
+		return false
+	}
+	if len(r.Countries) > 0 && (v.Country == "" || !containsString(r.Countries, v.Country)) {
+		return false
+	}
+	if r.StartAt != nil && v.Now.Before(*r.StartAt) {
+		return false
+	}
+	if r.EndAt != nil && !v.Now.Before(*r.EndAt) {
+		return false
+	}
+	if r.DailyFrom != "" && !r.inDailyWindow(v.Now) {
+		return false
+	}
+	return true
+}
+
+func (r RedirectRule) inDailyWindow(now time.Time) bool {
+	tz := r.Timezone
+	if tz == "" {
+		tz = defaultRuleTZ
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return false
+	}
+	from, err1 := time.Parse(clockLayout, r.DailyFrom)
+	to, err2 := time.Parse(clockLayout, r.DailyTo)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	local := now.In(loc)
+	cur := local.Hour()*60 + local.Minute()
+	start := from.Hour()*60 + from.Minute()
+	end := to.Hour()*60 + to.Minute()
+	if start <= end {
+		return cur >= start && cur < end
+	}
+	// 跨零点，例如 22:00 ~ 06:00
+	return cur >= start || cur < end
+}
+
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
+
+func matchLanguage(rules []string, lang string) bool {
+	if lang == "" {
+		return false
+	}
+	primary := lang
+	if i := strings.IndexByte(lang, '-'); i >= 0 {
+		primary = lang[:i]
+	}
+	for _, l := range rules {
+		if l == lang || l == primary {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeRules 校验并规范化规则（设备/系统/语言小写，国家码大写）。
+//
+// 每条规则至少要有一个条件，否则它会吞掉后面所有规则，应该直接改默认 URL。
+func NormalizeRules(rules []RedirectRule) ([]RedirectRule, error) {
+	if len(rules) > maxRedirectRules {
+		return nil, ErrInvalidRules
+	}
+	out := make([]RedirectRule, 0, len(rules))
+	for _, r := range rules {
+		if err := ValidateURL(r.URL); err != nil {
+			return nil, err
+		}
+		r.Devices = normalizeList(r.Devices, strings.ToLower)
+		r.OS = normalizeList(r.OS, strings.ToLower)
+		r.Languages = normalizeList(r.Languages, strings.ToLower)
+		r.Countries = normalizeList(r.Countries, strings.ToUpper)
+		for _, d := range r.Devices {
+			if !ruleDevices[d] {
+				return nil, ErrInvalidRules
+			}
+		}
+		for _, o := range r.OS {
+			if !ruleOS[o] {
+				return nil, ErrInvalidRules
+			}
+		}
+		for _, l := range r.Languages {
+			if !languageRe.MatchString(l) {
+				return nil, ErrInvalidRules
+			}
+		}
+		for _, c := range r.Countries {
+			if !countryRe.MatchString(c) {
+				return nil, ErrInvalidRules
+			}
+		}
+		// 统一存 UTC，比较是否与已有共享行一致时不受时区写法影响
+		if r.StartAt != nil {
+			t := r.StartAt.UTC()
+			r.StartAt = &t
+		}
+		if r.EndAt != nil {
+			t := r.EndAt.UTC()
+			r.EndAt = &t
+		}
+		if r.StartAt != nil && r.EndAt != nil && !r.EndAt.After(*r.StartAt) {
+			return nil, ErrInvalidRules
+		}
+		if (r.DailyFrom == "") != (r.DailyTo == "") {
+			return nil, ErrInvalidRules
+		}
+		if r.DailyFrom != "" {
+			if _, err := time.Parse(clockLayout, r.DailyFrom); err != nil {
+				return nil, ErrInvalidRules
+			}
+			if _, err := time.Parse(clockLayout, r.DailyTo); err != nil {
+				return nil, ErrInvalidRules
+			}
+		}
+		if r.Timezone != "" {
+			if r.DailyFrom == "" {
+				return nil, ErrInvalidRules
+			}
+			if _, err := time.LoadLocation(r.Timezone); err != nil {
+				return nil, ErrInvalidRules
+			}
+		}
+		if len(r.Devices) == 0 && len(r.OS) == 0 && len(r.Languages) == 0 && len(r.Countries) == 0 &&
+			r.StartAt == nil && r.EndAt == nil && r.DailyFrom == "" {
+			return nil, ErrInvalidRules
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
+
+func normalizeList(list []string, f func(string) string) []string {
+	if len(list) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, s := range list {
+		if s = f(strings.TrimSpace(s)); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// DetectDevice 从 User-Agent 粗略识别设备类型与操作系统，识别不了的操作系统为空。
+//
+// 只覆盖规则需要的主流平台，不追求完整的 UA 解析：
+// iPadOS 13+ 默认伪装成 Mac，这类请求会被识别为 macos/desktop。
+func DetectDevice(ua string) (device, os string) {
+	l := strings.ToLower(ua)
+	switch {
+	case strings.Contains(l, "ipad"):
+		return DeviceTablet, OSIOS
+	case strings.Contains(l, "iphone") || strings.Contains(l, "ipod"):
+		return DeviceMobile, OSIOS
+	case strings.Contains(l, "android"):
+		if strings.Contains(l, "mobile") {
+			return DeviceMobile, OSAndroid
+		}
+		return DeviceTablet, OSAndroid
+	case strings.Contains(l, "windows"):
+		return DeviceDesktop, OSWindows
+	case strings.Contains(l, "macintosh") || strings.Contains(l, "mac os x"):
+		return DeviceDesktop, OSMacOS
+	case strings.Contains(l, "linux"):
+		return DeviceDesktop, OSLinux
+	}
+	if strings.Contains(l, "mobile") {
+		return DeviceMobile, ""
+	}
+	return DeviceDesktop, ""
+}
+
+// PreferredLanguage 返回 Accept-Language 中权重最高的语言（小写），"*" 与空值忽略。
+func PreferredLanguage(header string) string {
+	best, bestQ := "", -1.0
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if f, err := strconv.ParseFloat(v, 64); err == nil {
+				q = f
+			}
+		}
+		// 同权重保持原顺序
+		if q > bestQ {
+			best, bestQ = tag, q
+		}
+	}
+	if bestQ <= 0 {
+		return ""
+	}
+	return best
+}
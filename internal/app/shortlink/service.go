@@ -10,25 +10,62 @@ import (
 // 说明：
 // - Code：短码（用于拼接成最终短链 URL，例如 https://s.example.com/{code}）
 // - URL：原始长链接
+// - ExpiresAt：过期时间，nil 表示永不过期
+// - RedirectType：跳转类型（301/302/307/308），0 表示默认
+// - CacheControl / RobotsTag：跳转响应附带的 Cache-Control、X-Robots-Tag，空表示不设置
+// - PasswordHash：访问密码的 bcrypt 哈希，空表示无需密码
+// - MaxClicks：点击上限（1 即一次性链接），0 表示不限
+// - Rules：条件跳转规则，按顺序匹配，都不匹配时跳到 URL（见 Destination）
+// - Variants：A/B 分流版本，设置后默认目标改为按权重选出的版本；StickyVariant 表示同一访客固定同一版本
+// - DeepLink：移动端 App 跳转配置，nil 表示不启用
+// - UTM：跳转时合并进目标地址的 UTM 参数；ForwardQuery 表示把短链上的查询参数透传给目标（见 DecorateURL）
+// - ForwardPath：把 /{code}/ 之后的剩余路径拼到目标地址后面（见 ExpandPath）
+// - DomainID / DomainKey：挂在自定义域名上的短链，通过 https://{域名}/{DomainKey} 访问，DomainKey 只在该域名内唯一，Code 仍是全局唯一的内部短码；0 表示默认域名
+// - Preview / PreviewForced：访问时先展示预览页，由访客确认后再跳转；后者由管理员对可疑短链强制开启（见 ShowPreview）
+// - FallbackURL / DestDown：健康检查判定默认目标故障（DestDown）时改跳备用地址，空表示不切换（见 UseFallback）
 //
 // 设计原因：
 // - 领域层只关心“业务含义”，不携带 HTTP/DB 细节（例如状态码、SQL 字段、JSON tag）
 type Shortlink struct {
-	Code string
-	URL  string
+	Code          string
+	URL           string
+	ExpiresAt     *time.Time
+	RedirectType  int
+	CacheControl  string
+	RobotsTag     string
+	PasswordHash  string
+	MaxClicks     int64
+	Rules         []RedirectRule
+	Variants      []Variant
+	StickyVariant bool
+	DeepLink      *DeepLink
+	UTM           *UTM
+	ForwardQuery  bool
+	ForwardPath   bool
+	DomainID      int64
+	DomainKey     string
+	Preview       bool
+	PreviewForced bool
+	FallbackURL   string
+	DestDown      bool
+}
+
+// Expired 判断短链在 now 时刻是否已过期（过期时间点本身即视为过期）。
+func (s Shortlink) Expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !now.Before(*s.ExpiresAt)
 }
 
 // Creator 表示“创建短链”的用例能力。
 //
 // 参数约定：
+// - link：要创建的短链，URL/ExpiresAt/跳转选项由调用方填好；Code 由实现生成
 // - createdBy：可空，表示匿名创建或未接入用户体系
-// - expiresAt：可空，表示不过期（MVP 可先不实现过期逻辑）
 //
 // 设计原因：
 // - 用接口表达用例：便于你后续实现不同版本（内存版/DB版/带缓存版）
 // - 上层（HTTP）只依赖接口：减少耦合，便于测试（mock）
 type Creator interface {
-	Create(ctx context.Context, url string, createdBy *int64, expiresAt *time.Time) (Shortlink, error)
+	Create(ctx context.Context, link Shortlink, createdBy *int64) (Shortlink, error)
 }
 
 // Resolver 表示“解析短码并返回目标 URL”的用例能力。
@@ -1,30 +1,49 @@
 package shortlink
 
 import (
-	"sync"
+	"fmt"
 
 	"github.com/sqids/sqids-go"
 )
 
-var (
-	sq   *sqids.Sqids
-	once sync.Once
-)
+// DefaultSqidsAlphabet 是 sqids 模式的默认字母表（打乱顺序的 base62）
+const DefaultSqidsAlphabet = "k3G7QAe51FCsiWrNOYBUwM6XzZvdLT4j9JhyHKg2cVbxfERq0mSoI8lDpunPat"
+
+// SqidsGenerator 把数据库自增 id 编码成短码：最短，但按 id 递增，知道字母表就能遍历全部短链。
+//
+// 换字母表后可遍历性会降低，但仍能从一个短码推出相邻的短码，不适合对外公开的实例。
+type SqidsGenerator struct {
+	s *sqids.Sqids
+}
 
-func getSqids() *sqids.Sqids {
-	once.Do(func() {
-		var err error
-		sq, err = sqids.New(sqids.Options{
-			Alphabet:  "k3G7QAe51FCsiWrNOYBUwM6XzZvdLT4j9JhyHKg2cVbxfERq0mSoI8lDpunPat",
-			MinLength: 3,
-		})
-		if err != nil {
-			panic("sqids init failed: " + err.Error())
-		}
+// NewSqidsGenerator 创建 sqids 生成器；alphabet 只能含字母数字（短码的字符集），
+// blocklist 追加在 sqids 自带的屏蔽词表之后，编码结果含屏蔽词时 sqids 会换一个。
+func NewSqidsGenerator(alphabet string, minLength int, blocklist []string) (*SqidsGenerator, error) {
+	if alphabet == "" {
+		alphabet = DefaultSqidsAlphabet
+	}
+	if !alnumRe.MatchString(alphabet) {
+		return nil, fmt.Errorf("sqids alphabet must only contain letters and digits")
+	}
+	if minLength < 0 || minLength > maxCodeLength {
+		return nil, fmt.Errorf("sqids min length must be between 0 and %d", maxCodeLength)
+	}
+	s, err := sqids.New(sqids.Options{
+		Alphabet:  alphabet,
+		MinLength: uint8(minLength),
+		Blocklist: sqids.Blocklist(blocklist...),
 	})
-	return sq
+	if err != nil {
+		return nil, fmt.Errorf("sqids: %w", err)
+	}
+	return &SqidsGenerator{s: s}, nil
 }
 
-func SqidsEncode(id uint64) (string, error) {
-	return getSqids().Encode([]uint64{id})
+// Generate 编码 id；重试时把 attempt 一起编码，得到同一 id 的另一个短码
+func (g *SqidsGenerator) Generate(id uint64, attempt int) (string, error) {
+	numbers := []uint64{id}
+	if attempt > 0 {
+		numbers = append(numbers, uint64(attempt))
+	}
+	return g.s.Encode(numbers)
 }
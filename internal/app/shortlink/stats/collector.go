@@ -9,6 +9,24 @@ type ClickEvent struct {
 	IP        string    //点击者的IP
 	UserAgent string    //客户端信息（浏览器、操作系统）
 	Referer   string    //从哪个页面点击过来的
+	Variant   string    //A/B 分流命中的版本名，未分流为空
+	Source    string    //访问来源，扫码为 "qr"，直接访问为空
+}
+
+// variantArg 把版本名转成 SQL 参数：未分流写 NULL
+func (e ClickEvent) variantArg() any {
+	if e.Variant == "" {
+		return nil
+	}
+	return e.Variant
+}
+
+// sourceArg 把来源转成 SQL 参数：直接访问写 NULL
+func (e ClickEvent) sourceArg() any {
+	if e.Source == "" {
+		return nil
+	}
+	return e.Source
 }
 
 // Collector 收集器接口（方便后续换 Kafka）
@@ -79,13 +79,13 @@ func (c *Consumer) flush(batch []ClickEvent) {
 	//使用CopyFrom批量插入 click_stats
 	rows := make([][]any, len(batch))
 	for i, e := range batch {
-		rows[i] = []any{e.Code, e.ClickedAt, e.IP, e.UserAgent, e.Referer}
+		rows[i] = []any{e.Code, e.ClickedAt, e.IP, e.UserAgent, e.Referer, e.variantArg(), e.sourceArg()}
 	}
 
 	_, err = tx.CopyFrom(
 		ctx,
 		pgx.Identifier{"click_stats"},
-		[]string{"code", "clicked_at", "ip", "user_agent", "referer"},
+		[]string{"code", "clicked_at", "ip", "user_agent", "referer", "variant", "source"},
 		pgx.CopyFromRows(rows),
 	)
 	if err != nil {
@@ -109,8 +109,8 @@ func (k *KafkaConsumer) flush(batch []ClickEvent) {
 
 	for _, e := range batch {
 		if _, err := tx.Exec(ctx,
-			`INSERT INTO click_stats (code,clicked_at,ip,user_agent,referer) VALUES ($1,$2,$3,$4,$5)`,
-			e.Code, e.ClickedAt, e.IP, e.UserAgent, e.Referer); err != nil {
+			`INSERT INTO click_stats (code,clicked_at,ip,user_agent,referer,variant,source) VALUES ($1,$2,$3,$4,$5,$6,$7)`,
+			e.Code, e.ClickedAt, e.IP, e.UserAgent, e.Referer, e.variantArg(), e.sourceArg()); err != nil {
 			slog.Error("kafka consumer: insert failed", "err", err, "code", e.Code)
 			continue
 		}
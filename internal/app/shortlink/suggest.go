@@ -0,0 +1,128 @@
+package shortlink
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxSuggestionCandidates 限制候选短码的数量，一次查询即可判断全部候选是否被占用
+const maxSuggestionCandidates = 12
+
+// suggestionStopWords 是从标题取词时跳过的虚词
+var suggestionStopWords = map[string]struct{}{
+	"a": {}, "an": {}, "and": {}, "at": {}, "by": {}, "for": {}, "from": {}, "in": {}, "is": {},
+	"of": {}, "on": {}, "or": {}, "our": {}, "the": {}, "to": {}, "with": {}, "your": {},
+}
+
+// SuggestCodes 为已被占用的短码生成候选，按推荐程度排列，全部通过 ValidateCode 且不含 code 本身。
+//
+// 候选来自目标地址的域名（github.com -> Github）与标题中的实词，例如 launch 被占用、
+// 目标是 github.com 上的 "Product Launch Notes" 时依次给出 launchGithub、githubLaunch、
+// ProductLaunch、ProductLaunchNotes、launchProduct……，最后补上 launch2、launch3 这类数字后缀兜底。
+// rawURL 与 title 都可以为空。
+//
+// 设计原因：
+// - 只做纯字符串变换、不查库：是否被占用由调用方对全部候选一次性查询
+// - 候选顺序固定，用户边输入边看时结果不会来回跳动
+func SuggestCodes(code, rawURL, title string) []string {
+	base := alnumOnly(strings.TrimSpace(code))
+	domain := domainWord(rawURL)
+	words := titleWords(title)
+
+	var candidates []string
+	if base != "" && domain != "" {
+		candidates = append(candidates, base+capitalize(domain), domain+capitalize(base))
+	}
+	for n := 2; n <= 3 && n <= len(words); n++ {
+		candidates = append(candidates, camelJoin(words[:n]))
+	}
+	if base != "" && len(words) > 0 {
+		candidates = append(candidates, base+capitalize(words[0]))
+	}
+	if domain != "" && len(words) > 0 {
+		candidates = append(candidates, domain+capitalize(words[0]))
+	}
+	for i := 2; base != "" && i <= 9; i++ {
+		candidates = append(candidates, base+strconv.Itoa(i))
+	}
+
+	seen := map[string]struct{}{code: {}}
+	out := make([]string, 0, maxSuggestionCandidates)
+	for _, c := range candidates {
+		if len(c) > maxCodeLength {
+			c = c[:maxCodeLength]
+		}
+		if _, ok := seen[c]; ok || ValidateCode(c) != nil {
+			continue
+		}
+		seen[c] = struct{}{}
+		out = append(out, c)
+		if len(out) == maxSuggestionCandidates {
+			break
+		}
+	}
+	return out
+}
+
+// domainWord 取目标地址主域名中最有辨识度的一段：docs.github.com -> github，bbc.co.uk -> bbc。
+// 不查公共后缀表，倒数第二段是 co、com 这类不超过 3 个字符的通用段时再往前取一段。
+func domainWord(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return ""
+	}
+	labels := strings.Split(strings.TrimPrefix(strings.ToLower(u.Hostname()), "www."), ".")
+	if len(labels) < 2 {
+		return ""
+	}
+	word := labels[len(labels)-2]
+	if len(labels) >= 3 && len(word) <= 3 {
+		word = labels[len(labels)-3]
+	}
+	return alnumOnly(word)
+}
+
+// titleWords 把标题拆成小写实词，只保留 ASCII 字母数字（短码的字符集），最多取前 3 个
+func titleWords(title string) []string {
+	fields := strings.FieldsFunc(title, func(r rune) bool {
+		return r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+	var words []string
+	for _, f := range fields {
+		f = strings.ToLower(f)
+		if _, stop := suggestionStopWords[f]; stop {
+			continue
+		}
+		words = append(words, f)
+		if len(words) == 3 {
+			break
+		}
+	}
+	return words
+}
+
+func camelJoin(words []string) string {
+	var b strings.Builder
+	for _, w := range words {
+		b.WriteString(capitalize(w))
+	}
+	return b.String()
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func alnumOnly(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return r
+		}
+		return -1
+	}, s)
+}
@@ -0,0 +1,118 @@
+package shortlink
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+var ErrInvalidUTM = errors.New("invalid utm parameters")
+
+const maxUTMValueLen = 256
+
+// UTM 是链接自带的 UTM 参数，跳转时合并进目标地址（utm_source、utm_medium ...）。
+//
+// 设计原因：
+// - 同一个目标地址按渠道建多条短链，各自带不同的 UTM，不必在目标地址里重复拼参数
+// - 与 RedirectRule 一样随短链整条缓存并以 JSON 存储，所以带 json tag
+type UTM struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// params 返回需要写入的 utm_* 参数，未设置的字段不写
+func (u *UTM) params() url.Values {
+	q := url.Values{}
+	if u == nil {
+		return q
+	}
+	for _, kv := range [][2]string{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+		{"utm_term", u.Term},
+		{"utm_content", u.Content},
+	} {
+		if kv[1] != "" {
+			q.Set(kv[0], kv[1])
+		}
+	}
+	return q
+}
+
+// NormalizeUTM 去掉首尾空白并校验长度与控制字符；全部为空时返回 nil（不启用）。
+func NormalizeUTM(u *UTM) (*UTM, error) {
+	if u == nil {
+		return nil, nil
+	}
+	out := UTM{
+		Source:   strings.TrimSpace(u.Source),
+		Medium:   strings.TrimSpace(u.Medium),
+		Campaign: strings.TrimSpace(u.Campaign),
+		Term:     strings.TrimSpace(u.Term),
+		Content:  strings.TrimSpace(u.Content),
+	}
+	if out == (UTM{}) {
+		return nil, nil
+	}
+	for _, v := range []string{out.Source, out.Medium, out.Campaign, out.Term, out.Content} {
+		if len(v) > maxUTMValueLen || strings.IndexFunc(v, unicode.IsControl) >= 0 {
+			return nil, ErrInvalidUTM
+		}
+	}
+	return &out, nil
+}
+
+// DecorateURL 把 UTM 参数与请求带来的查询参数 incoming（开启 ForwardQuery 时）合并进目标地址 dest。
+//
+// 优先级（高到低）：链接的 UTM > 目标地址自带的参数 > 请求透传的参数。
+// - UTM 覆盖目标地址里的同名 utm_*：渠道归因以短链为准
+// - 透传参数只补充目标地址里没有的键，访客不能借 ?utm_source=... 篡改归因或改写目标地址的参数
+//
+// 目标地址原有参数保持原样（不重新编码、不重排），只追加新参数；非 http(s) 地址（App scheme）原样返回。
+func (s Shortlink) DecorateURL(dest string, incoming url.Values) string {
+	utm := s.UTM.params()
+	if len(utm) == 0 && (!s.ForwardQuery || len(incoming) == 0) {
+		return dest
+	}
+	u, err := url.Parse(dest)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return dest
+	}
+
+	// 去掉被 UTM 覆盖的原参数，其余原样保留
+	var kept []string
+	existing := map[string]bool{}
+	if u.RawQuery != "" {
+		for _, pair := range strings.Split(u.RawQuery, "&") {
+			key, _, _ := strings.Cut(pair, "=")
+			if k, err := url.QueryUnescape(key); err == nil {
+				key = k
+			}
+			if _, ok := utm[key]; ok {
+				continue
+			}
+			existing[key] = true
+			kept = append(kept, pair)
+		}
+	}
+
+	extra := utm
+	if s.ForwardQuery {
+		for k, vs := range incoming {
+			if _, ok := utm[k]; ok || existing[k] {
+				continue
+			}
+			extra[k] = vs
+		}
+	}
+	if len(extra) > 0 {
+		kept = append(kept, extra.Encode())
+	}
+	u.RawQuery = strings.Join(kept, "&")
+	return u.String()
+}
@@ -4,7 +4,9 @@ import (
 	"errors"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ErrInvalidURL 是领域层对“URL 不合法”的统一错误。
@@ -14,6 +16,9 @@ import (
 // - 统一错误类型，避免各处返回不同字符串导致难以判断/测试
 var ErrInvalidURL = errors.New("invalid url")
 var ErrInvalidCode = errors.New("invalid code")
+var ErrInvalidExpireIn = errors.New("invalid expire_in")
+var ErrInvalidReason = errors.New("invalid reason")
+var ErrInvalidMaxClicks = errors.New("invalid max_clicks")
 
 // ValidateURL 校验用户输入的 URL 是否满足短链服务的最小要求。
 //
@@ -45,6 +50,8 @@ var reservedCodes = map[string]struct{}{
 	"healthz": {},
 	"_astro":  {},
 	"favicon": {},
+	// GET /api/v1/shortlinks/availability 是静态路由，同名短码的元数据查不到
+	"availability": {},
 }
 
 // ValidateCode 校验用户自定义短码。
@@ -58,8 +65,71 @@ func ValidateCode(code string) error {
 	if !codeRe.MatchString(code) {
 		return ErrInvalidCode
 	}
-	if _, ok := reservedCodes[strings.ToLower(code)]; ok {
+	if IsReservedCode(code) {
 		return ErrInvalidCode
 	}
 	return nil
 }
+
+// IsReservedCode 判断短码是否与站点已有路由前缀冲突（不区分大小写）
+func IsReservedCode(code string) bool {
+	_, ok := reservedCodes[strings.ToLower(strings.TrimSpace(code))]
+	return ok
+}
+
+// maxExpireIn 限制相对过期时间的上限，避免 now+d 溢出，也避免“永久”被写成一个极大的时长。
+const maxExpireIn = 10 * 365 * 24 * time.Hour
+
+// ParseExpireIn 解析创建短链时的相对过期时间（expire_in）。
+//
+// 规则：
+// - 支持 time.ParseDuration 的格式，例如 "90m"、"24h"
+// - 额外支持按天："7d"（time.ParseDuration 不支持 d）
+// - 必须大于 0，且不超过 10 年
+func ParseExpireIn(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return 0, ErrInvalidExpireIn
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n <= 0 || n > int(maxExpireIn/(24*time.Hour)) {
+			return 0, ErrInvalidExpireIn
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		d, err = time.ParseDuration(raw)
+		if err != nil {
+			return 0, ErrInvalidExpireIn
+		}
+	}
+	if d <= 0 || d > maxExpireIn {
+		return 0, ErrInvalidExpireIn
+	}
+	return d, nil
+}
+
+// maxReasonLen 限制启用/停用原因的长度（按字符计）
+const maxReasonLen = 500
+
+// ValidateReason 校验启用/停用时填写的原因，可为空。
+func ValidateReason(reason string) error {
+	if len([]rune(reason)) > maxReasonLen {
+		return ErrInvalidReason
+	}
+	return nil
+}
+
+// maxMaxClicks 点击上限的最大值，更大的需求等同于不限
+const maxMaxClicks = 1_000_000_000
+
+// ValidateMaxClicks 校验点击上限，0 表示不限。
+func ValidateMaxClicks(n int64) error {
+	if n < 0 || n > maxMaxClicks {
+		return ErrInvalidMaxClicks
+	}
+	return nil
+}
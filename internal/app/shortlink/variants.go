@@ -0,0 +1,78 @@
+package shortlink
+
+import (
+	"errors"
+	"math/rand/v2"
+	"regexp"
+)
+
+var ErrInvalidVariants = errors.New("invalid variants")
+
+const (
+	maxVariants      = 10
+	maxVariantWeight = 1000
+)
+
+var variantNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// Variant 是 A/B 分流中的一个目标：按 Weight 占总权重的比例分配点击。
+//
+// Name 会写进点击统计（click_stats.variant）和粘性 cookie，所以要求短且只含安全字符。
+// 与 RedirectRule 一样随短链整条缓存并以 JSON 存储，所以带 json tag。
+type Variant struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// PickVariant 按权重随机选一个版本；没有版本时返回 false。
+//
+// sticky 非空且是现有版本名时直接返回该版本（访客之前分到的版本），保证同一访客看到同一落地页。
+func (s Shortlink) PickVariant(sticky string) (Variant, bool) {
+	if len(s.Variants) == 0 {
+		return Variant{}, false
+	}
+	if sticky != "" {
+		for _, v := range s.Variants {
+			if v.Name == sticky {
+				return v, true
+			}
+		}
+	}
+	total := 0
+	for _, v := range s.Variants {
+		total += v.Weight
+	}
+	n := rand.IntN(total)
+	for _, v := range s.Variants {
+		if n < v.Weight {
+			return v, true
+		}
+		n -= v.Weight
+	}
+	return s.Variants[len(s.Variants)-1], true
+}
+
+// NormalizeVariants 校验分流版本：2~10 个，名称唯一，权重 1~1000。空列表表示不分流。
+func NormalizeVariants(variants []Variant) ([]Variant, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	if len(variants) < 2 || len(variants) > maxVariants {
+		return nil, ErrInvalidVariants
+	}
+	seen := make(map[string]bool, len(variants))
+	for _, v := range variants {
+		if !variantNameRe.MatchString(v.Name) || seen[v.Name] {
+			return nil, ErrInvalidVariants
+		}
+		seen[v.Name] = true
+		if v.Weight < 1 || v.Weight > maxVariantWeight {
+			return nil, ErrInvalidVariants
+		}
+		if err := ValidateURL(v.URL); err != nil {
+			return nil, err
+		}
+	}
+	return variants, nil
+}
@@ -50,6 +50,41 @@ type Config struct {
 	// RateLimit
 	RateLimitEnabled bool `env:"RATELIMIT_ENABLED" envDefault:"true"`
 
+	// Shortlink 过期清理
+	ExpirySweepInterval time.Duration `env:"EXPIRY_SWEEP_INTERVAL" envDefault:"1m"`
+	ExpiredRetention    time.Duration `env:"EXPIRED_RETENTION" envDefault:"720h"` // 过期多久后物理删除
+
+	// 目的地域名策略变化后复查已有短链的检查间隔
+	PolicyRecheckInterval time.Duration `env:"POLICY_RECHECK_INTERVAL" envDefault:"1m"`
+
+	// URL 信誉检查（Safe Browsing）：API key 为空表示不启用
+	SafeBrowsingAPIKey string        `env:"SAFE_BROWSING_API_KEY"`
+	URLCheckFailClosed bool          `env:"URL_CHECK_FAIL_CLOSED" envDefault:"false"` // 查询失败时拒绝创建（默认放行）
+	URLRescanInterval  time.Duration `env:"URL_RESCAN_INTERVAL" envDefault:"24h"`
+
+	// 目标地址健康检查的间隔，每轮探测上一轮之后没检查过的短链
+	HealthCheckInterval time.Duration `env:"HEALTH_CHECK_INTERVAL" envDefault:"10m"`
+
+	// 目标页面元信息（标题、图标等）的抓取间隔，每轮抓取一批新建或改过地址的短链
+	MetadataFetchInterval time.Duration `env:"METADATA_FETCH_INTERVAL" envDefault:"15s"`
+
+	// 密码保护短链：输入正确密码后免输有效期（cookie 用从 JWTSecret 派生的子密钥签名）
+	LinkUnlockTTL time.Duration `env:"LINK_UNLOCK_TTL" envDefault:"30m"`
+
+	// 条件跳转按国家匹配时，从哪个代理头读取访客国家码；置空表示不识别国家
+	GeoIPCountryHeader string `env:"GEOIP_COUNTRY_HEADER" envDefault:"CF-IPCountry"`
+
+	// 二维码中心 logo（PNG/JPEG 文件路径），为空表示不支持 logo=true
+	QRLogoFile string `env:"QR_LOGO_FILE"`
+
+	// 新短链的短码生成方式：sqids（按 id 编码，最短但可枚举）、random（随机 base62）、words（形容词+名词）
+	// 只影响之后生成的短码，已有短码照常跳转
+	CodeGenerator    string   `env:"CODE_GENERATOR" envDefault:"sqids"`
+	SqidsAlphabet    string   `env:"SQIDS_ALPHABET"` // 为空使用内置字母表
+	SqidsMinLength   int      `env:"SQIDS_MIN_LENGTH" envDefault:"3"`
+	SqidsBlocklist   []string `env:"SQIDS_BLOCKLIST"` // 逗号分隔，追加在 sqids 自带的屏蔽词表之后
+	RandomCodeLength int      `env:"RANDOM_CODE_LENGTH" envDefault:"8"`
+
 	// AIFlow
 	AIFlowEnabled   bool   `env:"AIFLOW_ENABLED" envDefault:"true"`
 	DeepSeekAPIKey  string `env:"DEEPSEEK_API_KEY"`
@@ -103,6 +138,19 @@ func Load() Config {
 
 		RateLimitEnabled: true,
 
+		ExpirySweepInterval:   time.Minute,
+		ExpiredRetention:      30 * 24 * time.Hour,
+		PolicyRecheckInterval: time.Minute,
+		URLRescanInterval:     24 * time.Hour,
+		HealthCheckInterval:   10 * time.Minute,
+		MetadataFetchInterval: 15 * time.Second,
+		LinkUnlockTTL:         30 * time.Minute,
+		GeoIPCountryHeader:    "CF-IPCountry",
+
+		CodeGenerator:    "sqids",
+		SqidsMinLength:   3,
+		RandomCodeLength: 8,
+
 		// AIFlow
 		AIFlowEnabled:   true,
 		DeepSeekBaseURL: "https://api.siliconflow.cn/v1",
@@ -225,6 +273,81 @@ func Load() Config {
 		cfg.RateLimitEnabled = strings.ToLower(v) == "true"
 	}
 
+	// Shortlink 过期清理
+	if v, ok := os.LookupEnv("EXPIRY_SWEEP_INTERVAL"); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.ExpirySweepInterval = d
+		}
+	}
+	if v, ok := os.LookupEnv("EXPIRED_RETENTION"); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			cfg.ExpiredRetention = d
+		}
+	}
+	if v, ok := os.LookupEnv("POLICY_RECHECK_INTERVAL"); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.PolicyRecheckInterval = d
+		}
+	}
+	if v, ok := os.LookupEnv("SAFE_BROWSING_API_KEY"); ok && v != "" {
+		cfg.SafeBrowsingAPIKey = v
+	}
+	if v, ok := os.LookupEnv("URL_CHECK_FAIL_CLOSED"); ok && v != "" {
+		cfg.URLCheckFailClosed = strings.ToLower(v) == "true"
+	}
+	if v, ok := os.LookupEnv("URL_RESCAN_INTERVAL"); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.URLRescanInterval = d
+		}
+	}
+	if v, ok := os.LookupEnv("HEALTH_CHECK_INTERVAL"); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.HealthCheckInterval = d
+		}
+	}
+	if v, ok := os.LookupEnv("METADATA_FETCH_INTERVAL"); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.MetadataFetchInterval = d
+		}
+	}
+	if v, ok := os.LookupEnv("LINK_UNLOCK_TTL"); ok && v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			cfg.LinkUnlockTTL = d
+		}
+	}
+	if v, ok := os.LookupEnv("GEOIP_COUNTRY_HEADER"); ok {
+		cfg.GeoIPCountryHeader = strings.TrimSpace(v)
+	}
+	if v, ok := os.LookupEnv("QR_LOGO_FILE"); ok {
+		cfg.QRLogoFile = strings.TrimSpace(v)
+	}
+
+	// 短码生成
+	if v, ok := os.LookupEnv("CODE_GENERATOR"); ok && v != "" {
+		cfg.CodeGenerator = strings.ToLower(strings.TrimSpace(v))
+	}
+	if v, ok := os.LookupEnv("SQIDS_ALPHABET"); ok {
+		cfg.SqidsAlphabet = strings.TrimSpace(v)
+	}
+	if v, ok := os.LookupEnv("SQIDS_MIN_LENGTH"); ok && v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cfg.SqidsMinLength = n
+		}
+	}
+	if v, ok := os.LookupEnv("SQIDS_BLOCKLIST"); ok && v != "" {
+		cfg.SqidsBlocklist = nil
+		for _, w := range strings.Split(v, ",") {
+			if w = strings.TrimSpace(w); w != "" {
+				cfg.SqidsBlocklist = append(cfg.SqidsBlocklist, w)
+			}
+		}
+	}
+	if v, ok := os.LookupEnv("RANDOM_CODE_LENGTH"); ok && v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.RandomCodeLength = n
+		}
+	}
+
 	// AIFlow
 	if v, ok := os.LookupEnv("AIFLOW_ENABLED"); ok && v != "" {
 		cfg.AIFlowEnabled = strings.ToLower(v) == "true"
@@ -0,0 +1,71 @@
+package dnsverify
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+)
+
+var ErrNotVerified = errors.New("domain verification record not found")
+
+// recordPrefix 是校验记录的子域名前缀，valuePrefix 是记录值的前缀
+const (
+	recordPrefix = "_shortlink-challenge."
+	valuePrefix  = "shortlink-verification="
+)
+
+// Verifier 校验调用方是否控制域名 host：token 是注册域名时下发的随机串。
+//
+// 设计原因：
+// - 校验方式可以是 DNS TXT，也可以是 HTTP 文件等；调用方只依赖接口，测试里可以换成假实现
+type Verifier interface {
+	Verify(ctx context.Context, host, token string) error
+}
+
+// TXTResolver 是 TXTVerifier 需要的 DNS 查询能力，*net.Resolver 满足该接口。
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// TXTVerifier 要求在 RecordName(host) 上添加值为 RecordValue(token) 的 TXT 记录。
+type TXTVerifier struct {
+	resolver TXTResolver
+}
+
+// NewTXTVerifier 创建 DNS TXT 校验器；resolver 为 nil 时使用 net.DefaultResolver。
+func NewTXTVerifier(resolver TXTResolver) *TXTVerifier {
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	return &TXTVerifier{resolver: resolver}
+}
+
+// RecordName 返回需要添加 TXT 记录的名字，例如 "_shortlink-challenge.go.team.example"
+func RecordName(host string) string {
+	return recordPrefix + host
+}
+
+// RecordValue 返回 TXT 记录的值
+func RecordValue(token string) string {
+	return valuePrefix + token
+}
+
+// Verify 查询 TXT 记录；记录不存在（NXDOMAIN）与值不匹配都返回 ErrNotVerified，其它 DNS 错误原样返回。
+func (v *TXTVerifier) Verify(ctx context.Context, host, token string) error {
+	records, err := v.resolver.LookupTXT(ctx, RecordName(host))
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) && dnsErr.IsNotFound {
+			return ErrNotVerified
+		}
+		return err
+	}
+	want := RecordValue(token)
+	for _, r := range records {
+		if strings.TrimSpace(r) == want {
+			return nil
+		}
+	}
+	return ErrNotVerified
+}
@@ -0,0 +1,45 @@
+package geoip
+
+import (
+	"net/http"
+	"strings"
+
+	"day.local/internal/platform/httpmiddleware"
+)
+
+// CountryResolver 根据请求解析访客所在国家。
+//
+// 返回 ISO 3166-1 alpha-2 大写国家码（例如 "DE"），无法判断时返回空字符串。
+//
+// 设计原因：
+// - 国家来源可以是 CDN 注入的头部，也可以是本地 GeoIP 数据库；调用方只依赖接口，切换来源不改业务代码
+type CountryResolver interface {
+	Country(req *http.Request) string
+}
+
+// HeaderResolver 从 CDN/反向代理注入的头部读取国家码，例如 Cloudflare 的 CF-IPCountry。
+//
+// 只信任来自可信代理的请求（规则同 httpmiddleware.ClientIP），否则客户端可以自己伪造头部。
+type HeaderResolver struct {
+	header string
+}
+
+// NewHeaderResolver 创建读取 header 的解析器；header 为空时返回 nil，表示不识别国家。
+func NewHeaderResolver(header string) *HeaderResolver {
+	if header == "" {
+		return nil
+	}
+	return &HeaderResolver{header: header}
+}
+
+func (h *HeaderResolver) Country(req *http.Request) string {
+	if h == nil || !httpmiddleware.FromTrustedProxy(req) {
+		return ""
+	}
+	c := strings.ToUpper(strings.TrimSpace(req.Header.Get(h.header)))
+	// Cloudflare 用 XX 表示未知、T1 表示 Tor
+	if len(c) != 2 || c == "XX" || c == "T1" || c[0] < 'A' || c[0] > 'Z' || c[1] < 'A' || c[1] > 'Z' {
+		return ""
+	}
+	return c
+}
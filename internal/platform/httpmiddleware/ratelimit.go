@@ -60,6 +60,16 @@ func ClientIP(req *http.Request) string {
 	return remoteHost
 }
 
+// FromTrustedProxy 判断请求是否直接来自可信代理，只有这种请求的转发类头部（IP、国家等）才可信。
+func FromTrustedProxy(req *http.Request) bool {
+	remoteHost, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		remoteHost = req.RemoteAddr
+	}
+	remoteIP := net.ParseIP(remoteHost)
+	return remoteIP != nil && isTrustedProxy(remoteIP)
+}
+
 func isTrustedProxy(ip net.IP) bool {
 	// 同机反代（如 Caddy 与应用在同一台机器）。
 	if ip.IsLoopback() {
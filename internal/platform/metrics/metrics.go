@@ -86,6 +86,75 @@ var (
 		},
 	)
 
+	// ShortlinkExpiredPurged：过期后被 sweeper 清理的短链数
+	ShortlinkExpiredPurged = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "shortlink_expired_purged_total",
+			Help: "过期后被清理的短链总数",
+		},
+	)
+
+	// ShortlinkAutoDisabled：被系统自动停用的短链数
+	// labels:
+	// - reason: "policy"（命中目的地域名策略）、"reputation"（复扫时被信誉服务标记）
+	ShortlinkAutoDisabled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shortlink_auto_disabled_total",
+			Help: "被系统自动停用的短链总数",
+		},
+		[]string{"reason"},
+	)
+
+	// URLCheckFlagged：被信誉服务标记的目标地址数
+	// labels:
+	// - stage: "create"（创建/修改时拒绝）、"rescan"（复扫时发现）
+	URLCheckFlagged = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shortlink_url_check_flagged_total",
+			Help: "被信誉服务标记的目标地址总数",
+		},
+		[]string{"stage"},
+	)
+
+	// URLCheckErrors：信誉查询失败次数（fail open 时这些请求未经检查就放行了）
+	URLCheckErrors = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "shortlink_url_check_errors_total",
+			Help: "信誉查询失败总数",
+		},
+	)
+
+	// DestinationHealthChecks：目标地址健康检查次数
+	// labels:
+	// - result: "ok"、"fail"
+	DestinationHealthChecks = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shortlink_destination_health_checks_total",
+			Help: "目标地址健康检查总数",
+		},
+		[]string{"result"},
+	)
+
+	// ShortcodeCollisions：生成的短码已被占用、需要重新生成的次数
+	// 持续升高说明短码空间偏小（例如随机短码太短），需要调大长度
+	ShortcodeCollisions = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "shortlink_code_collisions_total",
+			Help: "生成短码时遇到冲突的次数",
+		},
+	)
+
+	// MetadataFetches：目标页面元信息抓取次数
+	// labels:
+	// - result: "ok"、"fail"
+	MetadataFetches = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "shortlink_metadata_fetches_total",
+			Help: "目标页面元信息抓取总数",
+		},
+		[]string{"result"},
+	)
+
 	// ========== 数据库指标 ==========
 
 	// DBQueryDuration：数据库查询耗时
@@ -127,6 +196,13 @@ func Init() {
 			CacheOperations,
 			ShortlinkCreated,
 			ShortlinkRedirects,
+			ShortlinkExpiredPurged,
+			ShortlinkAutoDisabled,
+			URLCheckFlagged,
+			URLCheckErrors,
+			DestinationHealthChecks,
+			ShortcodeCollisions,
+			MetadataFetches,
 			DBQueryDuration,
 			StatsFlushDuration,
 			StatsFlushSize,
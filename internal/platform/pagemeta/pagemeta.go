@@ -0,0 +1,205 @@
+package pagemeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+var ErrNotHTML = errors.New("destination is not an html page")
+
+// 单个字段的长度上限：页面内容不可信，存储与展示前都要截断
+const (
+	maxTitleLen       = 300
+	maxDescriptionLen = 1000
+	maxURLLen         = 2048
+)
+
+// userAgent 标明请求来自短链服务抓取元信息
+const userAgent = "shortlink-metadata/1.0"
+
+// Metadata 是从目标页面 <head> 中取到的信息，取不到的字段为空
+type Metadata struct {
+	Title       string
+	Description string
+	FaviconURL  string
+	ImageURL    string
+}
+
+// Fetcher 抓取页面并解析元信息。
+//
+// 设计原因：
+// - 只读 maxBytes 字节、读到 <body> 即停：元信息都在 <head> 里，不必下载整个页面，也防止超大响应拖垮服务
+// - client 由调用方提供：线上用 safehttp 的客户端挡住内网地址（SSRF），测试里可以直接访问 httptest 服务
+type Fetcher struct {
+	client   *http.Client
+	maxBytes int64
+}
+
+// NewFetcher 创建抓取器，最多读取响应的前 512KB
+func NewFetcher(client *http.Client) *Fetcher {
+	return &Fetcher{client: client, maxBytes: 512 << 10}
+}
+
+// Fetch 抓取 rawURL 的元信息；相对地址按跟随跳转后的最终地址解析。
+// 状态码 >= 400 或响应不是 HTML 时返回错误。
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml;q=0.9,*/*;q=0.1")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return Metadata{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mt, _, err := mime.ParseMediaType(contentType); contentType != "" && (err != nil || (mt != "text/html" && mt != "application/xhtml+xml")) {
+		return Metadata{}, ErrNotHTML
+	}
+
+	body, err := charset.NewReader(io.LimitReader(resp.Body, f.maxBytes), contentType)
+	if err != nil {
+		return Metadata{}, err
+	}
+	return Parse(body, resp.Request.URL), nil
+}
+
+// Parse 从 HTML 中解析元信息，base 用于解析相对地址。
+//
+// 取值优先级：
+// - 标题：<title>，没有时用 og:title
+// - 描述：<meta name="description">，没有时用 og:description
+// - 图标：<link rel="icon">（含 shortcut icon、apple-touch-icon），没有时用站点根目录的 /favicon.ico
+// - 图片：og:image，没有时用 twitter:image
+func Parse(r io.Reader, base *url.URL) Metadata {
+	var m Metadata
+	var ogTitle, ogDescription, twitterImage, appleIcon string
+	z := html.NewTokenizer(r)
+	inTitle := false
+parse:
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			break parse
+		case html.TextToken:
+			if inTitle && m.Title == "" {
+				m.Title = string(z.Text())
+			}
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break parse
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			attrs := map[string]string{}
+			for hasAttr {
+				var k, v []byte
+				k, v, hasAttr = z.TagAttr()
+				attrs[string(k)] = string(v)
+			}
+			switch string(name) {
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "body":
+				break parse
+			case "meta":
+				key := attrs["property"]
+				if key == "" {
+					key = attrs["name"]
+				}
+				content := attrs["content"]
+				switch strings.ToLower(key) {
+				case "description":
+					setOnce(&m.Description, content)
+				case "og:title":
+					setOnce(&ogTitle, content)
+				case "og:description":
+					setOnce(&ogDescription, content)
+				case "og:image", "og:image:url":
+					setOnce(&m.ImageURL, resolve(base, content))
+				case "twitter:image":
+					setOnce(&twitterImage, resolve(base, content))
+				}
+			case "link":
+				for _, rel := range strings.Fields(strings.ToLower(attrs["rel"])) {
+					switch rel {
+					case "icon":
+						setOnce(&m.FaviconURL, resolve(base, attrs["href"]))
+					case "apple-touch-icon":
+						setOnce(&appleIcon, resolve(base, attrs["href"]))
+					}
+				}
+			}
+		}
+	}
+
+	setOnce(&m.Title, ogTitle)
+	setOnce(&m.Description, ogDescription)
+	setOnce(&m.ImageURL, twitterImage)
+	setOnce(&m.FaviconURL, appleIcon)
+	if base != nil {
+		setOnce(&m.FaviconURL, resolve(base, "/favicon.ico"))
+	}
+	m.Title = clean(m.Title, maxTitleLen)
+	m.Description = clean(m.Description, maxDescriptionLen)
+	return m
+}
+
+func setOnce(dst *string, v string) {
+	if *dst == "" {
+		*dst = strings.TrimSpace(v)
+	}
+}
+
+// resolve 把页面里的地址按 base 解析为绝对地址；只接受 http/https，过长的丢弃
+func resolve(base *url.URL, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	if base != nil {
+		u = base.ResolveReference(u)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	s := u.String()
+	if len(s) > maxURLLen {
+		return ""
+	}
+	return s
+}
+
+// clean 合并空白并按字符数截断，去掉无效的 UTF-8
+func clean(s string, max int) string {
+	s = strings.Join(strings.Fields(strings.ToValidUTF8(s, "")), " ")
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	return string([]rune(s)[:max])
+}
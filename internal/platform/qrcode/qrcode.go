@@ -0,0 +1,283 @@
+package qrcode
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // 支持 JPEG 格式的 logo
+	"image/png"
+	"os"
+	"strings"
+
+	goqrcode "github.com/skip2/go-qrcode"
+)
+
+var ErrSizeTooSmall = errors.New("size too small for this qr code")
+var ErrNoLogo = errors.New("qr logo not configured")
+
+// Format 是输出格式
+type Format string
+
+const (
+	PNG Format = "png"
+	SVG Format = "svg"
+)
+
+// ContentType 返回格式对应的 Content-Type
+func (f Format) ContentType() string {
+	if f == SVG {
+		return "image/svg+xml"
+	}
+	return "image/png"
+}
+
+// Level 是纠错等级，可恢复的损坏比例依次约为 7%、15%、25%、30%
+type Level int
+
+const (
+	LevelL Level = iota
+	LevelM
+	LevelQ
+	LevelH
+)
+
+// ParseLevel 解析 "L"/"M"/"Q"/"H"（不区分大小写）
+func ParseLevel(s string) (Level, bool) {
+	switch strings.ToUpper(s) {
+	case "L":
+		return LevelL, true
+	case "M":
+		return LevelM, true
+	case "Q":
+		return LevelQ, true
+	case "H":
+		return LevelH, true
+	}
+	return 0, false
+}
+
+func (l Level) recovery() goqrcode.RecoveryLevel {
+	switch l {
+	case LevelL:
+		return goqrcode.Low
+	case LevelQ:
+		return goqrcode.High
+	case LevelH:
+		return goqrcode.Highest
+	}
+	return goqrcode.Medium
+}
+
+// Options 是渲染参数
+type Options struct {
+	Format Format
+	Size   int // 图片边长（像素）；SVG 中是 width/height，矢量图可任意缩放
+	Margin int // 四周静区宽度（模块数），标准要求至少 4
+	Level  Level
+	FG     color.RGBA
+	BG     color.RGBA
+	Logo   bool // 在中心放置 logo，纠错等级自动提高到 H
+}
+
+// logoRatio 是 logo 区域占码区边长的比例：面积约 4%，远低于 H 级可恢复的 30%
+const logoRatio = 0.2
+
+// Renderer 把内容编码成二维码并输出 PNG 或 SVG。
+//
+// 设计原因：
+// - 编码用 go-qrcode，只取模块矩阵；尺寸、静区、颜色、logo 自己画，PNG 与 SVG 的模块位置完全一致
+// - logo 由部署方配置（QR_LOGO_FILE），不接受请求里的图片地址：既不用抓取外部图片（SSRF），也不会被人借服务生成带任意图案的码
+type Renderer struct {
+	logo    image.Image
+	logoPNG string // SVG 内嵌用的 base64 PNG
+}
+
+// NewRenderer 创建渲染器；logo 为 nil 表示不支持 logo
+func NewRenderer(logo image.Image) (*Renderer, error) {
+	r := &Renderer{logo: logo}
+	if logo != nil {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, logo); err != nil {
+			return nil, err
+		}
+		r.logoPNG = base64.StdEncoding.EncodeToString(buf.Bytes())
+	}
+	return r, nil
+}
+
+// NewRendererFromFile 用 logo 文件创建渲染器；path 为空表示不支持 logo
+func NewRendererFromFile(path string) (*Renderer, error) {
+	if path == "" {
+		return NewRenderer(nil)
+	}
+	logo, err := LoadLogo(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRenderer(logo)
+}
+
+// LoadLogo 读取 PNG 或 JPEG 格式的 logo 文件
+func LoadLogo(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode qr logo %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// Render 把 content 编码成二维码图片。
+// 内容超出容量时返回 go-qrcode 的错误；PNG 尺寸放不下每个模块 1 像素时返回 ErrSizeTooSmall。
+func (r *Renderer) Render(content string, opts Options) ([]byte, error) {
+	if opts.Logo {
+		if r == nil || r.logo == nil {
+			return nil, ErrNoLogo
+		}
+		opts.Level = LevelH
+	}
+	q, err := goqrcode.New(content, opts.Level.recovery())
+	if err != nil {
+		return nil, err
+	}
+	q.DisableBorder = true
+	m := matrix{modules: q.Bitmap()}
+	if opts.Logo {
+		m.clearCenter()
+	}
+	if opts.Format == SVG {
+		return r.svg(m, opts), nil
+	}
+	return r.png(m, opts)
+}
+
+// matrix 是模块矩阵（不含静区），logo 区域内的模块已清空
+type matrix struct {
+	modules [][]bool
+	logoAt  int // logo 区域左上角（模块坐标），logoLen 为 0 表示没有 logo
+	logoLen int
+}
+
+// clearCenter 清空中心 logo 区域的模块；区域边长与码区同奇偶，保证正好居中
+func (m *matrix) clearCenter() {
+	n := len(m.modules)
+	l := int(float64(n) * logoRatio)
+	if (n-l)%2 != 0 {
+		l++
+	}
+	m.logoAt, m.logoLen = (n-l)/2, l
+	for y := m.logoAt; y < m.logoAt+l; y++ {
+		for x := m.logoAt; x < m.logoAt+l; x++ {
+			m.modules[y][x] = false
+		}
+	}
+}
+
+func (r *Renderer) png(m matrix, opts Options) ([]byte, error) {
+	n := len(m.modules)
+	total := n + 2*opts.Margin
+	scale := opts.Size / total
+	if scale < 1 {
+		return nil, ErrSizeTooSmall
+	}
+	// 整数倍放大保证模块边缘清晰，放不下的余量平均分到四周
+	offset := (opts.Size-scale*total)/2 + opts.Margin*scale
+
+	img := image.NewPaletted(image.Rect(0, 0, opts.Size, opts.Size), color.Palette{opts.BG, opts.FG})
+	for y, row := range m.modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for py := offset + y*scale; py < offset+(y+1)*scale; py++ {
+				start := img.PixOffset(offset+x*scale, py)
+				for i := 0; i < scale; i++ {
+					img.Pix[start+i] = 1
+				}
+			}
+		}
+	}
+
+	var out image.Image = img
+	if m.logoLen > 0 {
+		rgba := image.NewRGBA(img.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), img, image.Point{}, draw.Src)
+		// logo 四周留 1 个模块的空白，与码区的模块分开
+		inset := image.Rect(0, 0, (m.logoLen-2)*scale, (m.logoLen-2)*scale).Add(image.Pt(offset+(m.logoAt+1)*scale, offset+(m.logoAt+1)*scale))
+		drawFit(rgba, inset, r.logo)
+		out = rgba
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, out); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawFit 把 src 按比例缩放（最近邻）后居中画进 dst 的 box 区域
+func drawFit(dst draw.Image, box image.Rectangle, src image.Image) {
+	sb := src.Bounds()
+	if sb.Empty() || box.Empty() {
+		return
+	}
+	w, h := box.Dx(), box.Dy()
+	if sb.Dx()*h > sb.Dy()*w {
+		h = max(1, sb.Dy()*w/sb.Dx())
+	} else {
+		w = max(1, sb.Dx()*h/sb.Dy())
+	}
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			scaled.Set(x, y, src.At(sb.Min.X+x*sb.Dx()/w, sb.Min.Y+y*sb.Dy()/h))
+		}
+	}
+	at := box.Min.Add(image.Pt((box.Dx()-w)/2, (box.Dy()-h)/2))
+	draw.Draw(dst, scaled.Bounds().Add(at), scaled, image.Point{}, draw.Over)
+}
+
+// svg 每行连续的深色模块合成一段路径，文件小，印刷时放大也不会有缝隙
+func (r *Renderer) svg(m matrix, opts Options) []byte {
+	n := len(m.modules)
+	total := n + 2*opts.Margin
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`, total, total, hexColor(opts.BG))
+	fmt.Fprintf(&b, `<path fill="%s" d="`, hexColor(opts.FG))
+	for y, row := range m.modules {
+		for x := 0; x < n; {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < n && row[x] {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+	b.WriteString(`"/>`)
+	if m.logoLen > 0 {
+		pos := m.logoAt + 1 + opts.Margin
+		fmt.Fprintf(&b, `<image x="%d" y="%d" width="%d" height="%d" preserveAspectRatio="xMidYMid meet" xlink:href="data:image/png;base64,%s"/>`,
+			pos, pos, m.logoLen-2, m.logoLen-2, r.logoPNG)
+	}
+	b.WriteString(`</svg>`)
+	return []byte(b.String())
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
@@ -0,0 +1,64 @@
+package urlcheck
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"day.local/internal/platform/metrics"
+)
+
+var ErrUnavailable = errors.New("url reputation check unavailable")
+
+// Match 是一条被标记的地址，Threat 是威胁类型，例如 "MALWARE"、"SOCIAL_ENGINEERING"
+type Match struct {
+	URL    string `json:"url"`
+	Threat string `json:"threat"`
+}
+
+// Reason 返回对外展示的拒绝/停用原因，不含地址本身（地址可能很长，也不宜回显给其他人）
+func (m Match) Reason() string {
+	return "destination flagged as unsafe: " + m.Threat
+}
+
+// Checker 查询地址的信誉，返回被标记的地址；没有命中时返回空切片。
+//
+// 设计原因：
+// - 信誉来源可以是 Safe Browsing，也可以是自建黑名单等；调用方只依赖接口，测试里可以换成假实现
+// - 一次查询多个地址：创建时要同时检查规则与分流版本，批量创建/复扫时也能少发请求
+type Checker interface {
+	Check(ctx context.Context, urls []string) ([]Match, error)
+}
+
+// Screener 在 Checker 之上加上查询失败时的处理方式，创建短链时使用。
+//
+// 设计原因：
+// - 信誉服务不可用时放行（fail open）还是拒绝（fail closed）取决于部署，由配置决定
+// - 公开注册的实例宁可暂停创建，内部实例更在意可用性
+type Screener struct {
+	checker    Checker
+	failClosed bool
+}
+
+// NewScreener 创建 Screener；checker 为 nil 表示未启用信誉检查，全部放行。
+func NewScreener(checker Checker, failClosed bool) *Screener {
+	return &Screener{checker: checker, failClosed: failClosed}
+}
+
+// Screen 检查一组地址，返回被标记的地址。
+// 查询失败时：fail closed 返回 ErrUnavailable；fail open 记录日志与指标后放行。
+func (s *Screener) Screen(ctx context.Context, urls []string) ([]Match, error) {
+	if s == nil || s.checker == nil || len(urls) == 0 {
+		return nil, nil
+	}
+	matches, err := s.checker.Check(ctx, urls)
+	if err != nil {
+		metrics.URLCheckErrors.Inc()
+		slog.Error("url reputation check failed", "err", err, "fail_closed", s.failClosed)
+		if s.failClosed {
+			return nil, ErrUnavailable
+		}
+		return nil, nil
+	}
+	return matches, nil
+}
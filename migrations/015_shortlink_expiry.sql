@@ -0,0 +1,2 @@
+-- 过期短链：Resolve 按 expires_at 判断是否过期，后台 sweeper 按 expires_at 清理。
+CREATE INDEX IF NOT EXISTS idx_shortlinks_expires_at ON shortlinks(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,8 @@
+-- 每条短链可选跳转状态码（301/302/307/308）以及附加的 Cache-Control、X-Robots-Tag 响应头。
+ALTER TABLE shortlinks DROP CONSTRAINT IF EXISTS shortlinks_redirect_type_check;
+ALTER TABLE shortlinks
+    ADD CONSTRAINT shortlinks_redirect_type_check CHECK (redirect_type IN ('301','302','307','308'));
+
+ALTER TABLE shortlinks
+    ADD COLUMN IF NOT EXISTS cache_control TEXT,
+    ADD COLUMN IF NOT EXISTS robots_tag TEXT;
@@ -0,0 +1,17 @@
+-- 停用原因与发起方：管理员停用的短链不允许拥有者自行恢复。
+ALTER TABLE shortlinks
+    ADD COLUMN IF NOT EXISTS disabled_reason TEXT,
+    ADD COLUMN IF NOT EXISTS disabled_by_admin BOOLEAN NOT NULL DEFAULT false;
+
+-- 启用/停用的变更记录（谁、何时、为什么）
+CREATE TABLE IF NOT EXISTS shortlink_state_changes (
+    id           BIGSERIAL PRIMARY KEY,
+    shortlink_id BIGINT NOT NULL REFERENCES shortlinks(id) ON DELETE CASCADE,
+    disabled     BOOLEAN NOT NULL,
+    reason       TEXT,
+    actor_id     BIGINT,
+    by_admin     BOOLEAN NOT NULL DEFAULT false,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_shortlink_state_changes_link ON shortlink_state_changes(shortlink_id, id DESC);
@@ -0,0 +1,2 @@
+-- 短链访问密码（bcrypt 哈希），NULL 表示无需密码
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS password_hash TEXT;
@@ -0,0 +1,5 @@
+-- 点击上限：达到后自动停用（1 即一次性链接），NULL 表示不限
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS max_clicks BIGINT;
+ALTER TABLE shortlinks DROP CONSTRAINT IF EXISTS shortlinks_max_clicks_check;
+ALTER TABLE shortlinks
+    ADD CONSTRAINT shortlinks_max_clicks_check CHECK (max_clicks IS NULL OR max_clicks > 0);
@@ -0,0 +1,7 @@
+-- 私有短链：owner_id 非空的行只属于一个用户，有独立的短码、统计与生命周期。
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS owner_id BIGINT REFERENCES users(id);
+
+-- url 唯一（去重复用）只对共享行（owner_id IS NULL）生效，私有行允许重复 url。
+ALTER TABLE shortlinks DROP CONSTRAINT IF EXISTS shortlinks_url_key;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_shortlinks_url_shared ON shortlinks(url) WHERE owner_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_shortlinks_owner_id ON shortlinks(owner_id) WHERE owner_id IS NOT NULL;
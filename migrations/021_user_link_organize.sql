@@ -0,0 +1,18 @@
+-- 用户维度的短链整理：标题/备注、标签、文件夹都挂在 user_shortlinks 上，
+-- 共享同一行短链的不同用户各自整理，互不影响。
+CREATE TABLE IF NOT EXISTS user_folders (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users(id),
+    name       TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (user_id, name)
+);
+
+ALTER TABLE user_shortlinks
+    ADD COLUMN IF NOT EXISTS title TEXT,
+    ADD COLUMN IF NOT EXISTS notes TEXT,
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS folder_id BIGINT REFERENCES user_folders(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_shortlinks_tags ON user_shortlinks USING GIN (tags);
+CREATE INDEX IF NOT EXISTS idx_user_shortlinks_folder ON user_shortlinks(folder_id) WHERE folder_id IS NOT NULL;
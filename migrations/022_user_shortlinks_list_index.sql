@@ -0,0 +1,3 @@
+-- /users/mine 按创建时间的游标分页：(created_at, shortlink_id) 作为键集排序
+CREATE INDEX IF NOT EXISTS idx_user_shortlinks_user_created
+    ON user_shortlinks(user_id, created_at DESC, shortlink_id DESC);
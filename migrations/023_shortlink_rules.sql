@@ -0,0 +1,2 @@
+-- 条件跳转规则：按顺序匹配的 JSON 数组，NULL 表示没有规则
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS rules JSONB;
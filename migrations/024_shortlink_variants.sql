@@ -0,0 +1,8 @@
+-- A/B 分流：按权重分配的目标版本（JSON 数组，NULL 表示不分流），sticky_variant 表示同一访客固定同一版本
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS variants JSONB;
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS sticky_variant BOOLEAN NOT NULL DEFAULT false;
+
+-- 点击明细记录命中的版本，未分流为 NULL
+ALTER TABLE click_stats ADD COLUMN IF NOT EXISTS variant TEXT;
+-- 索引：按短链 code 统计各版本点击数
+CREATE INDEX IF NOT EXISTS idx_click_stats_code_variant ON click_stats(code, variant);
@@ -0,0 +1,2 @@
+-- 移动端 App 跳转配置（iOS/Android 的 App 地址与商店地址），NULL 表示不启用
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS deep_link JSONB;
@@ -0,0 +1,3 @@
+-- UTM 参数（跳转时合并进目标地址），NULL 表示不设置；forward_query 表示把短链上的查询参数透传给目标
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS utm JSONB;
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS forward_query BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,2 @@
+-- 路径透传：/{code}/a/b 把剩余路径 a/b 拼到目标地址后面
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS forward_path BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,19 @@
+-- 自定义域名：用户注册域名，添加 DNS TXT 记录校验后即可在该域名下创建短链。
+CREATE TABLE IF NOT EXISTS domains (
+    id           BIGSERIAL PRIMARY KEY,
+    host         TEXT NOT NULL UNIQUE,
+    owner_id     BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    verify_token TEXT NOT NULL,
+    verified_at  TIMESTAMPTZ,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_domains_owner_id ON domains(owner_id);
+
+-- 挂在自定义域名上的短链：domain_key 只在域名内唯一，code 仍全局唯一用作内部标识。
+-- 不级联删除：域名下还有短链时删除域名会失败，避免短链悄悄失效。
+ALTER TABLE shortlinks
+    ADD COLUMN IF NOT EXISTS domain_id BIGINT REFERENCES domains(id),
+    ADD COLUMN IF NOT EXISTS domain_key TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_shortlinks_domain_key ON shortlinks(domain_id, domain_key) WHERE domain_id IS NOT NULL;
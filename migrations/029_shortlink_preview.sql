@@ -0,0 +1,5 @@
+-- 预览页：preview 由拥有者设置，每次访问先展示目标地址再由访客确认跳转；
+-- preview_forced 由管理员对可疑短链强制开启，拥有者不能关闭。
+ALTER TABLE shortlinks
+    ADD COLUMN IF NOT EXISTS preview BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS preview_forced BOOLEAN NOT NULL DEFAULT false;
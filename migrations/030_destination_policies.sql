@@ -0,0 +1,21 @@
+-- 目的地域名策略：管理员维护的 allow/deny 列表，创建、修改、导入短链时检查目标地址的域名。
+CREATE TABLE IF NOT EXISTS destination_policies (
+    id         BIGSERIAL PRIMARY KEY,
+    pattern    TEXT NOT NULL UNIQUE,
+    action     TEXT NOT NULL CHECK (action IN ('allow', 'deny')),
+    note       TEXT,
+    created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- 全局设置（单行）：allowlist_only 打开后只放行命中 allow 的域名。
+-- 每次策略变化 version 加一；复查任务处理完某个版本后记到 checked_version，多实例只需处理一次。
+CREATE TABLE IF NOT EXISTS destination_policy_settings (
+    id              BOOLEAN PRIMARY KEY DEFAULT true CHECK (id),
+    allowlist_only  BOOLEAN NOT NULL DEFAULT false,
+    version         BIGINT NOT NULL DEFAULT 0,
+    checked_version BIGINT NOT NULL DEFAULT 0,
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+INSERT INTO destination_policy_settings (id) VALUES (true) ON CONFLICT DO NOTHING;
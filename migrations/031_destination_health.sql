@@ -0,0 +1,21 @@
+-- 目标地址健康检查：后台任务定期探测默认目标（HEAD，不支持时 GET）。
+-- health_failures 是连续失败次数，达到阈值后 dest_down=true；设置了 fallback_url 时跳转改到备用地址，恢复后自动切回。
+ALTER TABLE shortlinks
+    ADD COLUMN IF NOT EXISTS fallback_url TEXT,
+    ADD COLUMN IF NOT EXISTS dest_down BOOLEAN NOT NULL DEFAULT false,
+    ADD COLUMN IF NOT EXISTS health_failures INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS health_checked_at TIMESTAMPTZ;
+
+-- 探测历史：status_code 为 0 表示没有拿到响应（超时、DNS 失败等，原因见 error）；只保留最近一段时间。
+CREATE TABLE IF NOT EXISTS destination_checks (
+    id           BIGSERIAL PRIMARY KEY,
+    shortlink_id BIGINT NOT NULL REFERENCES shortlinks(id) ON DELETE CASCADE,
+    checked_at   TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ok           BOOLEAN NOT NULL,
+    status_code  INT NOT NULL DEFAULT 0,
+    latency_ms   INT NOT NULL DEFAULT 0,
+    error        TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_destination_checks_link ON destination_checks (shortlink_id, checked_at DESC);
+CREATE INDEX IF NOT EXISTS idx_destination_checks_checked_at ON destination_checks (checked_at);
@@ -0,0 +1,13 @@
+-- 目标页面元信息：后台任务抓取 <title>、描述、favicon 与 og:image，列表与预览页展示用。
+-- url 记录抓取时的目标地址，目标地址修改后与 shortlinks.url 不一致，会被重新抓取；
+-- 抓取失败时 error 非空，过一段时间后重试。
+CREATE TABLE IF NOT EXISTS link_metadata (
+    shortlink_id BIGINT PRIMARY KEY REFERENCES shortlinks(id) ON DELETE CASCADE,
+    url          TEXT NOT NULL,
+    title        TEXT,
+    description  TEXT,
+    favicon_url  TEXT,
+    image_url    TEXT,
+    error        TEXT,
+    fetched_at   TIMESTAMPTZ NOT NULL DEFAULT now()
+);
@@ -0,0 +1,2 @@
+-- 点击明细记录访问来源：扫码（/{code}~qr）为 'qr'，直接访问为 NULL
+ALTER TABLE click_stats ADD COLUMN IF NOT EXISTS source TEXT;
@@ -0,0 +1,7 @@
+-- 共享行（owner_id IS NULL）只有可复用的才按 url 去重：带过期时间的创建各占一行，
+-- 不会被别人同 url 的创建延长、取消过期或在过期后复活。
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS reusable BOOLEAN NOT NULL DEFAULT true;
+UPDATE shortlinks SET reusable=false WHERE owner_id IS NULL AND expires_at IS NOT NULL;
+
+DROP INDEX IF EXISTS uniq_shortlinks_url_shared;
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_shortlinks_url_shared ON shortlinks(url) WHERE owner_id IS NULL AND reusable;
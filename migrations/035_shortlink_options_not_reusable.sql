@@ -0,0 +1,7 @@
+-- 设置了密码、点击上限、条件跳转、A/B 分流、App 跳转、UTM/参数/路径透传、预览页或备用地址的共享行
+-- 不再按 url 去重复用，同一 url 的普通创建会生成新行。
+UPDATE shortlinks SET reusable=false
+WHERE owner_id IS NULL AND reusable AND (
+    password_hash IS NOT NULL OR max_clicks IS NOT NULL OR rules IS NOT NULL OR variants IS NOT NULL OR sticky_variant
+    OR deep_link IS NOT NULL OR utm IS NOT NULL OR forward_query OR forward_path OR preview OR fallback_url IS NOT NULL
+);
@@ -0,0 +1,7 @@
+-- created_by 记录插入这一行的用户，只有自己创建、没有按 url 去重发给别人的行（reusable=false）才能由他修改。
+-- user_shortlinks 只表示“在我的列表里”：加入匿名创建的共享行的用户不能改掉别人拿到的短码。
+ALTER TABLE shortlinks ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES users(id);
+
+-- 私有行只属于 owner；早于 034 创建的私有行 reusable 仍是默认值，一并修正。
+-- 历史共享行无法确认短码是否已发给别人，不回填，拥有者需要修改时改建私有短链。
+UPDATE shortlinks SET created_by=owner_id, reusable=false WHERE owner_id IS NOT NULL AND created_by IS NULL;
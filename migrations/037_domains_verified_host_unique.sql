@@ -0,0 +1,7 @@
+-- 域名只在校验通过后才独占：未校验的认领不再占住 host，别人（真正控制 DNS 的人）也能认领并校验。
+-- 同一个 host 可以有多条未校验的认领，但同一用户只能有一条；已校验的 host 全局唯一，
+-- 多个认领者同时校验时由这个唯一索引决定谁先到，后到的失败。
+ALTER TABLE domains DROP CONSTRAINT IF EXISTS domains_host_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_domains_owner_host ON domains(owner_id, host);
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_domains_verified_host ON domains(host) WHERE verified_at IS NOT NULL;
@@ -2,14 +2,24 @@ package test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strconv"
 	"testing"
 	"time"
 
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
 	slcache "day.local/internal/app/shortlink/cache"
+	"day.local/internal/app/shortlink/httpapi"
 	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/app/shortlink/stats"
 	"day.local/internal/platform/db"
+	"day.local/internal/platform/geoip"
+	"day.local/internal/platform/qrcode"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/redis/go-redis/v9"
 )
@@ -60,7 +70,7 @@ func setupPostgresAndRedis(t *testing.T) (*repo.ShortlinksRepo, *pgxpool.Pool, *
 	}
 
 	cache := slcache.NewShortlinkCache(redisClient, nil)
-	slRepo := repo.NewShortlinksRepo(dbPool, cache, nil)
+	slRepo := repo.NewShortlinksRepo(dbPool, cache, nil, nil)
 
 	cleanup := func() {
 		_ = redisClient.Close()
@@ -77,22 +87,24 @@ func TestRedisCache_ResolveCachesAndDisableInvalidates(t *testing.T) {
 	defer cancel()
 
 	url1 := "https://example.com/cache-test-" + strconv.FormatInt(time.Now().UnixNano(), 10)
-	code, err := slRepo.Create(ctx, url1, nil)
+	link, err := slRepo.Create(ctx, shortlink.Shortlink{URL: url1}, repo.LinkMeta{}, nil)
 	if err != nil {
 		t.Fatalf("Create: %v", err)
 	}
+	code := link.Code
 
 	// 1) 第一次 Resolve：走 DB -> 写缓存
-	got1 := slRepo.Resolve(ctx, code)
-	if got1 != url1 {
-		t.Fatalf("Resolve#1: got %q, want %q", got1, url1)
+	got1, err := slRepo.Resolve(ctx, code)
+	if err != nil || got1.URL != url1 {
+		t.Fatalf("Resolve#1: got %q (err=%v), want %q", got1.URL, err, url1)
 	}
 	val1, err := redisClient.Get(ctx, "sl:"+code).Result()
 	if err != nil {
 		t.Fatalf("redis GET: %v", err)
 	}
-	if val1 != url1 {
-		t.Fatalf("redis value: got %q, want %q", val1, url1)
+	var cached1 shortlink.Shortlink
+	if err := json.Unmarshal([]byte(val1), &cached1); err != nil || cached1.URL != url1 {
+		t.Fatalf("redis value: got %q (err=%v), want url %q", val1, err, url1)
 	}
 
 	// 2) 修改 DB 中的 url，再次 Resolve 应优先命中缓存（返回旧值）
@@ -100,22 +112,21 @@ func TestRedisCache_ResolveCachesAndDisableInvalidates(t *testing.T) {
 	if _, err := dbPool.Exec(ctx, "UPDATE shortlinks SET url=$1 WHERE code=$2", url2, code); err != nil {
 		t.Fatalf("update db url: %v", err)
 	}
-	got2 := slRepo.Resolve(ctx, code)
-	if got2 != url1 {
-		t.Fatalf("Resolve#2 (expect cache hit): got %q, want %q", got2, url1)
+	got2, err := slRepo.Resolve(ctx, code)
+	if err != nil || got2.URL != url1 {
+		t.Fatalf("Resolve#2 (expect cache hit): got %q (err=%v), want %q", got2.URL, err, url1)
 	}
 
 	// 3) 禁用后必须删缓存，且 Resolve 返回 not found
-	if err := slRepo.DisableByCode(ctx, code); err != nil {
+	if err := slRepo.DisableByCode(ctx, code, repo.StateChange{Admin: true}); err != nil {
 		t.Fatalf("DisableByCode: %v", err)
 	}
 	_, err = redisClient.Get(ctx, "sl:"+code).Result()
 	if err == nil {
 		t.Fatalf("expected cache key to be deleted after disable")
 	}
-	got3 := slRepo.Resolve(ctx, code)
-	if got3 != "" {
-		t.Fatalf("Resolve#3 after disable: got %q, want empty", got3)
+	if _, err := slRepo.Resolve(ctx, code); !errors.Is(err, repo.ErrShortlinkNotFound) {
+		t.Fatalf("Resolve#3 after disable: got err=%v, want ErrShortlinkNotFound", err)
 	}
 }
 
@@ -144,19 +155,141 @@ func TestRedisCache_NegativeCacheTTLAndCustomCodeOverrides(t *testing.T) {
 
 	// 2) 创建同名自定义短码后，应覆盖负缓存
 	url := "https://example.com/custom-code-override-" + strconv.FormatInt(time.Now().UnixNano(), 10)
-	gotCode, err := slRepo.CreateWithCustomCode(ctx, url, customCode, nil)
+	got, err := slRepo.CreateWithCustomCode(ctx, shortlink.Shortlink{URL: url, Code: customCode}, repo.LinkMeta{}, nil)
 	if err != nil {
 		t.Fatalf("CreateWithCustomCode: %v", err)
 	}
-	if gotCode != customCode {
-		t.Fatalf("code mismatch: got %q, want %q", gotCode, customCode)
+	if got.Code != customCode {
+		t.Fatalf("code mismatch: got %q, want %q", got.Code, customCode)
 	}
 
 	val2, err := redisClient.Get(ctx, "sl:"+customCode).Result()
 	if err != nil {
 		t.Fatalf("redis GET after override: %v", err)
 	}
-	if val2 != url {
-		t.Fatalf("expected cached url after override, got %q, want %q", val2, url)
+	var cached2 shortlink.Shortlink
+	if err := json.Unmarshal([]byte(val2), &cached2); err != nil || cached2.URL != url {
+		t.Fatalf("expected cached url after override, got %q (err=%v), want %q", val2, err, url)
+	}
+}
+
+func TestRedisCache_SingleUseShortlink(t *testing.T) {
+	slRepo, _, redisClient, cleanup := setupPostgresAndRedis(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	url := "https://example.com/single-use-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	link, err := slRepo.Create(ctx, shortlink.Shortlink{URL: url, MaxClicks: 1}, repo.LinkMeta{}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_ = redisClient.Del(ctx, "slc:"+link.Code).Err()
+
+	if err := slRepo.TakeClick(ctx, link); err != nil {
+		t.Fatalf("first TakeClick: %v", err)
+	}
+	if err := slRepo.TakeClick(ctx, link); !errors.Is(err, repo.ErrClickLimitReached) {
+		t.Fatalf("second TakeClick: got %v, want ErrClickLimitReached", err)
+	}
+
+	// 用完名额后自动停用
+	if _, err := slRepo.Resolve(ctx, link.Code); !errors.Is(err, repo.ErrShortlinkNotFound) {
+		t.Fatalf("Resolve after exhausted: got %v, want ErrShortlinkNotFound", err)
+	}
+	if err := slRepo.EnableByCode(ctx, link.Code, repo.StateChange{Admin: true}); !errors.Is(err, repo.ErrClickLimitReached) {
+		t.Fatalf("EnableByCode after exhausted: got %v, want ErrClickLimitReached", err)
+	}
+}
+
+// 从 Redis 回填本地缓存时沿用 L2 的剩余 TTL：负缓存不会在别的实例上停留 5 分钟，带过期时间的短链也不会活过 expires_at
+func TestRedisCache_L1BackfillKeepsL2TTL(t *testing.T) {
+	_, _, redisClient, cleanup := setupPostgresAndRedis(t)
+	defer cleanup()
+
+	// 本地负缓存 TTL 调短，免得测试等上 10 秒
+	local, err := slcache.NewLocalCacheWithTTL(1000, 1<<20, time.Minute, 200*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := slcache.NewShortlinkCache(redisClient, local) // 另一个实例
+	defer other.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 负缓存：本实例看到 __nil__ 后，别的实例创建了这个短码
+	missing := "N" + strconv.FormatInt(time.Now().UnixNano()%1_000_000_000_000, 36)
+	redisClient.Set(ctx, "sl:"+missing, "__nil__", 30*time.Second)
+	if e, _ := other.Get(ctx, missing); e == nil || !e.NotFound {
+		t.Fatalf("expected negative hit, got %+v", e)
+	}
+	data, _ := json.Marshal(shortlink.Shortlink{Code: missing, URL: "https://example.com/created-elsewhere"})
+	redisClient.Set(ctx, "sl:"+missing, data, time.Minute)
+
+	// 即将过期的短链：L2 只剩 500 毫秒
+	soon := "S" + strconv.FormatInt(time.Now().UnixNano()%1_000_000_000_000, 36)
+	data, _ = json.Marshal(shortlink.Shortlink{Code: soon, URL: "https://example.com/expiring"})
+	redisClient.Set(ctx, "sl:"+soon, data, 500*time.Millisecond)
+	if e, _ := other.Get(ctx, soon); e == nil || e.Link == nil {
+		t.Fatalf("expected link hit, got %+v", e)
+	}
+
+	time.Sleep(time.Second)
+	if e, _ := other.Get(ctx, soon); e != nil {
+		t.Errorf("L1 outlived L2 TTL: %+v", e)
+	}
+	if e, _ := other.Get(ctx, missing); e == nil || e.Link == nil {
+		t.Errorf("negative entry should have left L1, got %+v", e)
+	}
+}
+
+// 命中规则的目标缺少路径段时不跳转，也不消耗一次性链接的名额
+func TestRedisCache_FailedExpansionDoesNotTakeClick(t *testing.T) {
+	slRepo, _, redisClient, cleanup := setupPostgresAndRedis(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	base := "https://example.com/once-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	link, err := slRepo.Create(ctx, shortlink.Shortlink{
+		URL:       base + "/{1}",
+		MaxClicks: 1,
+		Rules:     []shortlink.RedirectRule{{Languages: []string{"fr"}, URL: base + "/fr/{1}/{2}"}},
+	}, repo.LinkMeta{}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	_ = redisClient.Del(ctx, "slc:"+link.Code).Err()
+
+	qrRenderer, err := qrcode.NewRenderer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := stats.NewChannelCollector(100)
+	defer collector.Close()
+	r := gee.New()
+	httpapi.RegisterPublicRoutes(r, slRepo, collector, nil, shortlink.NewUnlockSigner("test-secret-key", time.Minute), geoip.NewHeaderResolver("CF-IPCountry"), qrRenderer)
+
+	visit := func(lang string) int {
+		req := httptest.NewRequest(http.MethodGet, "/"+link.Code+"/a", nil)
+		req.Header.Set("Accept-Language", lang)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := visit("fr"); code == http.StatusFound {
+		t.Fatalf("rule needing two segments should not redirect")
+	}
+	if n, _ := redisClient.Get(ctx, "slc:"+link.Code).Int64(); n != 0 {
+		t.Fatalf("failed expansion took a click: counter=%d", n)
+	}
+	if code := visit("en"); code != http.StatusFound {
+		t.Fatalf("first real visit: got %d, want 302", code)
+	}
+	if code := visit("en"); code == http.StatusFound {
+		t.Errorf("second visit should not redirect")
 	}
 }
@@ -13,12 +13,18 @@ import (
 
 	"day.local/gee"
 	"day.local/gee/middleware"
+	"day.local/internal/app/shortlink"
 	"day.local/internal/app/shortlink/httpapi"
 	"day.local/internal/app/shortlink/repo"
 	"day.local/internal/app/shortlink/stats"
 	"day.local/internal/platform/auth"
 	"day.local/internal/platform/db"
+	"day.local/internal/platform/dnsverify"
+	"day.local/internal/platform/geoip"
 	"day.local/internal/platform/httpmiddleware"
+	"day.local/internal/platform/pagemeta"
+	"day.local/internal/platform/qrcode"
+	"day.local/internal/platform/urlcheck"
 )
 
 // setupTestServer creates a test server with all shortlink routes
@@ -46,7 +52,7 @@ func setupTestServer(t *testing.T) (*gee.Engine, *repo.ShortlinksRepo, *repo.Use
 	}
 
 	// Create repos
-	slRepo := repo.NewShortlinksRepo(dbPool, nil, nil)
+	slRepo := repo.NewShortlinksRepo(dbPool, nil, nil, nil)
 	usersRepo := repo.NewUsersRepo(dbPool)
 
 	// Create JWT service
@@ -61,10 +67,16 @@ func setupTestServer(t *testing.T) (*gee.Engine, *repo.ShortlinksRepo, *repo.Use
 
 	// Register routes
 	api := r.Group("/api/v1")
-	httpapi.RegisterAPIRoutes(api, slRepo, usersRepo, ts, nil)
+	sb := newFakeSafeBrowsing(t)
+	screener := urlcheck.NewScreener(urlcheck.NewSafeBrowsing("test-key", sb.URL, sb.Client()), false)
+	httpapi.RegisterAPIRoutes(api, slRepo, usersRepo, ts, nil, dnsverify.NewTXTVerifier(testDNS), screener, pagemeta.NewFetcher(http.DefaultClient))
+	qrRenderer, err := qrcode.NewRenderer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	collector := stats.NewChannelCollector(100)
 	t.Cleanup(func() { collector.Close() })
-	httpapi.RegisterPublicRoutes(r, slRepo, collector, nil)
+	httpapi.RegisterPublicRoutes(r, slRepo, collector, nil, shortlink.NewUnlockSigner("test-secret-key", time.Minute), geoip.NewHeaderResolver("CF-IPCountry"), qrRenderer)
 
 	// Add healthz for route priority test
 	r.GET("/healthz", func(ctx *gee.Context) {
@@ -0,0 +1,85 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/httpapi"
+)
+
+func TestSuggestCodes(t *testing.T) {
+	got := shortlink.SuggestCodes("launch", "https://docs.github.com/en/a", "The Product Launch Notes")
+	want := []string{"launchGithub", "githubLaunch", "ProductLaunch", "ProductLaunchNotes", "launchProduct", "githubProduct", "launch2"}
+	if len(got) < len(want) || !slices.Equal(got[:len(want)], want) {
+		t.Errorf("suggestions = %v, want prefix %v", got, want)
+	}
+	for _, c := range got {
+		if c == "launch" || shortlink.ValidateCode(c) != nil {
+			t.Errorf("bad suggestion %q", c)
+		}
+	}
+
+	if got := shortlink.SuggestCodes("news", "https://www.bbc.co.uk/", ""); len(got) == 0 || got[0] != "newsBbc" {
+		t.Errorf("co.uk suggestions = %v", got)
+	}
+	// 保留字也给出候选；没有地址与标题时只剩数字后缀
+	if got := shortlink.SuggestCodes("api", "", ""); len(got) == 0 || got[0] != "api2" {
+		t.Errorf("reserved suggestions = %v", got)
+	}
+}
+
+func TestAvailabilityEndpoint(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	code := "avail" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	check := func(q url.Values) (int, httpapi.AvailabilityResponse) {
+		rec := doJSON(r, http.MethodGet, "/api/v1/shortlinks/availability?"+q.Encode(), "", nil)
+		var body httpapi.AvailabilityResponse
+		json.NewDecoder(rec.Body).Decode(&body)
+		return rec.Code, body
+	}
+
+	if status, body := check(url.Values{"code": {code}}); status != http.StatusOK || !body.Available {
+		t.Fatalf("fresh code: %d %+v", status, body)
+	}
+	if status, body := check(url.Values{"code": {"a-b"}}); status != http.StatusOK || body.Available || body.Reason != "invalid" {
+		t.Errorf("invalid code: %d %+v", status, body)
+	}
+	for _, reserved := range []string{"healthz", "Availability"} {
+		if _, body := check(url.Values{"code": {reserved}}); body.Available || body.Reason != "reserved" || len(body.Suggestions) == 0 {
+			t.Errorf("reserved code %q: %+v", reserved, body)
+		}
+	}
+	if status, _ := check(url.Values{}); status != http.StatusBadRequest {
+		t.Errorf("missing code: %d", status)
+	}
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": "https://example.com/avail/" + code, "code": code})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	_, body := check(url.Values{"code": {code}, "url": {"https://example.com/x"}, "title": {"Spring Sale"}})
+	if body.Available || body.Reason != "taken" {
+		t.Fatalf("taken code: %+v", body)
+	}
+	if len(body.Suggestions) == 0 || len(body.Suggestions) > 5 || body.Suggestions[0] != code+"Example" {
+		t.Errorf("suggestions = %v", body.Suggestions)
+	}
+	// 推荐的候选都可用
+	for _, s := range body.Suggestions {
+		if _, b := check(url.Values{"code": {s}}); !b.Available {
+			t.Errorf("suggested %q is not available", s)
+		}
+	}
+
+	if status, _ := check(url.Values{"code": {code}, "domain": {"go.example.com"}}); status != http.StatusUnauthorized {
+		t.Errorf("domain without login: %d", status)
+	}
+}
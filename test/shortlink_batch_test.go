@@ -0,0 +1,68 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestCreateShortlinksBatch(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	taken := "bt" + suffix[len(suffix)-8:]
+	createCustom := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": "https://example.com/taken-" + suffix, "code": taken,
+	})
+	if createCustom.Code != http.StatusOK {
+		t.Fatalf("create custom: %d, body=%s", createCustom.Code, createCustom.Body.String())
+	}
+
+	items := []map[string]any{
+		{"url": "https://example.com/news-" + suffix + "-0"},
+		{"url": "https://example.com/news-" + suffix + "-1", "expire_in": "7d"},
+		{"url": "ftp://bad"},
+		{"url": "https://example.com/news-" + suffix + "-3", "code": taken},
+	}
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks:batch", token, map[string]any{"items": items})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("batch: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Created int `json:"created"`
+		Failed  int `json:"failed"`
+		Results []struct {
+			Index  int    `json:"index"`
+			Code   string `json:"code"`
+			Status int    `json:"status"`
+		} `json:"results"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Created != 2 || resp.Failed != 2 || len(resp.Results) != len(items) {
+		t.Fatalf("unexpected summary: %+v", resp)
+	}
+	wantStatus := []int{http.StatusOK, http.StatusOK, http.StatusBadRequest, http.StatusConflict}
+	for i, res := range resp.Results {
+		if res.Index != i || res.Status != wantStatus[i] {
+			t.Errorf("item %d: got index=%d status=%d, want status %d", i, res.Index, res.Status, wantStatus[i])
+		}
+	}
+
+	// 成功的条目立刻可以跳转
+	redirect := doJSON(r, http.MethodGet, "/"+resp.Results[0].Code, "", nil)
+	if got := redirect.Header().Get("Location"); got != "https://example.com/news-"+suffix+"-0" {
+		t.Errorf("redirect after batch: got %q", got)
+	}
+
+	if rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks:batch", "", map[string]any{"items": items}); rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous batch: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	if rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks:batch", token, map[string]any{"items": []any{}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty batch: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestSqidsGenerator(t *testing.T) {
+	g, err := shortlink.NewCodeGenerator(shortlink.CodeGeneratorOptions{SqidsMinLength: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := g.Generate(42, 0)
+	b, _ := g.Generate(42, 0)
+	retry, _ := g.Generate(42, 1)
+	if a != b || len(a) < 3 {
+		t.Errorf("sqids should be deterministic: %q %q", a, b)
+	}
+	if retry == a {
+		t.Errorf("retry should give a different code, got %q twice", a)
+	}
+
+	custom, err := shortlink.NewCodeGenerator(shortlink.CodeGeneratorOptions{Kind: "sqids", SqidsAlphabet: "abcdefghij", SqidsMinLength: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := custom.Generate(42, 0)
+	if !regexp.MustCompile(`^[a-j]{10,}$`).MatchString(code) {
+		t.Errorf("custom alphabet/min length not applied: %q", code)
+	}
+}
+
+func TestRandomGenerator(t *testing.T) {
+	g, err := shortlink.NewCodeGenerator(shortlink.CodeGeneratorOptions{Kind: shortlink.CodeGeneratorRandom, RandomLength: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	re := regexp.MustCompile(`^[A-Za-z0-9]{10}$`)
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		code, err := g.Generate(1, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !re.MatchString(code) {
+			t.Fatalf("bad random code %q", code)
+		}
+		if seen[code] {
+			t.Fatalf("duplicate random code %q", code)
+		}
+		seen[code] = true
+	}
+}
+
+func TestWordsGenerator(t *testing.T) {
+	g, err := shortlink.NewCodeGenerator(shortlink.CodeGeneratorOptions{Kind: shortlink.CodeGeneratorWords})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := regexp.MustCompile(`^[A-Z][a-z]+[A-Z][a-z]+$`)
+	withDigits := regexp.MustCompile(`^[A-Z][a-z]+[A-Z][a-z]+\d\d$`)
+	for i := 0; i < 100; i++ {
+		code, _ := g.Generate(1, 0)
+		if !plain.MatchString(code) || shortlink.ValidateCode(code) != nil {
+			t.Fatalf("bad word code %q", code)
+		}
+		// 多次冲突后追加两位数字
+		code, _ = g.Generate(1, 2)
+		if !withDigits.MatchString(code) {
+			t.Fatalf("bad retried word code %q", code)
+		}
+	}
+}
+
+func TestNewCodeGeneratorInvalid(t *testing.T) {
+	cases := map[string]shortlink.CodeGeneratorOptions{
+		"unknown kind":        {Kind: "uuid"},
+		"alphabet symbols":    {SqidsAlphabet: "abc-def_ghij"},
+		"min length too long": {SqidsMinLength: 33},
+		"alphabet repeats":    {SqidsAlphabet: strings.Repeat("a", 10)},
+		"random too short":    {Kind: shortlink.CodeGeneratorRandom, RandomLength: 4},
+		"random too long":     {Kind: shortlink.CodeGeneratorRandom, RandomLength: 64},
+	}
+	for name, opts := range cases {
+		if _, err := shortlink.NewCodeGenerator(opts); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestNormalizeDeepLink(t *testing.T) {
+	d, err := shortlink.NormalizeDeepLink(&shortlink.DeepLink{})
+	if err != nil || d != nil {
+		t.Fatalf("empty deep link: got %v, %v; want nil, nil", d, err)
+	}
+
+	invalid := []shortlink.DeepLink{
+		{IOS: "javascript:alert(1)"},
+		{Android: "data:text/html,hi"},
+		{IOSStore: "https://apps.apple.com/app/id1"}, // 只有商店没有 App 地址
+		{IOS: "https://"},
+		{IOS: "not a url"},
+	}
+	for i, d := range invalid {
+		if _, err := shortlink.NormalizeDeepLink(&d); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+
+	d, err = shortlink.NormalizeDeepLink(&shortlink.DeepLink{
+		IOS:          " myapp://item/1 ",
+		IOSStore:     "https://apps.apple.com/app/id1",
+		Android:      "https://app.example.com/item/1",
+		AndroidStore: "market://details?id=com.example",
+	})
+	if err != nil {
+		t.Fatalf("NormalizeDeepLink: %v", err)
+	}
+	if app, ok := d.Target(shortlink.OSIOS); !ok || app.Direct || app.AppURL != "myapp://item/1" {
+		t.Errorf("ios target: %+v, %v", app, ok)
+	}
+	if app, ok := d.Target(shortlink.OSAndroid); !ok || !app.Direct {
+		t.Errorf("android target: %+v, %v", app, ok)
+	}
+	if _, ok := d.Target(shortlink.OSWindows); ok {
+		t.Error("desktop visitors should not get an app target")
+	}
+	// 只有 http(s) 地址算目的地，自定义 scheme 与 market:// 不参与策略与信誉检查
+	got := shortlink.Shortlink{URL: "https://example.com/", DeepLink: d}.Destinations()
+	want := []string{"https://example.com/", "https://apps.apple.com/app/id1", "https://app.example.com/item/1"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Destinations() = %v, want %v", got, want)
+	}
+}
+
+func TestDeepLinkRedirect(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	base := "https://example.com/app-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": base,
+		"deep_link": map[string]any{
+			"ios":           "myapp://item/1",
+			"ios_store":     "https://apps.apple.com/app/id1",
+			"android":       "https://app.example.com/item/1",
+			"android_store": "https://play.google.com/store/apps/details?id=com.example",
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	redirect := func(ua string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+created.Code, nil)
+		req.Header.Set("User-Agent", ua)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// iOS 自定义 scheme：落地页唤起，失败去商店
+	rec = redirect(uaIPhone)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"myapp://item/1"`) ||
+		!strings.Contains(rec.Body.String(), "https://apps.apple.com/app/id1") {
+		t.Fatalf("ios interstitial: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	// Android App Link 是 https，直接 302
+	if rec := redirect(uaAndroid); rec.Code != http.StatusFound || rec.Header().Get("Location") != "https://app.example.com/item/1" {
+		t.Errorf("android: %d, Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+	// 桌面端走网页
+	rec = redirect(uaDesktop)
+	if rec.Header().Get("Location") != base {
+		t.Errorf("desktop: Location=%q, want %q", rec.Header().Get("Location"), base)
+	}
+	if cc := rec.Header().Get("Cache-Control"); cc != "private, no-store" {
+		t.Errorf("deep link redirect must not be shared-cached, got Cache-Control %q", cc)
+	}
+
+	// 关闭后 iOS 也走网页
+	if rec := doJSON(r, http.MethodPut, "/api/v1/users/shortlinks/"+created.Code+"/deeplink", token, map[string]any{}); rec.Code != http.StatusOK {
+		t.Fatalf("clear deep link: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if got := redirect(uaIPhone).Header().Get("Location"); got != base {
+		t.Errorf("after clearing deep link: got %q, want %q", got, base)
+	}
+}
@@ -0,0 +1,186 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"day.local/internal/platform/dnsverify"
+)
+
+// fakeTXT 是测试用的 DNS：没有记录的名字返回 NXDOMAIN
+type fakeTXT struct {
+	mu      sync.Mutex
+	records map[string][]string
+}
+
+func (f *fakeTXT) set(name, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.records == nil {
+		f.records = map[string][]string{}
+	}
+	f.records[name] = append(f.records[name], value)
+}
+
+func (f *fakeTXT) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if r, ok := f.records[name]; ok {
+		return r, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+// testDNS 供 setupTestServer 的域名校验使用
+var testDNS = &fakeTXT{}
+
+func TestTXTVerifier(t *testing.T) {
+	dns := &fakeTXT{}
+	v := dnsverify.NewTXTVerifier(dns)
+
+	if err := v.Verify(context.Background(), "go.example.com", "tok"); !errors.Is(err, dnsverify.ErrNotVerified) {
+		t.Errorf("missing record: want ErrNotVerified, got %v", err)
+	}
+	dns.set(dnsverify.RecordName("go.example.com"), "shortlink-verification=other")
+	if err := v.Verify(context.Background(), "go.example.com", "tok"); !errors.Is(err, dnsverify.ErrNotVerified) {
+		t.Errorf("wrong value: want ErrNotVerified, got %v", err)
+	}
+	dns.set(dnsverify.RecordName("go.example.com"), dnsverify.RecordValue("tok"))
+	if err := v.Verify(context.Background(), "go.example.com", "tok"); err != nil {
+		t.Errorf("matching record: %v", err)
+	}
+}
+
+func TestDomainHost(t *testing.T) {
+	if got := shortlink.NormalizeHost("Go.Example.COM.:8080"); got != "go.example.com" {
+		t.Errorf("NormalizeHost = %q", got)
+	}
+	for _, h := range []string{"go.example.com", "a-b.co"} {
+		if err := shortlink.ValidateDomainHost(h); err != nil {
+			t.Errorf("%q: %v", h, err)
+		}
+	}
+	for _, h := range []string{"localhost", "127.0.0.1", "-a.example.com", "a..com", "a_b.example.com"} {
+		if err := shortlink.ValidateDomainHost(h); err == nil {
+			t.Errorf("%q should be rejected", h)
+		}
+	}
+}
+
+func TestCustomDomainRedirect(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	host := "d" + strconv.FormatInt(time.Now().UnixNano(), 10) + ".example.com"
+	dest := "https://example.com/" + host
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/users/domains", token, map[string]any{"host": host})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create domain: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var domain struct {
+		TXTName  string `json:"txt_name"`
+		TXTValue string `json:"txt_value"`
+	}
+	json.NewDecoder(rec.Body).Decode(&domain)
+
+	// 校验通过前不能建链，校验失败返回 409
+	if rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": dest, "domain": host}); rec.Code != http.StatusConflict {
+		t.Errorf("unverified domain: want 409, got %d", rec.Code)
+	}
+	if rec := doJSON(r, http.MethodPost, "/api/v1/users/domains/"+host+"/verify", token, nil); rec.Code != http.StatusConflict {
+		t.Errorf("verify without record: want 409, got %d", rec.Code)
+	}
+	testDNS.set(domain.TXTName, domain.TXTValue)
+	if rec := doJSON(r, http.MethodPost, "/api/v1/users/domains/"+host+"/verify", token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("verify: %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": dest, "domain": host, "code": "docs"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create link: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code     string `json:"code"`
+		ShortURL string `json:"short_url"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.ShortURL != "http://"+host+"/docs" {
+		t.Errorf("short_url = %q", created.ShortURL)
+	}
+
+	get := func(host, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := get(host, "/docs"); rec.Code != http.StatusFound || rec.Header().Get("Location") != dest {
+		t.Errorf("custom domain redirect: %d, Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+	// 同一个 key 在默认域名上不存在；内部短码也不能从默认域名绕过去
+	if rec := get("example.com", "/docs"); rec.Code == http.StatusFound && rec.Header().Get("Location") == dest {
+		t.Error("domain key must not resolve on the default domain")
+	}
+	if rec := get("example.com", "/"+created.Code); rec.Code != http.StatusNotFound {
+		t.Errorf("internal code on default domain: want 404, got %d", rec.Code)
+	}
+
+	// 域名下还有短链时不能删除
+	if rec := doJSON(r, http.MethodDelete, "/api/v1/users/domains/"+host, token, nil); rec.Code != http.StatusConflict {
+		t.Errorf("delete domain in use: want 409, got %d", rec.Code)
+	}
+}
+
+// 未校验的认领不占住域名：先认领的人没有 DNS 控制权时，真正的拥有者仍能认领并校验；
+// 校验通过后其他人的认领无法再校验，也不能新认领
+func TestDomainClaimNotExclusiveUntilVerified(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	squatter := newTestUserToken(t, usersRepo, ts)
+	owner := newTestUserToken(t, usersRepo, ts)
+	host := "c" + strconv.FormatInt(time.Now().UnixNano(), 10) + ".example.com"
+
+	claim := func(token string) (int, string, string) {
+		rec := doJSON(r, http.MethodPost, "/api/v1/users/domains", token, map[string]any{"host": host})
+		var d struct {
+			TXTName  string `json:"txt_name"`
+			TXTValue string `json:"txt_value"`
+		}
+		json.NewDecoder(rec.Body).Decode(&d)
+		return rec.Code, d.TXTName, d.TXTValue
+	}
+	code, squatName, squatValue := claim(squatter)
+	if code != http.StatusOK {
+		t.Fatalf("first claim: %d", code)
+	}
+	if code, _, _ := claim(squatter); code != http.StatusConflict {
+		t.Errorf("duplicate claim by the same user: want 409, got %d", code)
+	}
+	code, name, value := claim(owner)
+	if code != http.StatusOK {
+		t.Fatalf("claim after an unverified claim: want 200, got %d", code)
+	}
+	testDNS.set(name, value)
+	if rec := doJSON(r, http.MethodPost, "/api/v1/users/domains/"+host+"/verify", owner, nil); rec.Code != http.StatusOK {
+		t.Fatalf("verify: %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	// 即使对方的 TXT 记录也在（例如并发校验时双方都通过了 DNS 检查），也只有先提交的一方生效
+	testDNS.set(squatName, squatValue)
+	if rec := doJSON(r, http.MethodPost, "/api/v1/users/domains/"+host+"/verify", squatter, nil); rec.Code != http.StatusConflict {
+		t.Errorf("verify a host someone else verified: want 409, got %d", rec.Code)
+	}
+	other := newTestUserToken(t, usersRepo, ts)
+	if code, _, _ := claim(other); code != http.StatusConflict {
+		t.Errorf("claim a verified host: want 409, got %d", code)
+	}
+}
@@ -0,0 +1,209 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+)
+
+func TestParseExpireIn(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"90m", 90 * time.Minute, false},
+		{"24h", 24 * time.Hour, false},
+		{"7d", 7 * 24 * time.Hour, false},
+		{" 1d ", 24 * time.Hour, false},
+		{"", 0, true},
+		{"0s", 0, true},
+		{"-1h", 0, true},
+		{"0d", 0, true},
+		{"abc", 0, true},
+		{"99999d", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := shortlink.ParseExpireIn(tt.raw)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShortlinkExpired(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Second)
+	future := now.Add(time.Hour)
+
+	if (shortlink.Shortlink{}).Expired(now) {
+		t.Fatalf("link without expires_at must never expire")
+	}
+	if !(shortlink.Shortlink{ExpiresAt: &past}).Expired(now) {
+		t.Fatalf("link with past expires_at must be expired")
+	}
+	if !(shortlink.Shortlink{ExpiresAt: &now}).Expired(now) {
+		t.Fatalf("link must be expired exactly at expires_at")
+	}
+	if (shortlink.Shortlink{ExpiresAt: &future}).Expired(now) {
+		t.Fatalf("link with future expires_at must not be expired")
+	}
+}
+
+func TestCreateShortlinkWithExpireIn(t *testing.T) {
+	r, _, _, _ := setupTestServer(t)
+
+	body, _ := json.Marshal(map[string]string{
+		"url":       "https://example.com/expire-in-" + strconv.FormatInt(time.Now().UnixNano(), 10),
+		"expire_in": "1h",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create failed: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Code      string     `json:"code"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.ExpiresAt == nil || resp.ExpiresAt.Before(time.Now().Add(59*time.Minute)) {
+		t.Fatalf("unexpected expires_at: %v", resp.ExpiresAt)
+	}
+
+	redirectReq := httptest.NewRequest(http.MethodGet, "/"+resp.Code, nil)
+	redirectRec := httptest.NewRecorder()
+	r.ServeHTTP(redirectRec, redirectReq)
+	if redirectRec.Code != http.StatusFound {
+		t.Fatalf("redirect before expiry: got %d, want %d", redirectRec.Code, http.StatusFound)
+	}
+}
+
+// 带过期时间的短链各占一行：别人缩短同一 url（不过期或更晚过期）不会改变它的过期时间
+func TestExpiringShortlinkNotShared(t *testing.T) {
+	r, slRepo, _, _ := setupTestServer(t)
+
+	url := "https://example.com/campaign-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	create := func(body map[string]any) (string, *time.Time) {
+		rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", "", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("create %v: %d, body=%s", body, rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Code      string     `json:"code"`
+			ExpiresAt *time.Time `json:"expires_at"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp.Code, resp.ExpiresAt
+	}
+
+	campaign, expiresAt := create(map[string]any{"url": url, "expire_in": "1h"})
+	plain, plainExpiry := create(map[string]any{"url": url})
+	later, _ := create(map[string]any{"url": url, "expire_in": "7d"})
+	again, _ := create(map[string]any{"url": url})
+	if plain == campaign || later == campaign || plain == later {
+		t.Fatalf("expiring links must get their own codes: %s %s %s", campaign, plain, later)
+	}
+	if plainExpiry != nil || again != plain {
+		t.Errorf("plain creates should still share one row: %s %s (expires %v)", plain, again, plainExpiry)
+	}
+
+	link, err := slRepo.Resolve(context.Background(), campaign)
+	if err != nil {
+		t.Fatalf("resolve campaign: %v", err)
+	}
+	if link.ExpiresAt == nil || !link.ExpiresAt.Equal(*expiresAt) {
+		t.Errorf("campaign expiry changed: got %v, want %v", link.ExpiresAt, expiresAt)
+	}
+}
+
+func TestCreateShortlinkWithInvalidExpiry(t *testing.T) {
+	r, _, _, _ := setupTestServer(t)
+
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"bad expire_in", map[string]any{"url": "https://example.com/x", "expire_in": "soon"}},
+		{"past expires_at", map[string]any{"url": "https://example.com/x", "expires_at": time.Now().Add(-time.Hour)}},
+		{"both set", map[string]any{"url": "https://example.com/x", "expire_in": "1h", "expires_at": time.Now().Add(time.Hour)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, _ := json.Marshal(tt.body)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("status: got %d, want %d, body=%s", rec.Code, http.StatusBadRequest, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestRedirectExpiredShortlinkReturnsGone(t *testing.T) {
+	r, slRepo, _, _ := setupTestServer(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// 直接走 repo 写入一个已过期的短链（HTTP 层不允许传过去的时间）
+	past := time.Now().Add(-time.Minute)
+	code := "E" + strconv.FormatInt(time.Now().UnixNano()%1_000_000_000_000, 36)
+	url := "https://example.com/expired-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	if _, err := slRepo.CreateWithCustomCode(ctx, shortlink.Shortlink{URL: url, Code: code, ExpiresAt: &past}, repo.LinkMeta{}, nil); err != nil {
+		t.Fatalf("CreateWithCustomCode: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusGone {
+		t.Fatalf("redirect expired: got %d, want %d", rec.Code, http.StatusGone)
+	}
+
+	// 过期行超过保留期后会被 sweeper 清理
+	codes, err := slRepo.PurgeExpired(ctx, time.Now(), 1000)
+	if err != nil {
+		t.Fatalf("PurgeExpired: %v", err)
+	}
+	found := false
+	for _, c := range codes {
+		if c == code {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected %s to be purged, got %v", code, codes)
+	}
+
+	rec2 := httptest.NewRecorder()
+	r.ServeHTTP(rec2, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if rec2.Code != http.StatusNotFound {
+		t.Fatalf("redirect purged: got %d, want %d", rec2.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/probe"
+	"day.local/internal/platform/safehttp"
+)
+
+func TestProbe(t *testing.T) {
+	handler := func(head, get int) *httptest.Server {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead {
+				w.WriteHeader(head)
+				return
+			}
+			w.WriteHeader(get)
+		}))
+		t.Cleanup(srv.Close)
+		return srv
+	}
+	p := probe.NewProber(http.DefaultClient)
+
+	if res := p.Probe(context.Background(), handler(200, 200).URL); !res.OK() || res.StatusCode != 200 {
+		t.Errorf("healthy: %+v", res)
+	}
+	// 不支持 HEAD 的站点用 GET 确认，不算故障
+	if res := p.Probe(context.Background(), handler(405, 200).URL); !res.OK() {
+		t.Errorf("HEAD 405 / GET 200 should be healthy: %+v", res)
+	}
+	if res := p.Probe(context.Background(), handler(503, 503).URL); res.OK() || res.StatusCode != 503 {
+		t.Errorf("503: %+v", res)
+	}
+	down := handler(200, 200)
+	down.Close()
+	if res := p.Probe(context.Background(), down.URL); res.OK() || res.Err == nil || res.StatusCode != 0 {
+		t.Errorf("closed server: %+v", res)
+	}
+}
+
+func TestSafeHTTPBlocksPrivate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := safehttp.NewClient(time.Second).Get(srv.URL)
+	if !errors.Is(err, safehttp.ErrForbiddenAddress) {
+		t.Errorf("loopback should be blocked, got %v", err)
+	}
+	for addr, want := range map[string]bool{
+		"8.8.8.8": true, "2606:4700::1111": true,
+		"10.0.0.1": false, "192.168.1.1": false, "169.254.169.254": false, "100.64.0.1": false,
+		"::1": false, "fd00::1": false, "::ffff:127.0.0.1": false,
+	} {
+		if got := safehttp.IsPublic(netip.MustParseAddr(addr)); got != want {
+			t.Errorf("IsPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestUseFallback(t *testing.T) {
+	link := shortlink.Shortlink{URL: "https://example.com/", FallbackURL: "https://backup.example.com/"}
+	if link.UseFallback() {
+		t.Error("healthy primary must not use fallback")
+	}
+	link.DestDown = true
+	if !link.UseFallback() {
+		t.Error("down primary with fallback should use it")
+	}
+	if (shortlink.Shortlink{URL: "https://example.com/{path}"}).HealthCheckURL() != "" {
+		t.Error("template destinations are not probed")
+	}
+}
+
+func TestFallbackRedirect(t *testing.T) {
+	r, slRepo, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	dest := "https://example.com/health/" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	fallback := "https://backup.example.com/"
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": dest, "fallback_url": fallback, "private": true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	// 找到新建短链的 id（新建的在最后）
+	ctx := context.Background()
+	var id int64
+	for after := int64(0); id == 0; {
+		links, err := slRepo.ListLinksForHealthCheck(ctx, after, 500, time.Now().Add(time.Hour))
+		if err != nil || len(links) == 0 {
+			t.Fatalf("link not listed for health check: %v", err)
+		}
+		for _, l := range links {
+			if l.Link.Code == created.Code {
+				id = l.ID
+			}
+		}
+		after = links[len(links)-1].ID
+	}
+
+	redirect := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+created.Code, nil))
+		return rec
+	}
+	fail := repo.HealthCheck{CheckedAt: time.Now(), StatusCode: 502}
+	// 一次失败不切换
+	if _, changed, err := slRepo.RecordHealthCheck(ctx, id, created.Code, fail, 2); err != nil || changed {
+		t.Fatalf("first failure: changed=%v err=%v", changed, err)
+	}
+	if loc := redirect().Header().Get("Location"); loc != dest {
+		t.Errorf("after one failure: Location=%q, want %q", loc, dest)
+	}
+	if down, changed, err := slRepo.RecordHealthCheck(ctx, id, created.Code, fail, 2); err != nil || !down || !changed {
+		t.Fatalf("second failure: down=%v changed=%v err=%v", down, changed, err)
+	}
+	rec = redirect()
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != fallback {
+		t.Errorf("primary down: %d Location=%q, want fallback", rec.Code, rec.Header().Get("Location"))
+	}
+
+	rec = doJSON(r, http.MethodGet, "/api/v1/users/mine?broken=true", token, nil)
+	var mine struct {
+		Items []struct {
+			Code            string `json:"code"`
+			DestinationDown bool   `json:"destination_down"`
+		} `json:"items"`
+	}
+	json.NewDecoder(rec.Body).Decode(&mine)
+	found := false
+	for _, item := range mine.Items {
+		found = found || (item.Code == created.Code && item.DestinationDown)
+	}
+	if !found {
+		t.Errorf("broken link not flagged in /users/mine: %s", rec.Body.String())
+	}
+
+	// 恢复一次即切回
+	if down, _, err := slRepo.RecordHealthCheck(ctx, id, created.Code, repo.HealthCheck{CheckedAt: time.Now(), OK: true, StatusCode: 200}, 2); err != nil || down {
+		t.Fatalf("recovery: down=%v err=%v", down, err)
+	}
+	if loc := redirect().Header().Get("Location"); loc != dest {
+		t.Errorf("after recovery: Location=%q, want %q", loc, dest)
+	}
+}
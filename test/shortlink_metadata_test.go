@@ -0,0 +1,140 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"day.local/internal/platform/pagemeta"
+)
+
+func TestPageMetaParse(t *testing.T) {
+	base, _ := url.Parse("https://example.com/blog/post")
+	page := `<!doctype html><html><head>
+		<meta charset="utf-8">
+		<title>
+			Hello   World
+		</title>
+		<meta property="og:title" content="OG title">
+		<meta property="og:description" content="OG description">
+		<meta property="og:image" content="/img/cover.png">
+		<link rel="shortcut icon" href="../favicon.png">
+		<link rel="icon" href="javascript:alert(1)">
+		</head><body><title>ignored</title></body></html>`
+
+	got := pagemeta.Parse(strings.NewReader(page), base)
+	want := pagemeta.Metadata{
+		Title:       "Hello World",
+		Description: "OG description",
+		FaviconURL:  "https://example.com/favicon.png",
+		ImageURL:    "https://example.com/img/cover.png",
+	}
+	if got != want {
+		t.Errorf("Parse = %+v, want %+v", got, want)
+	}
+
+	// 没有 <title>、没有图标声明：用 og:title 与 /favicon.ico；过长的标题被截断
+	long := strings.Repeat("a", 500)
+	got = pagemeta.Parse(strings.NewReader(`<head><meta property="og:title" content="`+long+`"><meta name="description" content="plain"></head>`), base)
+	if len([]rune(got.Title)) > 300 || !strings.HasPrefix(got.Title, "aaa") {
+		t.Errorf("long og:title not truncated: %d chars", len(got.Title))
+	}
+	if got.Description != "plain" || got.FaviconURL != "https://example.com/favicon.ico" || got.ImageURL != "" {
+		t.Errorf("fallbacks: %+v", got)
+	}
+}
+
+func TestPageMetaFetch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/page":
+			w.Header().Set("Content-Type", "text/html; charset=iso-8859-1")
+			w.Write([]byte("<html><head><title>Caf\xe9</title></head></html>"))
+		case "/redirect":
+			http.Redirect(w, r, "/sub/page", http.StatusFound)
+		case "/sub/page":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<head><link rel="icon" href="icon.png"></head>`))
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		case "/huge":
+			// 标题在读取上限之后，不会被读到
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<head><!--" + strings.Repeat("x", 1<<20) + "--><title>late</title></head>"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	f := pagemeta.NewFetcher(srv.Client())
+	ctx := context.Background()
+
+	if m, err := f.Fetch(ctx, srv.URL+"/page"); err != nil || m.Title != "Café" {
+		t.Errorf("charset: %+v, %v", m, err)
+	}
+	// 相对地址按跳转后的最终地址解析
+	if m, err := f.Fetch(ctx, srv.URL+"/redirect"); err != nil || m.FaviconURL != srv.URL+"/sub/icon.png" {
+		t.Errorf("redirect: %+v, %v", m, err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/json"); !errors.Is(err, pagemeta.ErrNotHTML) {
+		t.Errorf("json: want ErrNotHTML, got %v", err)
+	}
+	if m, err := f.Fetch(ctx, srv.URL+"/huge"); err != nil || m.Title != "" {
+		t.Errorf("huge: %+v, %v", m, err)
+	}
+	if _, err := f.Fetch(ctx, srv.URL+"/missing"); err == nil {
+		t.Error("404 should be an error")
+	}
+}
+
+func TestRefreshMetadata(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<head><title>Example page</title><meta name="description" content="About"></head>`))
+	}))
+	defer page.Close()
+	dest := page.URL + "/meta/" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": dest, "private": true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	rec = doJSON(r, http.MethodPost, "/api/v1/users/shortlinks/"+created.Code+"/metadata/refresh", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("refresh: %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = doJSON(r, http.MethodGet, "/api/v1/shortlinks/"+created.Code, "", nil)
+	var data struct {
+		Metadata *struct {
+			Title       string `json:"title"`
+			Description string `json:"description"`
+			FaviconURL  string `json:"favicon_url"`
+		} `json:"metadata"`
+	}
+	json.NewDecoder(rec.Body).Decode(&data)
+	if data.Metadata == nil || data.Metadata.Title != "Example page" || data.Metadata.Description != "About" || data.Metadata.FaviconURL != page.URL+"/favicon.ico" {
+		t.Errorf("metadata not returned: %+v", data.Metadata)
+	}
+
+	other := newTestUserToken(t, usersRepo, ts)
+	if rec := doJSON(r, http.MethodPost, "/api/v1/users/shortlinks/"+created.Code+"/metadata/refresh", other, nil); rec.Code != http.StatusForbidden {
+		t.Errorf("non-owner refresh: %d", rec.Code)
+	}
+}
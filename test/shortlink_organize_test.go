@@ -0,0 +1,178 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	got, err := shortlink.NormalizeTags([]string{" Q3 ", "q3", "", "Launch"})
+	if err != nil {
+		t.Fatalf("NormalizeTags: %v", err)
+	}
+	if want := []string{"q3", "launch"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("NormalizeTags: got %v, want %v", got, want)
+	}
+	if _, err := shortlink.NormalizeTags([]string{"a,b"}); err == nil {
+		t.Errorf("tag with comma should be rejected")
+	}
+}
+
+func doJSON(r *gee.Engine, method, path, token string, body any) *httptest.ResponseRecorder {
+	var req *http.Request
+	if body != nil {
+		b, _ := json.Marshal(body)
+		req = httptest.NewRequest(method, path, bytes.NewReader(b))
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func listMine(t *testing.T, r *gee.Engine, token, query string) []map[string]any {
+	t.Helper()
+	rec := doJSON(r, http.MethodGet, "/api/v1/users/mine"+query, token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list mine: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var page struct {
+		Items []map[string]any `json:"items"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("decode list: %v", err)
+	}
+	return page.Items
+}
+
+func TestOrganizeShortlinksWithTagsAndFolders(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/users/folders", token, map[string]string{"name": "Campaigns"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create folder: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var folder map[string]any
+	json.NewDecoder(rec.Body).Decode(&folder)
+	folderID := int64(folder["id"].(float64))
+
+	rec = doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url":       "https://example.com/organize-" + suffix,
+		"title":     "Spring sale",
+		"tags":      []string{"Sale", "spring"},
+		"folder_id": folderID,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created map[string]any
+	json.NewDecoder(rec.Body).Decode(&created)
+	code := created["code"].(string)
+	createShortlinkAs(t, r, token, "https://example.com/untagged-"+suffix)
+
+	list := listMine(t, r, token, "?tag=sale")
+	if len(list) != 1 || list[0]["code"] != code || list[0]["title"] != "Spring sale" {
+		t.Fatalf("filter by tag: got %v", list)
+	}
+	if list := listMine(t, r, token, "?folder_id="+strconv.FormatInt(folderID, 10)); len(list) != 1 {
+		t.Fatalf("filter by folder: got %d items", len(list))
+	}
+
+	// 修改整理信息：换标签、移出文件夹
+	rec = doJSON(r, http.MethodPatch, "/api/v1/users/mine/"+code, token, map[string]any{"tags": []string{"archived"}, "folder_id": 0})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch meta: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if list := listMine(t, r, token, "?tag=sale"); len(list) != 0 {
+		t.Errorf("old tag should no longer match, got %d items", len(list))
+	}
+	if list := listMine(t, r, token, "?folder_id="+strconv.FormatInt(folderID, 10)); len(list) != 0 {
+		t.Errorf("link should be out of the folder, got %d items", len(list))
+	}
+
+	// 别人的文件夹不可用
+	other := newTestUserToken(t, usersRepo, ts)
+	if rec := doJSON(r, http.MethodDelete, "/api/v1/users/folders/"+strconv.FormatInt(folderID, 10), other, nil); rec.Code != http.StatusNotFound {
+		t.Errorf("delete other's folder: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	rec = doJSON(r, http.MethodPost, "/api/v1/shortlinks", other, map[string]any{
+		"url":       "https://example.com/foreign-folder-" + suffix,
+		"folder_id": folderID,
+	})
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("create into other's folder: got %d, want %d", rec.Code, http.StatusNotFound)
+	}
+	if list := listMine(t, r, other, ""); len(list) != 0 {
+		t.Errorf("rejected create should not leave a link behind, got %d items", len(list))
+	}
+}
+
+func TestListMinePaginationAndSearch(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	for i := 0; i < 3; i++ {
+		createShortlinkAs(t, r, token, "https://example.com/page-"+suffix+"-"+strconv.Itoa(i))
+	}
+	createShortlinkAs(t, r, token, "https://other.example.org/"+suffix)
+
+	seen := map[string]bool{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("too many pages")
+		}
+		query := "?limit=2&q=page-" + suffix
+		if cursor != "" {
+			query += "&cursor=" + cursor
+		}
+		rec := doJSON(r, http.MethodGet, "/api/v1/users/mine"+query, token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list: %d, body=%s", rec.Code, rec.Body.String())
+		}
+		var page struct {
+			Items      []map[string]any `json:"items"`
+			NextCursor string           `json:"next_cursor"`
+		}
+		json.NewDecoder(rec.Body).Decode(&page)
+		for _, item := range page.Items {
+			code := item["code"].(string)
+			if seen[code] {
+				t.Errorf("duplicate item across pages: %s", code)
+			}
+			seen[code] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	if len(seen) != 3 {
+		t.Errorf("search+pagination: got %d items, want 3", len(seen))
+	}
+
+	if rec := doJSON(r, http.MethodGet, "/api/v1/users/mine?sort=clicks&order=asc", token, nil); rec.Code != http.StatusOK {
+		t.Errorf("sort by clicks: got %d", rec.Code)
+	}
+	if rec := doJSON(r, http.MethodGet, "/api/v1/users/mine?cursor=bogus", token, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid cursor: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := doJSON(r, http.MethodGet, "/api/v1/users/mine?sort=name", token, nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid sort: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestUnlockSigner(t *testing.T) {
+	hash, err := shortlink.HashLinkPassword("s3cret")
+	if err != nil {
+		t.Fatalf("HashLinkPassword: %v", err)
+	}
+	link := shortlink.Shortlink{Code: "abc", URL: "https://example.com", PasswordHash: hash}
+	if !link.CheckPassword("s3cret") || link.CheckPassword("wrong") {
+		t.Fatalf("CheckPassword mismatch")
+	}
+
+	signer := shortlink.NewUnlockSigner("secret", time.Minute)
+	now := time.Now()
+	token := signer.Sign(link, now)
+	if !signer.Verify(link, token, now) {
+		t.Errorf("fresh token should verify")
+	}
+	if signer.Verify(link, token, now.Add(2*time.Minute)) {
+		t.Errorf("expired token should not verify")
+	}
+	if signer.Verify(shortlink.Shortlink{Code: "other", PasswordHash: hash}, token, now) {
+		t.Errorf("token must be bound to code")
+	}
+	rehashed, _ := shortlink.HashLinkPassword("s3cret")
+	if signer.Verify(shortlink.Shortlink{Code: "abc", PasswordHash: rehashed}, token, now) {
+		t.Errorf("token must be invalidated by a password change")
+	}
+	if signer.Verify(link, token+"x", now) || signer.Verify(link, "garbage", now) {
+		t.Errorf("tampered token should not verify")
+	}
+	// 签名用的是派生出的子密钥，拿原始密钥（即 JWT 密钥）算出的 HMAC 不能通过
+	exp, _, _ := strings.Cut(token, ".")
+	m := hmac.New(sha256.New, []byte("secret"))
+	m.Write([]byte("unlock|" + link.Code + "|" + exp + "|" + link.PasswordHash))
+	if signer.Verify(link, exp+"."+base64.RawURLEncoding.EncodeToString(m.Sum(nil)), now) {
+		t.Errorf("token signed with the raw secret should not verify")
+	}
+}
+
+func TestPasswordProtectedRedirect(t *testing.T) {
+	r, _, _, _ := setupTestServer(t)
+
+	dest := "https://example.com/protected-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	body, _ := json.Marshal(map[string]any{"url": dest, "password": "letmein"})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create failed: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	json.NewDecoder(rec.Body).Decode(&resp)
+	code, _ := resp["code"].(string)
+
+	// 没有凭证：展示密码页，不跳转
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Location") != "" {
+		t.Fatalf("challenge: got %d, Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+	if !strings.Contains(rec.Body.String(), `name="password"`) {
+		t.Errorf("challenge page should contain a password form")
+	}
+
+	// 公开元数据不能泄露目标地址
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/shortlinks/"+code, nil))
+	if strings.Contains(rec.Body.String(), dest) {
+		t.Errorf("metadata leaks protected url: %s", rec.Body.String())
+	}
+
+	submit := func(pw string) *httptest.ResponseRecorder {
+		form := url.Values{"password": {pw}}
+		req := httptest.NewRequest(http.MethodPost, "/"+code, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	if rec := submit("wrong"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+	rec = submit("letmein")
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("correct password: got %d, want %d", rec.Code, http.StatusSeeOther)
+	}
+	cookies := rec.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatalf("expected unlock cookie")
+	}
+
+	// 带凭证再次访问：直接跳转
+	req = httptest.NewRequest(http.MethodGet, "/"+code, nil)
+	req.AddCookie(cookies[0])
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != dest {
+		t.Errorf("unlocked redirect: got %d, Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+}
+
+// 带密码等选项的创建各占一行，不会让同一 url 的其它创建冲突
+func TestOptionLinksDoNotBlockSharedURL(t *testing.T) {
+	r, _, _, _ := setupTestServer(t)
+
+	dest := "https://example.com/popular-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	create := func(body map[string]any) string {
+		rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", "", body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("create %v: %d, body=%s", body, rec.Code, rec.Body.String())
+		}
+		var resp struct {
+			Code string `json:"code"`
+		}
+		json.NewDecoder(rec.Body).Decode(&resp)
+		return resp.Code
+	}
+
+	plain := create(map[string]any{"url": dest})
+	locked := create(map[string]any{"url": dest, "password": "letmein"})
+	lockedAgain := create(map[string]any{"url": dest, "password": "letmein"})
+	capped := create(map[string]any{"url": dest, "max_clicks": 1})
+	if locked == plain || lockedAgain == locked || capped == plain {
+		t.Fatalf("option links must get their own codes: plain=%s locked=%s/%s capped=%s", plain, locked, lockedAgain, capped)
+	}
+	if again := create(map[string]any{"url": dest}); again != plain {
+		t.Errorf("plain creates should share a code: %s vs %s", again, plain)
+	}
+
+	// 普通短链没有被加上密码
+	rec := doJSON(r, http.MethodGet, "/"+plain, "", nil)
+	if rec.Header().Get("Location") != dest {
+		t.Errorf("plain link: %d Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestExpandPath(t *testing.T) {
+	forward := shortlink.Shortlink{ForwardPath: true}
+	plain := shortlink.Shortlink{}
+
+	cases := []struct {
+		name     string
+		link     shortlink.Shortlink
+		dest     string
+		segments []string
+		want     string
+		err      error
+	}{
+		{"no path", plain, "https://example.com/docs", nil, "https://example.com/docs", nil},
+		{"path not allowed", plain, "https://example.com/docs", []string{"a"}, "", shortlink.ErrPathNotAllowed},
+		{"forward", forward, "https://example.com/docs/?v=1#top", []string{"some", "sub path"},
+			"https://example.com/docs/some/sub%20path?v=1#top", nil},
+		{"dot segments", forward, "https://example.com/docs", []string{"..", "etc"}, "", shortlink.ErrPathNotAllowed},
+		{"template", plain, "https://jira.example.com/browse/{1}", []string{"ABC-123"}, "https://jira.example.com/browse/ABC-123", nil},
+		{"template query", plain, "https://example.com/search?q={1}&p={path}", []string{"a b", "c"},
+			"https://example.com/search?q=a+b&p=a+b%2Fc", nil},
+		{"template path", plain, "https://example.com/{path}", []string{"x", "y/z"}, "https://example.com/x/y%2Fz", nil},
+		{"template missing", plain, "https://example.com/{2}", []string{"x"}, "", shortlink.ErrMissingSegment},
+	}
+	for _, tc := range cases {
+		got, err := tc.link.ExpandPath(tc.dest, tc.segments)
+		if !errors.Is(err, tc.err) || got != tc.want {
+			t.Errorf("%s: got %q, %v; want %q, %v", tc.name, got, err, tc.want, tc.err)
+		}
+	}
+
+	if err := shortlink.ValidateURL("https://{1}.example.com/"); err == nil {
+		t.Error("placeholder in host must be rejected")
+	}
+}
+
+func TestTemplatedRedirect(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	base := "https://jira.example.com/" + strconv.FormatInt(time.Now().UnixNano(), 10) + "/browse/{1}"
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": base})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+		URL  string `json:"url"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+created.Code+"/ABC-123", nil))
+	want, _ := (shortlink.Shortlink{}).ExpandPath(base, []string{"ABC-123"})
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != want {
+		t.Errorf("templated redirect: %d, Location=%q, want %q", rec.Code, rec.Header().Get("Location"), want)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+created.Code, nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("template without segment: want 400, got %d", rec.Code)
+	}
+}
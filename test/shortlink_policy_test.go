@@ -0,0 +1,143 @@
+package test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestPolicyPattern(t *testing.T) {
+	valid := map[string]string{
+		"Example.COM":   "example.com",
+		".example.com.": ".example.com",
+		"*.example.com": "*.example.com",
+		"paypal-*.com":  "paypal-*.com",
+		" bit.ly ":      "bit.ly",
+	}
+	for in, want := range valid {
+		got, err := shortlink.NormalizePolicyPattern(in)
+		if err != nil || got != want {
+			t.Errorf("NormalizePolicyPattern(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "*", "*.*", "a..com", "exa mple.com", "example.com/path", "a?.com"} {
+		if _, err := shortlink.NormalizePolicyPattern(in); !errors.Is(err, shortlink.ErrInvalidPolicyPattern) {
+			t.Errorf("NormalizePolicyPattern(%q): want ErrInvalidPolicyPattern, got %v", in, err)
+		}
+	}
+}
+
+func TestPolicyRuleMatches(t *testing.T) {
+	cases := []struct {
+		pattern, host string
+		want          bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "www.example.com", false},
+		{".example.com", "example.com", true},
+		{".example.com", "a.b.example.com", true},
+		{".example.com", "badexample.com", false},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"paypal-*.com", "paypal-login.com", true},
+		{"paypal-*.com", "paypal.com", false},
+	}
+	for _, c := range cases {
+		if got := (shortlink.PolicyRule{Pattern: c.pattern}).Matches(c.host); got != c.want {
+			t.Errorf("%q matches %q = %v, want %v", c.pattern, c.host, got, c.want)
+		}
+	}
+}
+
+func TestDestinationPolicyCheck(t *testing.T) {
+	var none *shortlink.DestinationPolicy
+	if err := none.Check("https://evil.example/"); err != nil {
+		t.Errorf("nil policy should allow everything, got %v", err)
+	}
+
+	p := &shortlink.DestinationPolicy{Rules: []shortlink.PolicyRule{
+		{Pattern: ".example.com", Action: shortlink.PolicyAllow},
+		{Pattern: "bad.example.com", Action: shortlink.PolicyDeny},
+	}}
+	if err := p.Check("https://www.example.com/a", "https://other.org/"); err != nil {
+		t.Errorf("allowed hosts: %v", err)
+	}
+	// deny 优先于 allow，且检查所有目标（规则/分流版本）
+	if err := p.Check("https://www.example.com/", "https://BAD.example.com/x"); !errors.Is(err, shortlink.ErrDestinationDenied) {
+		t.Errorf("deny should win over allow, got %v", err)
+	}
+
+	p.AllowlistOnly = true
+	if err := p.Check("https://other.org/"); !errors.Is(err, shortlink.ErrDestinationNotAllowed) {
+		t.Errorf("allowlist-only: want ErrDestinationNotAllowed, got %v", err)
+	}
+	if err := p.Check("https://example.com/"); err != nil {
+		t.Errorf("allowlist-only allowed host: %v", err)
+	}
+}
+
+func TestDestinationPolicyEnforced(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	adminToken, _ := ts.Sign("1", "admin")
+	host := "blocked-" + strconv.FormatInt(time.Now().UnixNano(), 10) + ".example"
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/admin/destination-policies", adminToken, map[string]any{
+		"pattern": "." + host, "action": "deny", "note": "test",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("add policy: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var entry struct {
+		ID int64 `json:"id"`
+	}
+	json.NewDecoder(rec.Body).Decode(&entry)
+	t.Cleanup(func() {
+		doJSON(r, http.MethodDelete, "/api/v1/admin/destination-policies/"+strconv.FormatInt(entry.ID, 10), adminToken, nil)
+	})
+
+	if rec := doJSON(r, http.MethodPost, "/api/v1/admin/destination-policies", token, map[string]any{
+		"pattern": "other.example", "action": "deny",
+	}); rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin add policy: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	if rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": "https://www." + host + "/x",
+	}); rec.Code != http.StatusForbidden {
+		t.Errorf("create denied destination: got %d, want %d, body=%s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+
+	// App 跳转里的 https 地址会被直接 302，同样受策略约束
+	denied := map[string]any{"ios": "https://" + host + "/app"}
+	if rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": "https://example.com/policy-ok", "deep_link": denied,
+	}); rec.Code != http.StatusForbidden {
+		t.Errorf("create with denied deep link: got %d, want %d, body=%s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+	code := createShortlinkAs(t, r, token, "https://example.com/policy-ok-"+host)
+	if rec := doJSON(r, http.MethodPut, "/api/v1/users/shortlinks/"+code+"/deeplink", token, denied); rec.Code != http.StatusForbidden {
+		t.Errorf("update denied deep link: got %d, want %d, body=%s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+	if rec := doJSON(r, http.MethodPut, "/api/v1/users/shortlinks/"+code+"/deeplink", token, map[string]any{"ios": "myapp://item/1"}); rec.Code != http.StatusOK {
+		t.Errorf("custom scheme deep link: got %d, want %d, body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	rec = doJSON(r, http.MethodPost, "/api/v1/shortlinks:batch", token, map[string]any{
+		"items": []map[string]any{{"url": "https://" + host + "/a"}},
+	})
+	var batch struct {
+		Results []struct {
+			Status int `json:"status"`
+		} `json:"results"`
+	}
+	json.NewDecoder(rec.Body).Decode(&batch)
+	if rec.Code != http.StatusOK || len(batch.Results) != 1 || batch.Results[0].Status != http.StatusForbidden {
+		t.Errorf("batch denied destination: %d, results=%+v", rec.Code, batch.Results)
+	}
+}
@@ -0,0 +1,69 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestShowPreview(t *testing.T) {
+	if (shortlink.Shortlink{}).ShowPreview() {
+		t.Error("preview off by default")
+	}
+	if !(shortlink.Shortlink{PreviewForced: true}).ShowPreview() {
+		t.Error("forced preview must be shown")
+	}
+	if !(shortlink.Shortlink{Variants: []shortlink.Variant{{Name: "a"}}}).DynamicDestination() {
+		t.Error("variants make the destination dynamic")
+	}
+}
+
+func TestPreviewPage(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	dest := "https://example.com/preview/" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	create := func(body map[string]any) string {
+		rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, body)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+		}
+		var created struct {
+			Code string `json:"code"`
+		}
+		json.NewDecoder(rec.Body).Decode(&created)
+		return created.Code
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// /{code}+ 只展示，不跳转；继续访问回到 /{code}
+	code := create(map[string]any{"url": dest})
+	rec := get("/" + code + "+")
+	if rec.Code != http.StatusOK || rec.Header().Get("Location") != "" {
+		t.Fatalf("preview: %d, Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, dest) || !strings.Contains(body, `href="/`+code+`"`) {
+		t.Errorf("preview page missing destination or continue link: %s", body)
+	}
+	if rec := get("/" + code); rec.Code != http.StatusFound {
+		t.Errorf("plain visit should still redirect, got %d", rec.Code)
+	}
+
+	// 总是展示预览页：普通访问也先展示，继续访问直接去目标
+	code = create(map[string]any{"url": dest + "/always", "preview": true, "private": true})
+	rec = get("/" + code)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `href="`+dest+`/always"`) {
+		t.Errorf("always-preview: %d, body=%s", rec.Code, rec.Body.String())
+	}
+}
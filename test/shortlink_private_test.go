@@ -0,0 +1,79 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/gee"
+)
+
+func createPrivateShortlinkAs(t *testing.T, r *gee.Engine, token, url string) string {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"url": url, "private": true})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create private failed: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	code, _ := resp["code"].(string)
+	return code
+}
+
+func TestPrivateShortlinksAreIndependent(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	alice := newTestUserToken(t, usersRepo, ts)
+	bob := newTestUserToken(t, usersRepo, ts)
+
+	url := "https://example.com/private-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+	aliceCode := createPrivateShortlinkAs(t, r, alice, url)
+	bobCode := createPrivateShortlinkAs(t, r, bob, url)
+	if aliceCode == bobCode {
+		t.Fatalf("private links for the same url should get different codes, got %q", aliceCode)
+	}
+
+	// 共享行仍然按 url 去重，不受私有行影响
+	sharedCode := createShortlinkAs(t, r, alice, url)
+	if sharedCode == aliceCode || sharedCode == bobCode {
+		t.Errorf("shared link should not reuse a private code")
+	}
+
+	// alice 停用自己的私有链接不影响 bob
+	if rec := postStateChange(r, "/api/v1/users/shortlinks/"+aliceCode+"/disable", alice, ""); rec.Code != http.StatusOK {
+		t.Fatalf("disable private: got %d, body=%s", rec.Code, rec.Body.String())
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+bobCode, nil))
+	if rec.Code != http.StatusFound {
+		t.Errorf("bob's link after alice disabled hers: got %d, want %d", rec.Code, http.StatusFound)
+	}
+
+	// bob 修改自己的目标地址不受 url 唯一约束影响
+	if rec := patchShortlinkURL(r, bob, bobCode, url+"/v2"); rec.Code != http.StatusOK {
+		t.Errorf("patch private: got %d, body=%s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPrivateShortlinkRequiresLogin(t *testing.T) {
+	r, _, _, _ := setupTestServer(t)
+
+	body, _ := json.Marshal(map[string]any{"url": "https://example.com/anon-private", "private": true})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("anonymous private: got %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
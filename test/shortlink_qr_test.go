@@ -0,0 +1,196 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"day.local/internal/platform/qrcode"
+)
+
+var (
+	qrBlack = color.RGBA{A: 0xff}
+	qrWhite = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+)
+
+func TestQRRenderPNG(t *testing.T) {
+	r, err := qrcode.NewRenderer(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := r.Render("https://s.example.com/abc123~qr", qrcode.Options{Format: qrcode.PNG, Size: 300, Margin: 4, Level: qrcode.LevelM, FG: qrBlack, BG: qrWhite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 300 || b.Dy() != 300 {
+		t.Fatalf("size = %v, want 300x300", b)
+	}
+	// 内容 30 字节、纠错 M 需要版本 3（29 模块），加静区共 37 模块，每模块 8 像素，余量 4 像素分到两边
+	isDark := func(x, y int) bool {
+		r, _, _, _ := img.At(x, y).RGBA()
+		return r == 0
+	}
+	if isDark(0, 0) || isDark(2+4*8-1, 2+4*8-1) {
+		t.Error("quiet zone must be light")
+	}
+	// 左上角定位图案：外框深、内圈浅、中心深
+	origin := 2 + 4*8
+	if !isDark(origin, origin) || isDark(origin+8+4, origin+8+4) || !isDark(origin+3*8+4, origin+3*8+4) {
+		t.Error("finder pattern not where expected")
+	}
+
+	if _, err := r.Render("https://s.example.com/abc123~qr", qrcode.Options{Format: qrcode.PNG, Size: 30, Margin: 4}); err != qrcode.ErrSizeTooSmall {
+		t.Errorf("tiny size: got %v", err)
+	}
+	if _, err := r.Render("x", qrcode.Options{Format: qrcode.PNG, Size: 300, Logo: true}); err != qrcode.ErrNoLogo {
+		t.Errorf("logo without configured logo: got %v", err)
+	}
+}
+
+func TestQRRenderLogo(t *testing.T) {
+	red := color.RGBA{R: 0xff, A: 0xff}
+	logo := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for i := range logo.Pix {
+		if i%4 == 0 || i%4 == 3 {
+			logo.Pix[i] = 0xff
+		}
+	}
+	r, err := qrcode.NewRenderer(logo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := qrcode.Options{Format: qrcode.PNG, Size: 400, Margin: 4, FG: qrBlack, BG: qrWhite, Logo: true}
+	body, err := r.Render("https://s.example.com/abc123~qr", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := color.RGBAModel.Convert(img.At(200, 200)); got != red {
+		t.Errorf("center pixel = %v, want logo color", got)
+	}
+
+	opts.Format = qrcode.SVG
+	svg, err := r.Render("https://s.example.com/abc123~qr", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(svg, []byte(`xlink:href="data:image/png;base64,`)) {
+		t.Error("svg should embed the logo")
+	}
+}
+
+func TestQRRenderSVG(t *testing.T) {
+	r, _ := qrcode.NewRenderer(nil)
+	body, err := r.Render("https://s.example.com/abc123~qr", qrcode.Options{Format: qrcode.SVG, Size: 1024, Margin: 2, Level: qrcode.LevelL, FG: color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}, BG: qrWhite})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Width   string `xml:"width,attr"`
+		ViewBox string `xml:"viewBox,attr"`
+		Path    struct {
+			Fill string `xml:"fill,attr"`
+			D    string `xml:"d,attr"`
+		} `xml:"path"`
+	}
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		t.Fatalf("invalid svg: %v", err)
+	}
+	// 纠错 L 时版本 2（25 模块）就够，加两边各 2 个模块的静区
+	if doc.Width != "1024" || doc.ViewBox != "0 0 29 29" {
+		t.Errorf("width=%q viewBox=%q", doc.Width, doc.ViewBox)
+	}
+	// 第一行从定位图案开始：7 个连续深色模块
+	if doc.Path.Fill != "#112233" || !strings.HasPrefix(doc.Path.D, "M2 2h7v1h-7z") {
+		t.Errorf("path fill=%q d=%.40q", doc.Path.Fill, doc.Path.D)
+	}
+}
+
+func TestQREndpoint(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	dest := "https://example.com/qr/" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": dest, "private": true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	get := func(path string, header map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for k, v := range header {
+			req.Header.Set(k, v)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec = get("/qr/"+created.Code+"?format=svg&size=512&fg=%23336699&ecc=H", nil)
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Fatalf("svg: %d %q, body=%s", rec.Code, rec.Header().Get("Content-Type"), rec.Body.String())
+	}
+	etag := rec.Header().Get("ETag")
+	if etag == "" || !strings.Contains(rec.Header().Get("Cache-Control"), "max-age") {
+		t.Errorf("missing caching headers: %v", rec.Header())
+	}
+	if rec := get("/qr/"+created.Code+"?format=svg&size=512&fg=%23336699&ecc=H", map[string]string{"If-None-Match": etag}); rec.Code != http.StatusNotModified {
+		t.Errorf("conditional request: %d", rec.Code)
+	}
+	if rec := get("/qr/"+created.Code, nil); rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("png: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	for _, q := range []string{"format=gif", "size=10", "size=abc", "margin=99", "ecc=X", "fg=zzz", "fg=fff&bg=ffffff", "logo=true"} {
+		if rec := get("/qr/"+created.Code+"?"+q, nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: %d, want 400", q, rec.Code)
+		}
+	}
+	if rec := get("/qr/doesnotexist999", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("missing link: %d", rec.Code)
+	}
+
+	// 不接受路径的短链上 /{code}/qr 同样返回二维码
+	if rec := get("/"+created.Code+"/qr?format=svg", nil); rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/svg+xml" {
+		t.Errorf("/{code}/qr: %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if rec := get("/"+created.Code+"/other", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("other path on a link without forward_path: %d, want 404", rec.Code)
+	}
+
+	// 码里编码的 /{code}~qr 照常跳转
+	rec = get("/"+created.Code+"~qr", nil)
+	if rec.Header().Get("Location") != dest {
+		t.Errorf("scan redirect: %d Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// 开启路径透传的短链，/{code}/qr 照常透传
+	rec = doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": dest + "/docs", "private": true, "forward_path": true})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create forward_path: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if rec := get("/"+created.Code+"/qr", nil); rec.Header().Get("Location") != dest+"/docs/qr" {
+		t.Errorf("forwarded /qr: %d Location=%q", rec.Code, rec.Header().Get("Location"))
+	}
+}
@@ -0,0 +1,107 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestValidateRedirectType(t *testing.T) {
+	for _, v := range []int{0, 301, 302, 307, 308} {
+		if err := shortlink.ValidateRedirectType(v); err != nil {
+			t.Errorf("ValidateRedirectType(%d): unexpected error %v", v, err)
+		}
+	}
+	for _, v := range []int{200, 303, 304, 404, -1} {
+		if err := shortlink.ValidateRedirectType(v); err == nil {
+			t.Errorf("ValidateRedirectType(%d): expected error", v)
+		}
+	}
+	if got := (shortlink.Shortlink{}).RedirectStatus(); got != http.StatusFound {
+		t.Errorf("default RedirectStatus: got %d, want %d", got, http.StatusFound)
+	}
+}
+
+func TestValidateHeaderValue(t *testing.T) {
+	valid := []string{"", "no-store", "public, max-age=3600", "noindex, nofollow"}
+	for _, v := range valid {
+		if err := shortlink.ValidateHeaderValue(v); err != nil {
+			t.Errorf("ValidateHeaderValue(%q): unexpected error %v", v, err)
+		}
+	}
+	invalid := []string{"no-store\r\nSet-Cookie: a=b", "tab\there", string(bytes.Repeat([]byte("a"), 257))}
+	for _, v := range invalid {
+		if err := shortlink.ValidateHeaderValue(v); err == nil {
+			t.Errorf("ValidateHeaderValue(%q): expected error", v)
+		}
+	}
+}
+
+func TestRedirectUsesPerLinkStatusAndHeaders(t *testing.T) {
+	r, _, _, _ := setupTestServer(t)
+
+	body, _ := json.Marshal(map[string]any{
+		"url":           "https://example.com/redirect-options-" + strconv.FormatInt(time.Now().UnixNano(), 10),
+		"redirect_type": 308,
+		"cache_control": "public, max-age=86400",
+		"robots_tag":    "noindex",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create failed: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	code, _ := resp["code"].(string)
+
+	redirectRec := httptest.NewRecorder()
+	r.ServeHTTP(redirectRec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if redirectRec.Code != http.StatusPermanentRedirect {
+		t.Fatalf("redirect status: got %d, want %d", redirectRec.Code, http.StatusPermanentRedirect)
+	}
+	if got := redirectRec.Header().Get("Cache-Control"); got != "public, max-age=86400" {
+		t.Errorf("Cache-Control: got %q", got)
+	}
+	if got := redirectRec.Header().Get("X-Robots-Tag"); got != "noindex" {
+		t.Errorf("X-Robots-Tag: got %q", got)
+	}
+}
+
+func TestCreateShortlinkWithInvalidRedirectType(t *testing.T) {
+	r, _, _, _ := setupTestServer(t)
+
+	body, _ := json.Marshal(map[string]any{
+		"url":           "https://example.com/x",
+		"redirect_type": 303,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d, body=%s", rec.Code, http.StatusBadRequest, rec.Body.String())
+	}
+}
+
+func TestValidateMaxClicks(t *testing.T) {
+	if err := shortlink.ValidateMaxClicks(0); err != nil {
+		t.Errorf("ValidateMaxClicks(0): unexpected error %v", err)
+	}
+	if err := shortlink.ValidateMaxClicks(1); err != nil {
+		t.Errorf("ValidateMaxClicks(1): unexpected error %v", err)
+	}
+	if err := shortlink.ValidateMaxClicks(-1); err == nil {
+		t.Errorf("ValidateMaxClicks(-1): expected error")
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"day.local/internal/platform/urlcheck"
+)
+
+// fakeMalwareURL 是 fake Safe Browsing 标记为 MALWARE 的地址（沿用 Google 的测试地址）
+const fakeMalwareURL = "https://testsafebrowsing.appspot.com/s/malware.html"
+
+// newFakeSafeBrowsing 启动一个按 v4 threatMatches:find 协议应答的假服务：
+// 只标记 fakeMalwareURL；key 不是 "test-key" 时返回 400。
+func newFakeSafeBrowsing(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Query().Get("key") != "test-key" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			ThreatInfo struct {
+				ThreatEntries []struct {
+					URL string `json:"url"`
+				} `json:"threatEntries"`
+			} `json:"threatInfo"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+		type match struct {
+			ThreatType string            `json:"threatType"`
+			Threat     map[string]string `json:"threat"`
+		}
+		resp := struct {
+			Matches []match `json:"matches,omitempty"`
+		}{}
+		for _, e := range req.ThreatInfo.ThreatEntries {
+			if e.URL == fakeMalwareURL {
+				resp.Matches = append(resp.Matches, match{ThreatType: "MALWARE", Threat: map[string]string{"url": e.URL}})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSafeBrowsingCheck(t *testing.T) {
+	srv := newFakeSafeBrowsing(t)
+	sb := urlcheck.NewSafeBrowsing("test-key", srv.URL, srv.Client())
+
+	matches, err := sb.Check(context.Background(), []string{"https://example.com/", fakeMalwareURL, fakeMalwareURL})
+	if err != nil {
+		t.Fatalf("Check: %v", err)
+	}
+	if len(matches) != 1 || matches[0].URL != fakeMalwareURL || matches[0].Threat != "MALWARE" {
+		t.Fatalf("matches = %+v", matches)
+	}
+	if !strings.Contains(matches[0].Reason(), "MALWARE") {
+		t.Errorf("reason = %q", matches[0].Reason())
+	}
+
+	if matches, err := sb.Check(context.Background(), []string{"https://example.com/"}); err != nil || len(matches) != 0 {
+		t.Errorf("clean url: %+v, %v", matches, err)
+	}
+	if _, err := urlcheck.NewSafeBrowsing("wrong-key", srv.URL, srv.Client()).Check(context.Background(), []string{"https://example.com/"}); err == nil {
+		t.Error("non-200 response should be an error")
+	}
+}
+
+type failingChecker struct{}
+
+func (failingChecker) Check(context.Context, []string) ([]urlcheck.Match, error) {
+	return nil, errors.New("upstream down")
+}
+
+func TestScreenerFailMode(t *testing.T) {
+	urls := []string{"https://example.com/"}
+	if m, err := urlcheck.NewScreener(failingChecker{}, false).Screen(context.Background(), urls); err != nil || len(m) != 0 {
+		t.Errorf("fail open: %+v, %v", m, err)
+	}
+	if _, err := urlcheck.NewScreener(failingChecker{}, true).Screen(context.Background(), urls); !errors.Is(err, urlcheck.ErrUnavailable) {
+		t.Errorf("fail closed: want ErrUnavailable, got %v", err)
+	}
+	if m, err := urlcheck.NewScreener(nil, true).Screen(context.Background(), urls); err != nil || len(m) != 0 {
+		t.Errorf("disabled checker should allow: %+v, %v", m, err)
+	}
+}
+
+func TestReputationBlocksCreate(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{"url": fakeMalwareURL})
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "MALWARE") {
+		t.Errorf("create flagged url: %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	// App 跳转里的 https 地址同样会被 302 出去，也要过信誉检查
+	flagged := map[string]any{"android": fakeMalwareURL}
+	rec = doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": "https://example.com/reputation-app", "deep_link": flagged,
+	})
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "MALWARE") {
+		t.Errorf("create flagged deep link: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	code := createShortlinkAs(t, r, token, "https://example.com/reputation-app")
+	rec = doJSON(r, http.MethodPut, "/api/v1/users/shortlinks/"+code+"/deeplink", token, flagged)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "MALWARE") {
+		t.Errorf("update flagged deep link: %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = doJSON(r, http.MethodPost, "/api/v1/shortlinks:batch", token, map[string]any{
+		"items": []map[string]any{{"url": "https://example.com/reputation-ok"}, {"url": fakeMalwareURL}},
+	})
+	var batch struct {
+		Results []struct {
+			Status int `json:"status"`
+		} `json:"results"`
+	}
+	json.NewDecoder(rec.Body).Decode(&batch)
+	if rec.Code != http.StatusOK || len(batch.Results) != 2 || batch.Results[0].Status != http.StatusOK || batch.Results[1].Status != http.StatusForbidden {
+		t.Errorf("batch: %d, results=%+v", rec.Code, batch.Results)
+	}
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+const (
+	uaIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	uaAndroid = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36"
+	uaDesktop = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/120.0 Safari/537.36"
+)
+
+func TestRedirectRulesMatching(t *testing.T) {
+	rules, err := shortlink.NormalizeRules([]shortlink.RedirectRule{
+		{OS: []string{"iOS"}, URL: "https://example.com/ios"},
+		{OS: []string{"android"}, URL: "https://example.com/android"},
+		{Languages: []string{"de"}, URL: "https://example.com/de"},
+		{Countries: []string{"fr"}, URL: "https://example.com/fr"},
+		{DailyFrom: "22:00", DailyTo: "06:00", URL: "https://example.com/night"},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeRules: %v", err)
+	}
+	link := shortlink.Shortlink{URL: "https://example.com/default", Rules: rules}
+	noon := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	visitor := func(ua, lang, country string, now time.Time) shortlink.Visitor {
+		v := shortlink.Visitor{Language: shortlink.PreferredLanguage(lang), Country: country, Now: now}
+		v.Device, v.OS = shortlink.DetectDevice(ua)
+		return v
+	}
+	cases := []struct {
+		name string
+		v    shortlink.Visitor
+		want string
+	}{
+		{"ios", visitor(uaIPhone, "de-DE", "", noon), "https://example.com/ios"},
+		{"android", visitor(uaAndroid, "", "", noon), "https://example.com/android"},
+		{"german desktop", visitor(uaDesktop, "en;q=0.5, de-AT", "", noon), "https://example.com/de"},
+		{"french desktop", visitor(uaDesktop, "fr", "FR", noon), "https://example.com/fr"},
+		{"night", visitor(uaDesktop, "en", "", time.Date(2026, 1, 1, 23, 30, 0, 0, time.UTC)), "https://example.com/night"},
+		{"default", visitor(uaDesktop, "en-US", "US", noon), "https://example.com/default"},
+	}
+	for _, tc := range cases {
+		if got := link.Destination(tc.v); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestNormalizeRulesRejectsInvalid(t *testing.T) {
+	invalid := [][]shortlink.RedirectRule{
+		{{URL: "https://example.com"}}, // 没有条件
+		{{OS: []string{"symbian"}, URL: "https://example.com"}},
+		{{Countries: []string{"GER"}, URL: "https://example.com"}},
+		{{DailyFrom: "09:00", URL: "https://example.com"}},
+		{{DailyFrom: "09:00", DailyTo: "18:00", Timezone: "Mars/Base", URL: "https://example.com"}},
+		{{OS: []string{"ios"}, URL: "ftp://example.com"}},
+	}
+	for i, rules := range invalid {
+		if _, err := shortlink.NormalizeRules(rules); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestConditionalRedirect(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	base := "https://example.com/rules-" + suffix
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": base,
+		"rules": []map[string]any{
+			{"os": []string{"ios"}, "url": base + "/ios"},
+			{"os": []string{"android"}, "url": base + "/android"},
+			{"languages": []string{"de"}, "url": base + "/de"},
+			{"countries": []string{"JP"}, "url": base + "/jp"},
+		},
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	code := created.Code
+
+	redirect := func(ua, lang, country string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.Header.Set("User-Agent", ua)
+		req.Header.Set("Accept-Language", lang)
+		if country != "" {
+			req.RemoteAddr = "127.0.0.1:12345"
+			req.Header.Set("CF-IPCountry", country)
+		}
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		return rec
+	}
+	for _, tc := range []struct{ ua, lang, country, want string }{
+		{uaIPhone, "en", "", base + "/ios"},
+		{uaAndroid, "en", "", base + "/android"},
+		{uaDesktop, "de-DE,de;q=0.9", "", base + "/de"},
+		{uaDesktop, "en", "JP", base + "/jp"},
+		{uaDesktop, "en", "", base},
+	} {
+		rec := redirect(tc.ua, tc.lang, tc.country)
+		if got := rec.Header().Get("Location"); got != tc.want {
+			t.Errorf("ua=%q lang=%q country=%q: got %q, want %q", tc.ua, tc.lang, tc.country, got, tc.want)
+		}
+		if cc := rec.Header().Get("Cache-Control"); cc != "private, no-store" {
+			t.Errorf("rules redirect must not be shared-cached, got Cache-Control %q", cc)
+		}
+	}
+
+	// 清除规则后恢复默认跳转
+	if rec := doJSON(r, http.MethodPut, "/api/v1/users/shortlinks/"+code+"/rules", token, map[string]any{"rules": []any{}}); rec.Code != http.StatusOK {
+		t.Fatalf("clear rules: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if got := redirect(uaIPhone, "en", "").Header().Get("Location"); got != base {
+		t.Errorf("after clearing rules: got %q, want %q", got, base)
+	}
+}
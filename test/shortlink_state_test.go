@@ -0,0 +1,79 @@
+package test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/gee"
+)
+
+func postStateChange(r *gee.Engine, path, token, reason string) *httptest.ResponseRecorder {
+	var req *http.Request
+	if reason == "" {
+		req = httptest.NewRequest(http.MethodPost, path, nil)
+	} else {
+		body, _ := json.Marshal(map[string]string{"reason": reason})
+		req = httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOwnerDisableAndEnableShortlink(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	owner := newTestUserToken(t, usersRepo, ts)
+	adminToken, _ := ts.Sign("1", "admin")
+
+	code := createShortlinkAs(t, r, owner, "https://example.com/pause-"+strconv.FormatInt(time.Now().UnixNano(), 10))
+	userPath := "/api/v1/users/shortlinks/" + code
+	adminPath := "/api/v1/admin/shortlinks/" + code
+
+	if rec := postStateChange(r, userPath+"/disable", owner, "campaign paused"); rec.Code != http.StatusOK {
+		t.Fatalf("owner disable: got %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := postStateChange(r, userPath+"/disable", owner, ""); rec.Code != http.StatusConflict {
+		t.Errorf("disable twice: got %d, want %d", rec.Code, http.StatusConflict)
+	}
+	redirectRec := httptest.NewRecorder()
+	r.ServeHTTP(redirectRec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if redirectRec.Code != http.StatusNotFound {
+		t.Errorf("redirect while disabled: got %d, want %d", redirectRec.Code, http.StatusNotFound)
+	}
+
+	if rec := postStateChange(r, userPath+"/enable", owner, ""); rec.Code != http.StatusOK {
+		t.Fatalf("owner enable: got %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := postStateChange(r, userPath+"/enable", owner, ""); rec.Code != http.StatusConflict {
+		t.Errorf("enable twice: got %d, want %d", rec.Code, http.StatusConflict)
+	}
+	redirectRec = httptest.NewRecorder()
+	r.ServeHTTP(redirectRec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if redirectRec.Code != http.StatusFound {
+		t.Errorf("redirect after enable: got %d, want %d", redirectRec.Code, http.StatusFound)
+	}
+
+	// 管理员停用后拥有者不能自行恢复，只能由管理员恢复
+	if rec := postStateChange(r, adminPath+"/disable", adminToken, "abuse report"); rec.Code != http.StatusOK {
+		t.Fatalf("admin disable: got %d, body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := postStateChange(r, userPath+"/enable", owner, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("owner enable after admin disable: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := postStateChange(r, adminPath+"/enable", adminToken, "resolved"); rec.Code != http.StatusOK {
+		t.Errorf("admin enable: got %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	// 非拥有者不能操作
+	other := newTestUserToken(t, usersRepo, ts)
+	if rec := postStateChange(r, userPath+"/disable", other, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("non-owner disable: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,113 @@
+package test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestParseImportCSV_Layouts(t *testing.T) {
+	cases := []struct {
+		name     string
+		input    string
+		wantCode string
+		wantURL  string
+	}{
+		{"native", "code,url,title,tags\nabc123,https://example.com/a,Hello,\"x,y\"\n", "abc123", "https://example.com/a"},
+		{"bitly", "\ufeffTitle,Long URL,Bitlink,Tags\nHello,https://example.com/a,bit.ly/abc123,x\n", "abc123", "https://example.com/a"},
+		{"yourls", "keyword,url,title,timestamp,ip,clicks\nabc123,https://example.com/a,Hello,2020-01-01 00:00:00,127.0.0.1,3\n", "abc123", "https://example.com/a"},
+	}
+	for _, tc := range cases {
+		rows, err := shortlink.ParseImportCSV(strings.NewReader(tc.input))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if len(rows) != 1 || rows[0].Code != tc.wantCode || rows[0].URL != tc.wantURL || rows[0].Row != 2 {
+			t.Errorf("%s: got %+v", tc.name, rows)
+		}
+	}
+
+	if _, err := shortlink.ParseImportCSV(strings.NewReader("foo,bar\n1,2\n")); err == nil {
+		t.Errorf("csv without url column should be rejected")
+	}
+}
+
+func TestParseImportJSON_Layouts(t *testing.T) {
+	bitly := `{"links":[{"id":"bit.ly/abc123","link":"https://bit.ly/abc123","long_url":"https://example.com/a","title":"Hello","tags":["x","y"]}]}`
+	rows, err := shortlink.ParseImportJSON(strings.NewReader(bitly))
+	if err != nil || len(rows) != 1 || rows[0].Code != "abc123" || len(rows[0].Tags) != 2 {
+		t.Errorf("bitly: got %+v, err=%v", rows, err)
+	}
+
+	yourls := `{"result":"success","links":{"link_10":{"shorturl":"https://sho.rt/ten","url":"https://example.com/10"},"link_2":{"shorturl":"https://sho.rt/two","url":"https://example.com/2"}}}`
+	rows, err = shortlink.ParseImportJSON(strings.NewReader(yourls))
+	if err != nil || len(rows) != 2 || rows[0].Code != "two" || rows[1].Code != "ten" {
+		t.Errorf("yourls: got %+v, err=%v", rows, err)
+	}
+
+	if _, err := shortlink.ParseImportJSON(strings.NewReader(`"nope"`)); err == nil {
+		t.Errorf("invalid json layout should be rejected")
+	}
+}
+
+func TestImportAndExportShortlinks(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	code := "im" + suffix[len(suffix)-8:]
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"Title", "Long URL", "Bitlink", "Tags", "Folder"})
+	w.Write([]string{"Kept", "https://example.com/import-" + suffix, "bit.ly/" + code, "news,q1", "Imported"})
+	w.Write([]string{"", "https://example.com/import-" + suffix + "-dup", "bit.ly/" + code, "", ""})
+	w.Write([]string{"", "ftp://bad", "", "", ""})
+	w.Flush()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/mine/import?format=csv", &buf)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("import: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var report struct {
+		Created   int `json:"created"`
+		Conflicts int `json:"conflicts"`
+		Invalid   int `json:"invalid"`
+		Rows      []struct {
+			Row    int    `json:"row"`
+			Status string `json:"status"`
+		} `json:"rows"`
+	}
+	json.NewDecoder(rec.Body).Decode(&report)
+	if report.Created != 1 || report.Conflicts != 1 || report.Invalid != 1 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	rec = doJSON(r, http.MethodGet, "/api/v1/users/mine/export?format=json", token, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var exported []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&exported); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if len(exported) != 1 || exported[0]["code"] != code || exported[0]["folder"] != "Imported" || exported[0]["title"] != "Kept" {
+		t.Errorf("unexpected export: %+v", exported)
+	}
+
+	rec = doJSON(r, http.MethodGet, "/api/v1/users/mine/export", token, nil)
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil || len(records) != 2 || records[0][0] != "code" || records[1][0] != code {
+		t.Errorf("unexpected csv export: %v, err=%v", records, err)
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/gee"
+	"day.local/internal/app/shortlink/repo"
+	"day.local/internal/platform/auth"
+)
+
+// newTestUserToken 注册一个随机用户并返回其 token
+func newTestUserToken(t *testing.T, usersRepo *repo.UsersRepo, ts auth.TokenService) string {
+	t.Helper()
+	name := "u" + strconv.FormatInt(time.Now().UnixNano()%1_000_000_000, 10)
+	id, err := usersRepo.RegistUser(context.Background(), name, "password123")
+	if err != nil {
+		t.Fatalf("RegistUser: %v", err)
+	}
+	token, err := ts.Sign(strconv.FormatInt(id, 10), "user")
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	return token
+}
+
+func createShortlinkAs(t *testing.T, r *gee.Engine, token, url string) string {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"url": url})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/shortlinks", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create failed: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var resp map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	code, _ := resp["code"].(string)
+	return code
+}
+
+func patchShortlinkURL(r *gee.Engine, token, code, url string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(map[string]string{"url": url})
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/shortlinks/"+code, bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestUpdateShortlinkURL(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	owner := newTestUserToken(t, usersRepo, ts)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	code := createShortlinkAs(t, r, owner, "https://example.com/typo-"+suffix)
+	newURL := "https://example.com/fixed-" + suffix
+
+	rec := patchShortlinkURL(r, owner, code, newURL)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("patch failed: %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	redirectRec := httptest.NewRecorder()
+	r.ServeHTTP(redirectRec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if got := redirectRec.Header().Get("Location"); got != newURL {
+		t.Errorf("Location after update: got %q, want %q", got, newURL)
+	}
+
+	// 非拥有者不能修改
+	other := newTestUserToken(t, usersRepo, ts)
+	if rec := patchShortlinkURL(r, other, code, newURL+"-x"); rec.Code != http.StatusForbidden {
+		t.Errorf("non-owner: got %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	// 目标 URL 已有匿名创建的共享短链也可以改过去，两个短码各自跳转
+	taken := "https://example.com/taken-" + suffix
+	takenCode := createShortlinkAs(t, r, "", taken)
+	if rec := patchShortlinkURL(r, owner, code, taken); rec.Code != http.StatusOK {
+		t.Fatalf("taken url: got %d, body=%s", rec.Code, rec.Body.String())
+	}
+	for _, c := range []string{code, takenCode} {
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+c, nil))
+		if got := rec.Header().Get("Location"); got != taken {
+			t.Errorf("%s after retarget: got %q, want %q", c, got, taken)
+		}
+	}
+	// 改过目标的行不再参与去重：同一 url 的新建拿到的是原来那个共享短码
+	if again := createShortlinkAs(t, r, "", taken); again != takenCode {
+		t.Errorf("dedup picked up the edited row: got %q, want %q", again, takenCode)
+	}
+
+	if rec := patchShortlinkURL(r, owner, code, "ftp://bad"); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid url: got %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+// 匿名创建的共享行被登录用户再次缩短后，后来者只是加入，不能改掉别人手里的短码；
+// 反过来，登录用户自己创建的行也不会按 url 发给匿名用户
+func TestSharedRowNotEditableByJoiner(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	popular := "https://example.com/popular-" + suffix
+	anonCode := createShortlinkAs(t, r, "", popular)
+	joiner := newTestUserToken(t, usersRepo, ts)
+	if code := createShortlinkAs(t, r, joiner, popular); code != anonCode {
+		t.Fatalf("re-shortener should share the anonymous row: got %q, want %q", code, anonCode)
+	}
+	if rec := patchShortlinkURL(r, joiner, anonCode, "https://phish.example.net/"+suffix); rec.Code != http.StatusConflict {
+		t.Errorf("edit shared row: got %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := postStateChange(r, "/api/v1/users/shortlinks/"+anonCode+"/disable", joiner, ""); rec.Code != http.StatusConflict {
+		t.Errorf("disable shared row: got %d, want %d", rec.Code, http.StatusConflict)
+	}
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+anonCode, nil))
+	if got := rec.Header().Get("Location"); got != popular {
+		t.Errorf("shared row changed: Location=%q", got)
+	}
+
+	owned := "https://example.com/owned-" + suffix
+	ownCode := createShortlinkAs(t, r, joiner, owned)
+	if again := createShortlinkAs(t, r, joiner, owned); again != ownCode {
+		t.Errorf("creator's repeat create: got %q, want %q", again, ownCode)
+	}
+	if anon := createShortlinkAs(t, r, "", owned); anon == ownCode {
+		t.Errorf("anonymous create got the logged-in user's editable code %q", ownCode)
+	}
+}
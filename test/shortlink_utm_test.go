@@ -0,0 +1,78 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestDecorateURL(t *testing.T) {
+	utm := &shortlink.UTM{Source: "newsletter", Medium: "email"}
+	incoming := url.Values{"ref": {"x"}, "utm_source": {"spoof"}, "id": {"9"}}
+
+	cases := []struct {
+		name string
+		link shortlink.Shortlink
+		dest string
+		want string
+	}{
+		{"plain", shortlink.Shortlink{}, "https://example.com/p?a=1", "https://example.com/p?a=1"},
+		{"utm", shortlink.Shortlink{UTM: utm}, "https://example.com/p", "https://example.com/p?utm_medium=email&utm_source=newsletter"},
+		{"utm overrides destination", shortlink.Shortlink{UTM: utm}, "https://example.com/p?b=2&utm_source=old#top",
+			"https://example.com/p?b=2&utm_medium=email&utm_source=newsletter#top"},
+		{"forward keeps destination params", shortlink.Shortlink{ForwardQuery: true}, "https://example.com/p?id=1",
+			"https://example.com/p?id=1&ref=x&utm_source=spoof"},
+		{"utm wins over forwarded", shortlink.Shortlink{UTM: utm, ForwardQuery: true}, "https://example.com/p",
+			"https://example.com/p?id=9&ref=x&utm_medium=email&utm_source=newsletter"},
+		{"app scheme untouched", shortlink.Shortlink{UTM: utm}, "myapp://item/1", "myapp://item/1"},
+	}
+	for _, tc := range cases {
+		if got := tc.link.DecorateURL(tc.dest, incoming); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	if _, err := shortlink.NormalizeUTM(&shortlink.UTM{Source: "a\nb"}); err == nil {
+		t.Error("expected error for control characters")
+	}
+	if u, err := shortlink.NormalizeUTM(&shortlink.UTM{Source: "  "}); err != nil || u != nil {
+		t.Errorf("blank utm: got %v, %v; want nil, nil", u, err)
+	}
+}
+
+func TestRedirectWithUTMAndForwardedQuery(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	base := "https://example.com/utm-" + strconv.FormatInt(time.Now().UnixNano(), 10)
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url":           base,
+		"utm":           map[string]any{"source": "twitter", "campaign": "launch"},
+		"forward_query": true,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+created.Code+"?ref=x&utm_source=spoof", nil))
+	loc, err := url.Parse(rec.Header().Get("Location"))
+	if err != nil || !strings.HasPrefix(loc.String(), base) {
+		t.Fatalf("unexpected Location %q", rec.Header().Get("Location"))
+	}
+	q := loc.Query()
+	if q.Get("ref") != "x" || q.Get("utm_source") != "twitter" || q.Get("utm_campaign") != "launch" {
+		t.Errorf("unexpected query %v", q)
+	}
+}
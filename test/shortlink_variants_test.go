@@ -0,0 +1,152 @@
+package test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"day.local/internal/app/shortlink"
+)
+
+func TestPickVariant(t *testing.T) {
+	variants, err := shortlink.NormalizeVariants([]shortlink.Variant{
+		{Name: "a", URL: "https://example.com/a", Weight: 3},
+		{Name: "b", URL: "https://example.com/b", Weight: 1},
+	})
+	if err != nil {
+		t.Fatalf("NormalizeVariants: %v", err)
+	}
+	link := shortlink.Shortlink{URL: "https://example.com", Variants: variants}
+
+	counts := map[string]int{}
+	for i := 0; i < 4000; i++ {
+		v, ok := link.PickVariant("")
+		if !ok {
+			t.Fatal("expected a variant")
+		}
+		counts[v.Name]++
+	}
+	// 期望约 3000/1000，留足随机波动的余量
+	if counts["a"] < 2700 || counts["b"] < 700 || counts["a"]+counts["b"] != 4000 {
+		t.Errorf("unexpected distribution: %v", counts)
+	}
+
+	if v, _ := link.PickVariant("b"); v.Name != "b" {
+		t.Errorf("sticky variant: got %q, want b", v.Name)
+	}
+	if _, ok := (shortlink.Shortlink{URL: "https://example.com"}).PickVariant(""); ok {
+		t.Error("link without variants should not pick one")
+	}
+}
+
+func TestNormalizeVariantsRejectsInvalid(t *testing.T) {
+	a := shortlink.Variant{Name: "a", URL: "https://example.com/a", Weight: 1}
+	invalid := [][]shortlink.Variant{
+		{a}, // 只有一个版本
+		{a, {Name: "a", URL: "https://example.com/b", Weight: 1}},
+		{a, {Name: "b c", URL: "https://example.com/b", Weight: 1}},
+		{a, {Name: "b", URL: "https://example.com/b", Weight: 0}},
+		{a, {Name: "b", URL: "https://example.com/b", Weight: 1001}},
+		{a, {Name: "b", URL: "ftp://example.com/b", Weight: 1}},
+	}
+	for i, variants := range invalid {
+		if _, err := shortlink.NormalizeVariants(variants); err == nil {
+			t.Errorf("case %d: expected error", i)
+		}
+	}
+}
+
+func TestStickyVariantRedirect(t *testing.T) {
+	r, _, usersRepo, ts := setupTestServer(t)
+	token := newTestUserToken(t, usersRepo, ts)
+	suffix := strconv.FormatInt(time.Now().UnixNano(), 10)
+	base := "https://example.com/ab-" + suffix
+
+	if rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": base, "sticky_variant": true,
+	}); rec.Code != http.StatusBadRequest {
+		t.Fatalf("sticky without variants: want 400, got %d", rec.Code)
+	}
+
+	rec := doJSON(r, http.MethodPost, "/api/v1/shortlinks", token, map[string]any{
+		"url": base,
+		"variants": []map[string]any{
+			{"name": "a", "url": base + "/a", "weight": 1},
+			{"name": "b", "url": base + "/b", "weight": 1},
+		},
+		"sticky_variant": true,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	var created struct {
+		Code string `json:"code"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	code := created.Code
+
+	first := httptest.NewRecorder()
+	r.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	dest := first.Header().Get("Location")
+	if dest != base+"/a" && dest != base+"/b" {
+		t.Fatalf("unexpected destination %q", dest)
+	}
+	if cc := first.Header().Get("Cache-Control"); cc != "private, no-store" {
+		t.Errorf("split redirect must not be shared-cached, got Cache-Control %q", cc)
+	}
+	cookies := first.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "sl_v_"+code {
+		t.Fatalf("expected sticky cookie, got %v", cookies)
+	}
+
+	// 带着 cookie 的访客始终落到同一版本
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/"+code, nil)
+		req.AddCookie(cookies[0])
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Location"); got != dest {
+			t.Fatalf("sticky visitor moved from %q to %q", dest, got)
+		}
+	}
+
+	// 扫码入口 "/{code}~qr" 也要拿到 cookie：Path "/{code}" 不会随这个路径发送
+	scan := httptest.NewRecorder()
+	r.ServeHTTP(scan, httptest.NewRequest(http.MethodGet, "/"+code+"~qr", nil))
+	scanDest := scan.Header().Get("Location")
+	var scanCookie *http.Cookie
+	for _, c := range scan.Result().Cookies() {
+		if c.Path == "/"+code+"~qr" {
+			scanCookie = c
+		}
+	}
+	if scanCookie == nil {
+		t.Fatalf("expected a sticky cookie for the QR path, got %v", scan.Result().Cookies())
+	}
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/"+code+"~qr", nil)
+		req.AddCookie(scanCookie)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if got := rec.Header().Get("Location"); got != scanDest {
+			t.Fatalf("sticky QR visitor moved from %q to %q", scanDest, got)
+		}
+	}
+
+	if rec := doJSON(r, http.MethodGet, "/api/v1/users/shortlinks/"+code+"/stats/variants", token, nil); rec.Code != http.StatusOK {
+		t.Fatalf("variant stats: %d, body=%s", rec.Code, rec.Body.String())
+	}
+
+	// 关闭分流后恢复默认跳转
+	if rec := doJSON(r, http.MethodPut, "/api/v1/users/shortlinks/"+code+"/variants", token, map[string]any{"variants": []any{}}); rec.Code != http.StatusOK {
+		t.Fatalf("clear variants: %d, body=%s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/"+code, nil))
+	if got := rec.Header().Get("Location"); got != base {
+		t.Errorf("after clearing variants: got %q, want %q", got, base)
+	}
+}